@@ -2,6 +2,8 @@ package hoist
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"net/http"
@@ -20,6 +22,9 @@ var (
 
 const defaultUsername = "default"
 
+// tokenRefreshGrace is how long before expiry an access token is refreshed, to prevent race conditions/issues
+const tokenRefreshGrace = 5 * time.Minute
+
 type Store interface {
 	// Set stores an authenticated user's access and refresh tokens
 	Set(username string, auth AuthResponse)
@@ -30,8 +35,21 @@ type Store interface {
 }
 
 // AuthManagerOption configures AuthManager for usage
+// Authentication endpoints, using the AuthManager's API version (see WithAuthAPIVersion)
+const (
+	apiAuthenticate = "api/{version}/auth/authenticate-user"
+	apiRefreshToken = "api/{version}/auth/refresh-token"
+)
+
 type AuthManagerOption func(*authManager)
 
+// WithAuthAPIVersion sets the API version used for the authentication endpoints, such as "v2". Defaults to "v1".
+func WithAuthAPIVersion(version string) AuthManagerOption {
+	return func(manager *authManager) {
+		manager.apiVersion = version
+	}
+}
+
 // WithAuthClient specifies the http client to use for authentication requests
 func WithAuthClient(client *http.Client) AuthManagerOption {
 	return func(manager *authManager) {
@@ -46,6 +64,20 @@ func WithAuthStore(store Store) AuthManagerOption {
 	}
 }
 
+// WithAuthClock overrides the clock used to check token expiry, defaulting to time.Now
+func WithAuthClock(now func() time.Time) AuthManagerOption {
+	return func(manager *authManager) {
+		manager.now = now
+	}
+}
+
+// WithTokenRedactor overrides how tokens are shown in debug output, defaulting to RedactToken
+func WithTokenRedactor(redact func(token string) string) AuthManagerOption {
+	return func(manager *authManager) {
+		manager.redact = redact
+	}
+}
+
 func WithClientID(clientID string) AuthManagerOption {
 	return func(manager *authManager) {
 		manager.clientID = clientID
@@ -55,7 +87,10 @@ func WithClientID(clientID string) AuthManagerOption {
 type AuthManager interface {
 	Authenticate(ctx context.Context, username, password, twoFactorCode string) error
 	RefreshToken(ctx context.Context) error
+	ForceRefresh(ctx context.Context) error
 	GetToken(ctx context.Context) (string, error)
+	AccessToken(ctx context.Context) (token string, expiry time.Time, err error)
+	TokenStatus(ctx context.Context) (valid bool, expiresIn time.Duration, err error)
 	ClientID() string
 }
 
@@ -64,16 +99,36 @@ type authManager struct {
 	mu           sync.Mutex
 	client       *http.Client
 	apiURL       string
+	apiVersion   string
 	lastResponse *AuthResponse
 	store        Store
 	clientID     string
+	now          func() time.Time
+	redact       func(token string) string
+
+	// refreshing holds the refresh in flight for each user, see refresh
+	refreshMu  sync.Mutex
+	refreshing map[string]*refreshCall
+}
+
+// refreshTimeout bounds a shared token refresh, which runs until it completes even if every caller waiting for it
+// gives up
+const refreshTimeout = 30 * time.Second
+
+// refreshCall is a token refresh shared by every caller which asked for one while it was in flight
+type refreshCall struct {
+	done chan struct{}
+	err  error
 }
 
 // NewAuthManager initializes the AuthManager.
 func NewAuthManager(apiURL string, opts ...AuthManagerOption) AuthManager {
 	a := &authManager{
-		client: http.DefaultClient,
-		apiURL: apiURL,
+		client:     http.DefaultClient,
+		apiURL:     apiURL,
+		apiVersion: defaultAPIVersion,
+		now:        time.Now,
+		redact:     RedactToken,
 	}
 
 	for _, opt := range opts {
@@ -102,6 +157,49 @@ type AuthResponse struct {
 	RefreshTokenExpiration time.Time `json:"refreshTokenExpiration"`
 }
 
+// String describes the response with tokens redacted, so it's safe to log
+func (r AuthResponse) String() string {
+	return fmt.Sprintf("AuthResponse{Username: %s, Token: %s, TokenExpiration: %s, RefreshToken: %s, RefreshTokenExpiration: %s}",
+		r.Username, RedactToken(r.Token), r.TokenExpiration, RedactToken(r.RefreshToken), r.RefreshTokenExpiration)
+}
+
+// GoString prevents %#v from printing tokens in full
+func (r AuthResponse) GoString() string {
+	return r.String()
+}
+
+// ExpiresIn returns how long the access token remains valid from now, which is negative once expired
+func (r AuthResponse) ExpiresIn(now time.Time) time.Duration {
+	return r.TokenExpiration.Sub(now)
+}
+
+// NeedsRefresh reports whether the access token has expired or expires within the refresh grace period
+func (r AuthResponse) NeedsRefresh(now time.Time) bool {
+	return r.TokenExpiration.Before(now.Add(tokenRefreshGrace))
+}
+
+// RefreshExpired reports whether the refresh token has expired, requiring a new Authenticate
+func (r AuthResponse) RefreshExpired(now time.Time) bool {
+	return r.RefreshTokenExpiration.Before(now)
+}
+
+// RedactToken returns a loggable form of a token, showing a short prefix and a hash to tell tokens apart
+func RedactToken(token string) string {
+	if token == "" {
+		return ""
+	}
+
+	sum := sha256.Sum256([]byte(token))
+	hash := hex.EncodeToString(sum[:4])
+
+	// Short tokens would be mostly revealed by a prefix
+	if len(token) <= 12 {
+		return "[redacted sha256:" + hash + "]"
+	}
+
+	return token[:4] + "...[redacted sha256:" + hash + "]"
+}
+
 // Authenticate obtains a new token.
 func (am *authManager) Authenticate(ctx context.Context, username, password, twoFactorCode string) error {
 	log.WithFields(log.Fields{
@@ -112,7 +210,11 @@ func (am *authManager) Authenticate(ctx context.Context, username, password, two
 	defer am.mu.Unlock()
 
 	// Construct the API URL for authentication
-	url := fmt.Sprintf("%s/api/v1/auth/authenticate-user", am.apiURL)
+	url, err := versionedURL(am.apiURL, apiAuthenticate, am.apiVersion)
+
+	if err != nil {
+		return err
+	}
 
 	res, err := doHttpRequest(ctx, am.client, http.MethodPost, url, authRequest{
 		ClientID:      am.clientID,
@@ -128,7 +230,7 @@ func (am *authManager) Authenticate(ctx context.Context, username, password, two
 	defer res.Close()
 
 	if res.StatusCode != http.StatusOK {
-		return fmt.Errorf("unexpected status code %d", res.StatusCode)
+		return statusError(res)
 	}
 
 	// Parse the response
@@ -138,6 +240,11 @@ func (am *authManager) Authenticate(ctx context.Context, username, password, two
 		return fmt.Errorf("failed to decode authenteication response: %w", err)
 	}
 
+	log.WithFields(log.Fields{
+		"username": username,
+		"token":    am.redact(response.Token),
+	}).Debug("Authenticated user")
+
 	// Store the token and expiration time
 	if am.store != nil {
 		ctxUsername, err := contextUsername(ctx)
@@ -165,22 +272,20 @@ func (am *authManager) RefreshToken(ctx context.Context) error {
 	am.mu.Lock()
 	defer am.mu.Unlock()
 
-	url := fmt.Sprintf("%s/api/v1/auth/refresh-token", am.apiURL)
+	url, err := versionedURL(am.apiURL, apiRefreshToken, am.apiVersion)
 
-	response := am.lastResponse
+	if err != nil {
+		return err
+	}
 
-	if am.store != nil {
-		username, err := contextUsername(ctx)
+	response, err := am.response(ctx)
 
-		if err != nil {
-			return err
-		}
-
-		response, err = am.store.Get(username)
+	if err != nil {
+		return err
+	}
 
-		if err != nil {
-			return err
-		}
+	if response == nil {
+		return ErrNoToken
 	}
 
 	res, err := doHttpRequest(ctx, am.client, http.MethodPost, url, refreshRequest{
@@ -195,7 +300,7 @@ func (am *authManager) RefreshToken(ctx context.Context) error {
 	defer res.Close()
 
 	if res.StatusCode != http.StatusOK {
-		return fmt.Errorf("unexpected status code %d: %s", res.StatusCode, string(res.Data()))
+		return statusError(res)
 	}
 
 	var newResponse AuthResponse
@@ -204,6 +309,10 @@ func (am *authManager) RefreshToken(ctx context.Context) error {
 		return fmt.Errorf("failed to decode refresh response: %w", err)
 	}
 
+	log.WithFields(log.Fields{
+		"token": am.redact(newResponse.Token),
+	}).Debug("Refreshed access token")
+
 	if am.store != nil {
 		am.store.Set(response.Username, newResponse)
 	} else {
@@ -213,48 +322,190 @@ func (am *authManager) RefreshToken(ctx context.Context) error {
 	return nil
 }
 
-// GetToken ensures the token is valid and returns it.
-func (am *authManager) GetToken(ctx context.Context) (string, error) {
-	response := am.lastResponse
+// ForceRefresh refreshes the access token immediately, regardless of its expiry.
+// This is useful when the token is known to be bad, such as after a 401 or credential rotation.
+// Calls made while a refresh for the same user is in flight wait for it and return its result instead of refreshing
+// again, so a burst of 401s causes a single refresh. A call made after it completes refreshes again.
+func (am *authManager) ForceRefresh(ctx context.Context) error {
+	log.Debug("Forcing token refresh")
+
+	return am.refresh(ctx)
+}
+
+// refresh starts RefreshToken, or joins the refresh already in flight for the context's user, and waits for its result
+// until ctx is done. The refresh itself runs on its own context, so one caller giving up doesn't fail the others.
+func (am *authManager) refresh(ctx context.Context) error {
+	var key string
 
 	if am.store != nil {
 		username, err := contextUsername(ctx)
 
 		if err != nil {
-			return "", err
+			return err
 		}
 
-		response, err = am.store.Get(username)
+		key = username
+	}
 
-		if err != nil {
-			return "", err
+	am.refreshMu.Lock()
+
+	if call, ok := am.refreshing[key]; ok {
+		am.refreshMu.Unlock()
+
+		select {
+		case <-call.done:
+			return call.err
+		case <-ctx.Done():
+			return ctx.Err()
 		}
 	}
 
+	call := &refreshCall{done: make(chan struct{})}
+
+	if am.refreshing == nil {
+		am.refreshing = make(map[string]*refreshCall)
+	}
+
+	am.refreshing[key] = call
+	am.refreshMu.Unlock()
+
+	// The refresh is shared, so it mustn't end when the caller which started it gives up
+	refreshCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), refreshTimeout)
+
+	go func() {
+		defer cancel()
+
+		call.err = am.RefreshToken(refreshCtx)
+
+		am.refreshMu.Lock()
+		delete(am.refreshing, key)
+		am.refreshMu.Unlock()
+
+		close(call.done)
+	}()
+
+	select {
+	case <-call.done:
+		return call.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// currentResponse returns the stored response like response, taking the lock
+func (am *authManager) currentResponse(ctx context.Context) (*AuthResponse, error) {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	return am.response(ctx)
+}
+
+// response returns the stored response for the context's user, or the last response without a store.
+// It must be called with the lock held, see currentResponse.
+func (am *authManager) response(ctx context.Context) (*AuthResponse, error) {
+	if am.store == nil {
+		return am.lastResponse, nil
+	}
+
+	username, err := contextUsername(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return am.store.Get(username)
+}
+
+// TokenStatus reports whether the current access token is usable and how long until it expires, without refreshing.
+// An expired token reports a zero duration. ErrNoToken is returned if there's no token at all.
+func (am *authManager) TokenStatus(ctx context.Context) (bool, time.Duration, error) {
+	response, err := am.currentResponse(ctx)
+
+	if err != nil {
+		return false, 0, err
+	}
+
+	if response == nil || response.Token == "" {
+		return false, 0, ErrNoToken
+	}
+
+	expiresIn := response.ExpiresIn(am.now())
+
+	if expiresIn <= 0 {
+		return false, 0, nil
+	}
+
+	return true, expiresIn, nil
+}
+
+// GetToken ensures the token is valid and returns it.
+func (am *authManager) GetToken(ctx context.Context) (string, error) {
+	response, err := am.validResponse(ctx)
+
+	if err != nil {
+		return "", err
+	}
+
+	return response.Token, nil
+}
+
+// AccessToken ensures the token is valid like GetToken, also returning when it expires so it can be handed to other
+// tools and cached by them appropriately
+func (am *authManager) AccessToken(ctx context.Context) (string, time.Time, error) {
+	response, err := am.validResponse(ctx)
+
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return response.Token, response.TokenExpiration, nil
+}
+
+// validResponse returns the current response, refreshing the access token first if it's near expiry
+func (am *authManager) validResponse(ctx context.Context) (*AuthResponse, error) {
+	response, err := am.currentResponse(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
 	if response == nil || response.Token == "" {
 		log.Debug("No token set in AuthManager")
-		return "", ErrNoToken
+		return nil, ErrNoToken
 	}
 
 	// Handle if we can't use our refresh token
-	if response.RefreshTokenExpiration.Before(time.Now()) {
-		log.Debug(am, "Refresh token expired")
-		return "", ErrExpiredRefreshToken
+	if response.RefreshExpired(am.now()) {
+		log.WithFields(log.Fields{
+			"refreshToken": am.redact(response.RefreshToken),
+		}).Debug("Refresh token expired")
+		return nil, ErrExpiredRefreshToken
 	}
 
-	// Give us a 5 minute grace period to prevent race conditions/issues
-	if response.TokenExpiration.Before(time.Now().Add(5 * time.Minute)) {
+	// Give us a grace period to prevent race conditions/issues
+	if response.NeedsRefresh(am.now()) {
 		log.Debug("Access token expires soon, need to refresh")
 
-		// Refresh token
-		if err := am.RefreshToken(ctx); err != nil {
-			return "", fmt.Errorf("failed to refresh token: %w", err)
+		// Refresh token, sharing the refresh with concurrent callers
+		if err := am.refresh(ctx); err != nil {
+			return nil, fmt.Errorf("failed to refresh token: %w", err)
+		}
+
+		// Use the refreshed response rather than the one it replaced
+		if response, err = am.currentResponse(ctx); err != nil {
+			return nil, err
+		}
+
+		if response == nil || response.Token == "" {
+			return nil, ErrNoToken
 		}
 	}
 
-	log.Debug("Using existing token")
+	log.WithFields(log.Fields{
+		"token": am.redact(response.Token),
+	}).Debug("Using existing token")
 
-	return response.Token, nil
+	return response, nil
 }
 
 // ClientID returns either the set or generated client id
@@ -0,0 +1,352 @@
+package hoist
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	log "github.com/sirupsen/logrus"
+)
+
+var _ = Describe("Auth tests", func() {
+	var server *httptest.Server
+	var refreshes int
+	var now time.Time
+	var expiry time.Time
+
+	BeforeEach(func() {
+		refreshes = 0
+		now = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+		expiry = now.Add(time.Hour)
+
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			refreshes++
+
+			_ = json.NewEncoder(w).Encode(AuthResponse{
+				Token:                  "refreshed-token",
+				TokenExpiration:        now.Add(2 * time.Hour),
+				RefreshToken:           "refresh",
+				RefreshTokenExpiration: now.Add(48 * time.Hour),
+			})
+		}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	newManager := func() *authManager {
+		am := NewAuthManager(server.URL, WithAuthClock(func() time.Time {
+			return now
+		})).(*authManager)
+
+		am.lastResponse = &AuthResponse{
+			Token:                  "token",
+			TokenExpiration:        expiry,
+			RefreshToken:           "refresh",
+			RefreshTokenExpiration: now.Add(24 * time.Hour),
+		}
+
+		return am
+	}
+
+	It("Should use the existing token when it isn't near expiry", func() {
+		token, err := newManager().GetToken(context.Background())
+
+		Expect(err).To(BeNil())
+		Expect(token).To(Equal("token"))
+		Expect(refreshes).To(Equal(0))
+	})
+	It("Should refresh the token when it is near expiry", func() {
+		am := newManager()
+
+		now = expiry.Add(-time.Minute)
+
+		token, err := am.GetToken(context.Background())
+
+		Expect(err).To(BeNil())
+		Expect(token).To(Equal("refreshed-token"))
+		Expect(refreshes).To(Equal(1))
+		Expect(am.lastResponse.Token).To(Equal("refreshed-token"))
+	})
+	It("Should return the access token with its stored expiry", func() {
+		token, tokenExpiry, err := newManager().AccessToken(context.Background())
+
+		Expect(err).To(BeNil())
+		Expect(token).To(Equal("token"))
+		Expect(tokenExpiry).To(Equal(expiry))
+		Expect(refreshes).To(Equal(0))
+	})
+	It("Should return the refreshed token's expiry when near expiry", func() {
+		am := newManager()
+
+		now = expiry.Add(-time.Minute)
+
+		token, tokenExpiry, err := am.AccessToken(context.Background())
+
+		Expect(err).To(BeNil())
+		Expect(token).To(Equal("refreshed-token"))
+		Expect(tokenExpiry).To(Equal(now.Add(2 * time.Hour)))
+		Expect(refreshes).To(Equal(1))
+	})
+	It("Should fail without a token", func() {
+		am := newManager()
+		am.lastResponse = nil
+
+		_, _, err := am.AccessToken(context.Background())
+
+		Expect(err).To(MatchError(ErrNoToken))
+	})
+	It("Should fail when the refresh token has expired", func() {
+		am := newManager()
+
+		now = now.Add(25 * time.Hour)
+
+		_, err := am.GetToken(context.Background())
+
+		Expect(errors.Is(err, ErrExpiredRefreshToken)).To(BeTrue())
+	})
+	It("Should force a refresh even when the token isn't near expiry", func() {
+		am := newManager()
+
+		Expect(am.ForceRefresh(context.Background())).To(Succeed())
+		Expect(refreshes).To(Equal(1))
+
+		token, err := am.GetToken(context.Background())
+
+		Expect(err).To(BeNil())
+		Expect(token).To(Equal("refreshed-token"))
+		Expect(refreshes).To(Equal(1))
+	})
+	It("Should share a refresh between concurrent ForceRefresh calls", func() {
+		var mu sync.Mutex
+		var requests int
+
+		release := make(chan struct{})
+
+		slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			requests++
+			mu.Unlock()
+
+			<-release
+
+			_ = json.NewEncoder(w).Encode(AuthResponse{
+				Token:                  "refreshed-token",
+				TokenExpiration:        now.Add(2 * time.Hour),
+				RefreshToken:           "refresh",
+				RefreshTokenExpiration: now.Add(48 * time.Hour),
+			})
+		}))
+		defer slow.Close()
+
+		am := newManager()
+		am.apiURL = slow.URL
+
+		count := func() int {
+			mu.Lock()
+			defer mu.Unlock()
+
+			return requests
+		}
+
+		var wg sync.WaitGroup
+
+		errs := make(chan error, 5)
+
+		for range 5 {
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+
+				errs <- am.ForceRefresh(context.Background())
+			}()
+		}
+
+		Eventually(count).Should(Equal(1))
+		Consistently(count, 50*time.Millisecond).Should(Equal(1))
+
+		close(release)
+		wg.Wait()
+		close(errs)
+
+		for err := range errs {
+			Expect(err).To(BeNil())
+		}
+
+		Expect(count()).To(Equal(1))
+
+		token, err := am.GetToken(context.Background())
+
+		Expect(err).To(BeNil())
+		Expect(token).To(Equal("refreshed-token"))
+	})
+	It("Should keep a shared refresh going when the caller which started it gives up", func() {
+		var mu sync.Mutex
+		var requests int
+
+		release := make(chan struct{})
+
+		slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			requests++
+			mu.Unlock()
+
+			<-release
+
+			_ = json.NewEncoder(w).Encode(AuthResponse{
+				Token:                  "refreshed-token",
+				TokenExpiration:        now.Add(2 * time.Hour),
+				RefreshToken:           "refresh",
+				RefreshTokenExpiration: now.Add(48 * time.Hour),
+			})
+		}))
+		defer slow.Close()
+
+		am := newManager()
+		am.apiURL = slow.URL
+
+		count := func() int {
+			mu.Lock()
+			defer mu.Unlock()
+
+			return requests
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		first := make(chan error, 1)
+
+		go func() {
+			first <- am.ForceRefresh(ctx)
+		}()
+
+		Eventually(count).Should(Equal(1))
+
+		second := make(chan error, 1)
+
+		go func() {
+			second <- am.ForceRefresh(context.Background())
+		}()
+
+		cancel()
+
+		Eventually(first).Should(Receive(MatchError(context.Canceled)))
+		Consistently(second, 50*time.Millisecond).ShouldNot(Receive())
+
+		close(release)
+
+		Eventually(second).Should(Receive(BeNil()))
+		Expect(count()).To(Equal(1))
+
+		token, err := am.GetToken(context.Background())
+
+		Expect(err).To(BeNil())
+		Expect(token).To(Equal("refreshed-token"))
+	})
+	It("Should report a valid token without refreshing", func() {
+		valid, expiresIn, err := newManager().TokenStatus(context.Background())
+
+		Expect(err).To(BeNil())
+		Expect(valid).To(BeTrue())
+		Expect(expiresIn).To(Equal(time.Hour))
+		Expect(refreshes).To(Equal(0))
+	})
+	It("Should report a token near expiry as valid without refreshing", func() {
+		am := newManager()
+
+		now = expiry.Add(-time.Minute)
+
+		valid, expiresIn, err := am.TokenStatus(context.Background())
+
+		Expect(err).To(BeNil())
+		Expect(valid).To(BeTrue())
+		Expect(expiresIn).To(Equal(time.Minute))
+		Expect(am.lastResponse.NeedsRefresh(now)).To(BeTrue())
+		Expect(refreshes).To(Equal(0))
+	})
+	It("Should report an expired token as invalid", func() {
+		am := newManager()
+
+		now = expiry.Add(time.Second)
+
+		valid, expiresIn, err := am.TokenStatus(context.Background())
+
+		Expect(err).To(BeNil())
+		Expect(valid).To(BeFalse())
+		Expect(expiresIn).To(BeZero())
+		Expect(refreshes).To(Equal(0))
+
+		_, _, err = NewAuthManager(server.URL).TokenStatus(context.Background())
+
+		Expect(errors.Is(err, ErrNoToken)).To(BeTrue())
+	})
+	It("Should never log tokens in full", func() {
+		var buf bytes.Buffer
+
+		level, out := log.GetLevel(), log.StandardLogger().Out
+		log.SetOutput(&buf)
+		log.SetLevel(log.DebugLevel)
+
+		DeferCleanup(func() {
+			log.SetOutput(out)
+			log.SetLevel(level)
+		})
+
+		am := newManager()
+
+		Expect(am.Authenticate(context.Background(), "user", "password", "")).To(Succeed())
+
+		token, err := am.GetToken(context.Background())
+
+		Expect(err).To(BeNil())
+		Expect(token).To(Equal("refreshed-token"))
+		Expect(am.ForceRefresh(context.Background())).To(Succeed())
+
+		log.Debug(*am.lastResponse)
+		log.Debugf("%v %+v %#v", *am.lastResponse, am.lastResponse, *am.lastResponse)
+
+		Expect(buf.String()).To(ContainSubstring(RedactToken("refreshed-token")))
+		Expect(buf.String()).ToNot(ContainSubstring("refreshed-token"))
+	})
+	It("Should request the auth endpoints for the configured API version", func() {
+		var paths []string
+
+		versioned := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			paths = append(paths, r.URL.Path)
+
+			_ = json.NewEncoder(w).Encode(AuthResponse{
+				Token:                  "refreshed-token",
+				TokenExpiration:        now.Add(2 * time.Hour),
+				RefreshToken:           "refresh",
+				RefreshTokenExpiration: now.Add(48 * time.Hour),
+			})
+		}))
+		defer versioned.Close()
+
+		am := NewAuthManager(versioned.URL, WithAuthAPIVersion("v2"), WithAuthClock(func() time.Time {
+			return now
+		}))
+
+		Expect(am.Authenticate(context.Background(), "user", "password", "")).To(Succeed())
+		Expect(am.ForceRefresh(context.Background())).To(Succeed())
+
+		Expect(paths).To(Equal([]string{"/api/v2/auth/authenticate-user", "/api/v2/auth/refresh-token"}))
+	})
+	It("Should redact tokens to a short prefix and hash", func() {
+		Expect(RedactToken("")).To(BeEmpty())
+		Expect(RedactToken("short")).ToNot(ContainSubstring("short"))
+		Expect(RedactToken("a-much-longer-token")).To(HavePrefix("a-mu..."))
+		Expect(RedactToken("a-much-longer-token")).ToNot(Equal(RedactToken("a-much-longer-token2")))
+		Expect(fmt.Sprint(AuthResponse{Token: "a-much-longer-token"})).ToNot(ContainSubstring("a-much-longer-token"))
+	})
+})
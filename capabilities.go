@@ -0,0 +1,90 @@
+package hoist
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+const apiCapabilities = "api/{version}/filestorage/capabilities"
+
+// Capabilities describes which optional features the backend supports. Trash, Versioning, Search and Batch are
+// reported for callers, as hoist has no operations depending on them. Optional endpoints without a flag, such as
+// PatchFile, are still detected by probing, failing with ErrNotSupported.
+type Capabilities struct {
+	Trash      bool `json:"trash"`
+	Versioning bool `json:"versioning"`
+	Search     bool `json:"search"`
+	Batch      bool `json:"batch"`
+	// Ranges is set when downloads honour Range headers, allowing the fs package to serve ReadAt with ranged
+	// downloads rather than requiring a read cache
+	Ranges bool `json:"ranges"`
+	// MaxChunkSize is the largest upload chunk the backend accepts. Once it's been fetched, ChunkSize is capped to it.
+	MaxChunkSize int64 `json:"maxChunkSize"`
+}
+
+// BaselineCapabilities are assumed for backends which don't expose a capabilities document
+func BaselineCapabilities() Capabilities {
+	return Capabilities{
+		MaxChunkSize: defaultChunkSize,
+	}
+}
+
+type capabilitiesResponse struct {
+	defaultResponse
+	Capabilities Capabilities `json:"capabilities"`
+}
+
+// Capabilities returns the features supported by the backend, falling back to BaselineCapabilities when the
+// backend doesn't expose them. The result is cached on the client after the first successful call.
+func (c *client) Capabilities(ctx context.Context) (*Capabilities, error) {
+	c.capabilitiesMu.Lock()
+	defer c.capabilitiesMu.Unlock()
+
+	if c.capabilities != nil {
+		caps := *c.capabilities
+		return &caps, nil
+	}
+
+	caps, err := c.fetchCapabilities(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	c.capabilities = caps
+
+	result := *caps
+
+	return &result, nil
+}
+
+func (c *client) fetchCapabilities(ctx context.Context) (*Capabilities, error) {
+	res, err := c.doFeatureRequest(ctx, http.MethodGet, apiCapabilities, nil)
+
+	if errors.Is(err, ErrNotSupported) {
+		caps := BaselineCapabilities()
+		return &caps, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return nil, statusError(res)
+	}
+
+	var response capabilitiesResponse
+
+	if err := res.Decode(&response); err != nil {
+		return nil, err
+	}
+
+	// Only a limit the backend reports caps the chunk size, not the default
+	if response.Capabilities.MaxChunkSize > 0 {
+		c.maxChunkSize.Store(response.Capabilities.MaxChunkSize)
+	} else {
+		response.Capabilities.MaxChunkSize = defaultChunkSize
+	}
+
+	return &response.Capabilities, nil
+}
@@ -0,0 +1,98 @@
+package hoist
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Capabilities tests", func() {
+	It("Should decode and cache the capabilities document", func() {
+		var requests int
+
+		c, server := newTestClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+
+			Expect(r.URL.Path).To(Equal(testPath(apiCapabilities)))
+
+			_ = json.NewEncoder(w).Encode(capabilitiesResponse{
+				defaultResponse: defaultResponse{Success: true},
+				Capabilities:    Capabilities{Trash: true, Search: true, MaxChunkSize: 5 * 1024 * 1024},
+			})
+		}))
+		defer server.Close()
+
+		caps, err := c.Capabilities(context.Background())
+
+		Expect(err).To(BeNil())
+		Expect(caps.Trash).To(BeTrue())
+		Expect(caps.Search).To(BeTrue())
+		Expect(caps.Versioning).To(BeFalse())
+		Expect(caps.MaxChunkSize).To(Equal(int64(5 * 1024 * 1024)))
+
+		_, err = c.Capabilities(context.Background())
+
+		Expect(err).To(BeNil())
+		Expect(requests).To(Equal(1))
+	})
+	It("Should map every flag from the capabilities response", func() {
+		c, server := newTestClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"success":true,"capabilities":{"trash":true,"versioning":true,"search":true,` +
+				`"batch":true,"ranges":true,"maxChunkSize":1048576}}`))
+		}))
+		defer server.Close()
+
+		caps, err := c.Capabilities(context.Background())
+
+		Expect(err).To(BeNil())
+		Expect(*caps).To(Equal(Capabilities{
+			Trash:        true,
+			Versioning:   true,
+			Search:       true,
+			Batch:        true,
+			Ranges:       true,
+			MaxChunkSize: 1048576,
+		}))
+	})
+	It("Should cap the chunk size at the backend's limit", func() {
+		upload := &testUploadServer{}
+		mux := http.NewServeMux()
+
+		mux.HandleFunc(testPath(apiCapabilities), func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"success":true,"capabilities":{"maxChunkSize":4}}`))
+		})
+		mux.Handle("/", upload)
+
+		c, server := newTestClient(mux)
+		defer server.Close()
+
+		Expect(c.ChunkSize()).To(Equal(int64(defaultChunkSize)))
+
+		_, err := c.Capabilities(context.Background())
+
+		Expect(err).To(BeNil())
+		Expect(c.ChunkSize()).To(Equal(int64(4)))
+
+		data := []byte("0123456789")
+
+		_, err = c.ChunkedUpload(context.Background(), bytes.NewReader(data), "/file.txt", int64(len(data)))
+
+		Expect(err).To(BeNil())
+		Expect(upload.Chunks).To(HaveLen(3))
+		Expect(string(upload.Data())).To(Equal(string(data)))
+	})
+	It("Should fall back to the baseline when the endpoint is absent", func() {
+		c, server := newTestClient(http.NotFoundHandler())
+		defer server.Close()
+
+		caps, err := c.Capabilities(context.Background())
+
+		Expect(err).To(BeNil())
+		Expect(*caps).To(Equal(BaselineCapabilities()))
+		Expect(c.ChunkSize()).To(Equal(int64(defaultChunkSize)))
+	})
+})
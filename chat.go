@@ -0,0 +1,36 @@
+package hoist
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/namecrane/hoist/internal/validate"
+)
+
+// contextChatFiles is the upload context for chat attachments, counted as DiskUsage.ChatFiles
+const contextChatFiles = "chat-files"
+
+var ErrInvalidConversationID = validate.ErrInvalidConversationID
+
+// chatContextData links a chat file upload to its conversation
+type chatContextData struct {
+	ConversationID string `json:"conversationId"`
+}
+
+// UploadChatFile uploads a chat attachment to the conversation with the chunked uploader. Chat files aren't stored
+// in a folder, so the returned file has no FolderPath. The conversation ID must be non-empty, at most 128
+// characters, and have no whitespace, control characters or slashes, failing with ErrInvalidConversationID otherwise.
+func (c *client) UploadChatFile(ctx context.Context, in io.Reader, conversationID, fileName string, size int64) (*File, error) {
+	if err := validate.ConversationID(conversationID); err != nil {
+		return nil, err
+	}
+
+	if fileName == "" || strings.ContainsAny(fileName, "/\\") {
+		return nil, fmt.Errorf("invalid chat file name: %q", fileName)
+	}
+
+	return c.ChunkedUpload(ctx, in, "/"+fileName, size,
+		withUploadContext(contextChatFiles, chatContextData{ConversationID: conversationID}))
+}
@@ -0,0 +1,63 @@
+package hoist
+
+import (
+	"context"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Chat file tests", func() {
+	It("Should upload into the chat files context", func() {
+		upload := &testUploadServer{}
+
+		c, server := newTestClient(upload)
+		defer server.Close()
+
+		c.chunkSize = 4
+
+		file, err := c.UploadChatFile(context.Background(), strings.NewReader("attachment"), "conv-1", "photo.png", 10)
+
+		Expect(err).To(BeNil())
+		Expect(file.Size).To(Equal(int64(10)))
+		Expect(upload.Chunks).To(HaveLen(3))
+
+		for _, chunk := range upload.Chunks {
+			Expect(chunk.Fields["context"]).To(Equal("chat-files"))
+			Expect(chunk.Fields["contextData"]).To(MatchJSON(`{"conversationId":"conv-1"}`))
+			Expect(chunk.Fields["resumableFilename"]).To(Equal("photo.png"))
+		}
+
+		Expect(string(upload.Data())).To(Equal("attachment"))
+	})
+	It("Should keep the file storage context for regular uploads", func() {
+		upload := &testUploadServer{}
+
+		c, server := newTestClient(upload)
+		defer server.Close()
+
+		_, err := c.ChunkedUpload(context.Background(), strings.NewReader("data"), "/a/file.txt", 4)
+
+		Expect(err).To(BeNil())
+		Expect(upload.Chunks[0].Fields["context"]).To(Equal("file-storage"))
+		Expect(upload.Chunks[0].Fields["contextData"]).To(MatchJSON(`{"folder":"/a"}`))
+	})
+	It("Should reject invalid conversation IDs without uploading", func() {
+		upload := &testUploadServer{}
+
+		c, server := newTestClient(upload)
+		defer server.Close()
+
+		for _, id := range []string{"", "has space", "a/b", "tab\t", strings.Repeat("x", 129)} {
+			_, err := c.UploadChatFile(context.Background(), strings.NewReader("data"), id, "file.txt", 4)
+
+			Expect(err).To(MatchError(ErrInvalidConversationID), id)
+		}
+
+		_, err := c.UploadChatFile(context.Background(), strings.NewReader("data"), "conv-1", "a/b.txt", 4)
+
+		Expect(err).ToNot(BeNil())
+		Expect(upload.Chunks).To(BeEmpty())
+	})
+})
@@ -1,13 +1,31 @@
 package hoist
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"io"
 	"net/http"
 	"net/url"
 	"path"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultAPIVersion = "v1"
+	defaultAuthHeader = "Authorization"
+	defaultAuthScheme = "Bearer"
+
+	// MinChunkSize and MaxChunkSize bound the upload chunk size accepted by WithChunkSize
+	MinChunkSize = 1 << 20   // 1 MiB
+	MaxChunkSize = 100 << 20 // 100 MiB
 )
 
 var (
@@ -15,10 +33,55 @@ var (
 	ErrUnexpectedStatus = errors.New("unexpected status")
 	ErrNoFolder         = errors.New("no folder found")
 	ErrNoFile           = errors.New("no file found")
+	ErrPartialFailure   = errors.New("operation partially failed")
+	ErrNotSupported     = errors.New("not supported")
+	ErrNotDir           = errors.New("not a directory")
+	ErrOperationFailed  = errors.New("operation failed")
+	ErrPermissionDenied = errors.New("permission denied")
+	ErrRootOperation    = errors.New("operation not allowed on the root folder")
+	ErrInvalidChunkSize = errors.New("invalid chunk size")
 )
 
 type ClientOption func(*client)
 
+// WithAPIVersion sets the API version used for versioned endpoints, such as "v2". Defaults to "v1".
+// Authentication is requested by the AuthManager, which has its own version, see WithAuthAPIVersion.
+func WithAPIVersion(version string) ClientOption {
+	return func(c *client) {
+		c.apiVersion = version
+	}
+}
+
+// WithRequestModifier adds a function which can arbitrarily modify outgoing API requests, such as for signing or
+// custom auth schemes. Modifiers run last, after all standard headers and request options have been applied,
+// in the order they were added. Returning an error aborts the request.
+func WithRequestModifier(modifier func(*http.Request) error) ClientOption {
+	return func(c *client) {
+		c.requestModifiers = append(c.requestModifiers, modifier)
+	}
+}
+
+// WithRequestSigner sets a function which signs outgoing API requests, such as with an HMAC for deployments which
+// require signed requests alongside bearer tokens. The signer runs after every header, request option and modifier
+// (see WithRequestModifier) has been applied and the body is final, including any compression, so it can sign the
+// method, path and payload. The body can be read with req.GetBody without consuming it. Returning an error aborts
+// the request.
+func WithRequestSigner(signer func(*http.Request) error) ClientOption {
+	return func(c *client) {
+		c.requestSigner = signer
+	}
+}
+
+// WithAuthScheme overrides how the token is sent, for backends or gateways which don't expect
+// "Authorization: Bearer <token>". The header is set to the scheme followed by a space and the token,
+// or just the token if scheme is empty, such as WithAuthScheme("X-Api-Token", "").
+func WithAuthScheme(header, scheme string) ClientOption {
+	return func(c *client) {
+		c.authHeader = header
+		c.authScheme = scheme
+	}
+}
+
 // WithHttpClient defines the http client to use for http requests
 func WithHttpClient(httpClient *http.Client) ClientOption {
 	return func(c *client) {
@@ -26,23 +89,150 @@ func WithHttpClient(httpClient *http.Client) ClientOption {
 	}
 }
 
+// WithCompression requests gzip compressed responses, which are transparently decompressed
+func WithCompression() ClientOption {
+	return func(c *client) {
+		c.compression = true
+	}
+}
+
+// WithRequestCompression gzip compresses JSON request bodies over minSize bytes (such as large delete ID lists).
+// Only use this when the backend accepts gzip encoded requests.
+func WithRequestCompression(minSize int) ClientOption {
+	return func(c *client) {
+		c.requestCompression = true
+		c.requestCompressionMin = minSize
+	}
+}
+
+// WithIDGenerator overrides how upload identifiers (resumableIdentifier) are generated, defaulting to UUIDv7
+func WithIDGenerator(generator func() (string, error)) ClientOption {
+	return func(c *client) {
+		c.newID = generator
+	}
+}
+
+// WithChunkSize sets how many bytes ChunkedUpload sends per chunk, defaulting to 15 MB. Smaller chunks suit
+// deployments behind proxies which limit request bodies, while larger chunks reduce round-trips on fast links.
+// The size must be between MinChunkSize and MaxChunkSize: NewCheckedClient rejects other sizes with
+// ErrInvalidChunkSize, while NewClient logs a warning and keeps the default.
+func WithChunkSize(size int64) ClientOption {
+	return func(c *client) {
+		if size < MinChunkSize || size > MaxChunkSize {
+			c.optionErrs = append(c.optionErrs, fmt.Errorf("%w: %d bytes, must be between %d and %d",
+				ErrInvalidChunkSize, size, MinChunkSize, MaxChunkSize))
+			return
+		}
+
+		c.chunkSize = size
+	}
+}
+
+// WithChunkRetry re-sends upload chunks which fail with a NetworkError or 5xx response for every upload, making up to
+// maxAttempts attempts in total and waiting baseDelay before the first retry, doubling the wait each time. Other
+// failures, such as an AuthError, a request signer or modifier error or a 4xx response, aren't retried.
+// WithUploadRetry overrides it for a single upload. maxAttempts must be at least 1 and baseDelay can't be negative:
+// NewCheckedClient rejects other values, while NewClient logs a warning and ignores them.
+func WithChunkRetry(maxAttempts int, baseDelay time.Duration) ClientOption {
+	return func(c *client) {
+		if maxAttempts < 1 || baseDelay < 0 {
+			c.optionErrs = append(c.optionErrs, fmt.Errorf("invalid chunk retry: %d attempts, %s base delay",
+				maxAttempts, baseDelay))
+			return
+		}
+
+		c.chunkRetry = chunkRetry{attempts: maxAttempts, base: baseDelay}
+	}
+}
+
 type Client interface {
 	FileClient
+	Capabilities(ctx context.Context) (*Capabilities, error)
+	ClockSkew() time.Duration
+	ChunkSize() int64
+	Drain(ctx context.Context) error
 }
 
 // client is the Hoist API client implementation
 type client struct {
-	apiURL      string
-	authManager AuthManager
-	client      *http.Client
+	apiURL            string
+	apiVersion        string
+	authManager       AuthManager
+	authHeader        string
+	authScheme        string
+	client            *http.Client
+	combineRetryDelay time.Duration
+	newID             func() (string, error)
+	requestModifiers  []func(*http.Request) error
+	requestSigner     func(*http.Request) error
+	chunkSize         int64
+	chunkRetry        chunkRetry
+	filesCoalescer    *filesCoalescer
+	ensureRoot        bool
+
+	// Clock skew from response Date headers, see WithServerTime
+	serverTime bool
+	clockSkew  atomic.Int64
+
+	capabilitiesMu sync.Mutex
+	capabilities   *Capabilities
+	// maxChunkSize is the backend's reported MaxChunkSize once Capabilities has been fetched, capping ChunkSize
+	maxChunkSize atomic.Int64
+
+	// unsupported caches feature endpoints which returned a 404, see doFeatureRequest
+	unsupported sync.Map
+
+	// fileFolders maps file IDs to the folder they were last found in, see findFiles
+	fileFolders sync.Map
+
+	compression           bool
+	requestCompression    bool
+	requestCompressionMin int
+
+	// Errors from invalid options, see NewCheckedClient
+	optionErrs []error
+
+	// In-flight uploads, see Drain
+	uploadsMu sync.Mutex
+	uploads   sync.WaitGroup
+	draining  bool
 }
 
-// NewClient creates a new Hoist client with the specified URL and auth manager
+// NewClient creates a new Hoist client with the specified URL and auth manager.
+// Invalid options are logged and ignored, use NewCheckedClient to reject them instead.
 func NewClient(apiURL string, authManager AuthManager, opts ...ClientOption) Client {
+	c := newClient(apiURL, authManager, opts...)
+
+	for _, err := range c.optionErrs {
+		log.WithError(err).Warning("Ignoring invalid client option")
+	}
+
+	return c
+}
+
+// NewCheckedClient creates a new Hoist client like NewClient, returning an error if any option is invalid,
+// such as ErrInvalidChunkSize from WithChunkSize
+func NewCheckedClient(apiURL string, authManager AuthManager, opts ...ClientOption) (Client, error) {
+	c := newClient(apiURL, authManager, opts...)
+
+	if err := errors.Join(c.optionErrs...); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func newClient(apiURL string, authManager AuthManager, opts ...ClientOption) *client {
 	c := &client{
-		apiURL:      apiURL,
-		authManager: authManager,
-		client:      http.DefaultClient,
+		apiURL:            apiURL,
+		apiVersion:        defaultAPIVersion,
+		authManager:       authManager,
+		authHeader:        defaultAuthHeader,
+		authScheme:        defaultAuthScheme,
+		client:            http.DefaultClient,
+		combineRetryDelay: combineRetryDelay,
+		newID:             newUUID,
+		chunkSize:         defaultChunkSize,
 	}
 
 	for _, opt := range opts {
@@ -52,6 +242,17 @@ func NewClient(apiURL string, authManager AuthManager, opts ...ClientOption) Cli
 	return c
 }
 
+// newUUID generates a new UUIDv7 string
+func newUUID() (string, error) {
+	id, err := uuid.NewV7()
+
+	if err != nil {
+		return "", err
+	}
+
+	return id.String(), nil
+}
+
 // defaultResponse represents a default API response, containing Success and optionally Message
 type defaultResponse struct {
 	Success bool   `json:"success"`
@@ -59,18 +260,28 @@ type defaultResponse struct {
 }
 
 func (c *client) String() string {
-	return "Hoist API (Endpoint: " + c.apiURL + ")"
+	return "Hoist API (Endpoint: " + c.Endpoint() + ")"
 }
 
-// apiUrl joins the base API URL with the path specified
+// Endpoint returns the base API URL the client was configured with
+func (c *client) Endpoint() string {
+	return c.apiURL
+}
+
+// apiUrl joins the base API URL with the path specified, filling in the API version
 func (c *client) apiUrl(subPath string) (string, error) {
-	u, err := url.Parse(c.apiURL)
+	return versionedURL(c.apiURL, subPath, c.apiVersion)
+}
+
+// versionedURL joins apiURL with subPath, replacing {version} in subPath with version
+func versionedURL(apiURL, subPath, version string) (string, error) {
+	u, err := url.Parse(apiURL)
 
 	if err != nil {
 		return "", err
 	}
 
-	u.Path = path.Join(u.Path, subPath)
+	u.Path = path.Join(u.Path, strings.Replace(subPath, "{version}", version, 1))
 
 	return u.String(), nil
 }
@@ -81,10 +292,23 @@ func (c *client) doRequest(ctx context.Context, method, path string, body any, o
 	token, err := c.authManager.GetToken(ctx)
 
 	if err != nil {
-		return nil, fmt.Errorf("failed to retrieve token: %w", err)
+		return nil, &AuthError{Err: fmt.Errorf("failed to retrieve token: %w", err)}
 	}
 
-	opts = append(opts, WithHeader("Authorization", "Bearer "+token))
+	if c.authScheme != "" {
+		token = c.authScheme + " " + token
+	}
+
+	opts = append(opts, WithHeader(c.authHeader, token))
+
+	if c.compression {
+		// Prepend so that callers can still override it (for example, ranged downloads)
+		opts = append([]RequestOpt{WithHeader("Accept-Encoding", "gzip")}, opts...)
+	}
+
+	if c.requestCompression {
+		opts = append(opts, WithGzipBody(c.requestCompressionMin))
+	}
 
 	apiUrl, err := c.apiUrl(path)
 
@@ -92,7 +316,82 @@ func (c *client) doRequest(ctx context.Context, method, path string, body any, o
 		return nil, err
 	}
 
-	return doHttpRequest(ctx, c.client, method, apiUrl, body, opts...)
+	req, err := newHttpRequest(ctx, method, apiUrl, body, opts...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, modifier := range c.requestModifiers {
+		if err := modifier(req); err != nil {
+			return nil, fmt.Errorf("request modifier failed: %w", err)
+		}
+	}
+
+	if c.requestSigner != nil {
+		if err := c.requestSigner(req); err != nil {
+			return nil, fmt.Errorf("request signer failed: %w", err)
+		}
+	}
+
+	res, err := sendHttpRequestTimeout(c.client, req)
+
+	if err != nil {
+		return nil, err
+	}
+
+	c.recordClockSkew(res.Response, time.Now())
+
+	return res, nil
+}
+
+// doFeatureRequest performs a request against an optional endpoint which may not exist on older backends.
+// A 404 without the API's JSON envelope means the route itself is missing, marking the endpoint as unsupported and
+// returning ErrNotSupported for this and any later calls, so callers can feature-detect with errors.Is.
+// A 404 with the envelope is an ordinary missing resource (such as an unknown file ID), which is returned as a
+// plain *APIError without affecting later calls. Only use it for genuinely optional endpoints.
+func (c *client) doFeatureRequest(ctx context.Context, method, endpoint string, body any, opts ...RequestOpt) (*Response, error) {
+	if _, ok := c.unsupported.Load(endpoint); ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotSupported, endpoint)
+	}
+
+	res, err := c.doRequest(ctx, method, endpoint, body, opts...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode != http.StatusNotFound {
+		return res, nil
+	}
+
+	data, _ := io.ReadAll(io.LimitReader(res.Body, 64*1024))
+
+	_ = res.Close()
+
+	if !isAPIEnvelope(data) {
+		c.unsupported.Store(endpoint, true)
+
+		return nil, fmt.Errorf("%w: %s", ErrNotSupported, endpoint)
+	}
+
+	res.Body = io.NopCloser(bytes.NewReader(data))
+
+	return nil, statusError(res)
+}
+
+// isAPIEnvelope reports whether body is a JSON object in the API's response format, with a success or message field
+func isAPIEnvelope(body []byte) bool {
+	var envelope map[string]json.RawMessage
+
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return false
+	}
+
+	_, success := envelope["success"]
+	_, message := envelope["message"]
+
+	return success || message
 }
 
 // ParsePath parses the last segment off the specified path, representing either a file or directory
@@ -100,6 +399,12 @@ func (c *client) ParsePath(path string) (basePath, lastSegment string) {
 	return ParsePath(path)
 }
 
+// IsRoot reports whether p refers to the root folder, such as "", "/" or "/a/..".
+// Deleting, moving or renaming the root is rejected with ErrRootOperation before any request is sent.
+func IsRoot(p string) bool {
+	return path.Clean("/"+strings.ReplaceAll(p, "\\", "/")) == "/"
+}
+
 // ParsePath parses the last segment off the specified path, representing either a file or directory
 func ParsePath(path string) (basePath, lastSegment string) {
 	trimmedPath := strings.Trim(path, "/")
@@ -1,6 +1,18 @@
 package hoist
 
 import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 )
@@ -24,4 +36,272 @@ var _ = Describe("Client tests", func() {
 		Expect(path).To(Equal("/"))
 		Expect(sub).To(Equal("something"))
 	})
+	It("Should build versioned endpoints from the API version", func() {
+		c := NewClient("https://example.com", &testAuthManager{}, WithAPIVersion("v2")).(*client)
+
+		u, err := c.apiUrl(apiFolders)
+
+		Expect(err).To(BeNil())
+		Expect(u).To(Equal("https://example.com/api/v2/filestorage/folders"))
+
+		u, err = c.apiUrl(apiUpload)
+
+		Expect(err).To(BeNil())
+		Expect(u).To(Equal("https://example.com/api/upload"))
+	})
+	It("Should default to the v1 API", func() {
+		c := NewClient("https://example.com", &testAuthManager{}).(*client)
+
+		u, err := c.apiUrl(apiDiskUsage)
+
+		Expect(err).To(BeNil())
+		Expect(u).To(Equal("https://example.com/api/v1/filestorage/disk-usage-summary"))
+	})
+	It("Should map 404s on missing feature routes to ErrNotSupported", func() {
+		var requests int
+
+		c, server := newTestClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			http.NotFound(w, r)
+		}))
+		defer server.Close()
+
+		ctx := context.Background()
+
+		err := c.PatchFile(ctx, "1", strings.NewReader("data"), 0, 4)
+		Expect(errors.Is(err, ErrNotSupported)).To(BeTrue())
+
+		Expect(requests).To(Equal(1))
+
+		// Unsupported endpoints are cached, so no further requests are made
+		err = c.PatchFile(ctx, "2", strings.NewReader("data"), 0, 4)
+		Expect(errors.Is(err, ErrNotSupported)).To(BeTrue())
+		Expect(requests).To(Equal(1))
+	})
+	It("Should treat 404s with the API envelope as a missing resource", func() {
+		var requests int
+
+		c, server := newTestClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+
+			if strings.Contains(r.URL.Path, "/missing/") {
+				w.WriteHeader(http.StatusNotFound)
+				_ = json.NewEncoder(w).Encode(defaultResponse{Success: false, Message: "File not found"})
+				return
+			}
+
+			_ = json.NewEncoder(w).Encode(defaultResponse{Success: true})
+		}))
+		defer server.Close()
+
+		ctx := context.Background()
+
+		err := c.PatchFile(ctx, "missing", strings.NewReader("data"), 0, 4)
+
+		var apiErr *APIError
+
+		Expect(errors.Is(err, ErrNotSupported)).To(BeFalse())
+		Expect(errors.As(err, &apiErr)).To(BeTrue())
+		Expect(apiErr.Status).To(Equal(http.StatusNotFound))
+		Expect(c.PatchFile(ctx, "1", strings.NewReader("data"), 0, 4)).To(Succeed())
+	})
+	It("Should keep editing files after one file isn't found", func() {
+		c, server := newTestClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if strings.Contains(r.URL.Path, "/missing/") {
+				http.NotFound(w, r)
+				return
+			}
+
+			_ = json.NewEncoder(w).Encode(defaultResponse{Success: true})
+		}))
+		defer server.Close()
+
+		ctx := context.Background()
+
+		err := c.EditFile(ctx, "missing", EditFileParams{})
+
+		Expect(errors.Is(err, ErrNotSupported)).To(BeFalse())
+		Expect(err).To(MatchError(ErrUnexpectedStatus))
+		Expect(c.EditFile(ctx, "1", EditFileParams{})).To(Succeed())
+	})
+	It("Should run request modifiers after the standard headers", func() {
+		var signature string
+
+		c, server := newTestClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			signature = r.Header.Get("X-Signature")
+
+			_ = json.NewEncoder(w).Encode(diskUsageResponse{DiskUsage: &DiskUsage{}})
+		}), WithRequestModifier(func(r *http.Request) error {
+			r.Header.Set("X-Signature", r.Method+" "+r.Header.Get("Authorization"))
+			return nil
+		}))
+		defer server.Close()
+
+		_, err := c.DiskUsageSummary(context.Background())
+
+		Expect(err).To(BeNil())
+		Expect(signature).To(Equal("GET Bearer test-token"))
+	})
+	It("Should send the token with a custom auth header and scheme", func() {
+		var header, authorization string
+
+		c, server := newTestClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header = r.Header.Get("X-Api-Token")
+			authorization = r.Header.Get("Authorization")
+
+			_ = json.NewEncoder(w).Encode(diskUsageResponse{DiskUsage: &DiskUsage{}})
+		}), WithAuthScheme("X-Api-Token", "Token"))
+		defer server.Close()
+
+		_, err := c.DiskUsageSummary(context.Background())
+
+		Expect(err).To(BeNil())
+		Expect(header).To(Equal("Token test-token"))
+		Expect(authorization).To(BeEmpty())
+	})
+	It("Should abort the request when a modifier fails", func() {
+		var requests int
+
+		modifierErr := errors.New("signing failed")
+
+		c, server := newTestClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+		}), WithRequestModifier(func(r *http.Request) error {
+			return modifierErr
+		}))
+		defer server.Close()
+
+		_, err := c.DiskUsageSummary(context.Background())
+
+		Expect(errors.Is(err, modifierErr)).To(BeTrue())
+		Expect(requests).To(Equal(0))
+	})
+	It("Should sign the final request, including the compressed body", func() {
+		key := []byte("secret")
+
+		sign := func(method, path string, body []byte) string {
+			mac := hmac.New(sha256.New, key)
+			mac.Write([]byte(method + "\n" + path + "\n"))
+			mac.Write(body)
+
+			return hex.EncodeToString(mac.Sum(nil))
+		}
+
+		var valid bool
+
+		c, server := newTestClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+
+			valid = r.Header.Get("Content-Encoding") == "gzip" &&
+				r.Header.Get("X-Modified") == "true" &&
+				hmac.Equal([]byte(r.Header.Get("X-Signature")), []byte(sign(r.Method, r.URL.Path, body)))
+
+			_ = json.NewEncoder(w).Encode(defaultResponse{Success: true})
+		}), WithRequestCompression(0), WithRequestModifier(func(r *http.Request) error {
+			r.Header.Set("X-Modified", "true")
+			return nil
+		}), WithRequestSigner(func(r *http.Request) error {
+			if r.Header.Get("X-Modified") != "true" {
+				return errors.New("signed before modifiers ran")
+			}
+
+			body, err := r.GetBody()
+
+			if err != nil {
+				return err
+			}
+
+			data, err := io.ReadAll(body)
+
+			if err != nil {
+				return err
+			}
+
+			r.Header.Set("X-Signature", sign(r.Method, r.URL.Path, data))
+
+			return nil
+		}))
+		defer server.Close()
+
+		Expect(c.DeleteFiles(context.Background(), "one")).To(Succeed())
+		Expect(valid).To(BeTrue())
+	})
+	Describe("Chunk size", func() {
+		It("Should reject chunk sizes out of range with NewCheckedClient", func() {
+			for _, size := range []int64{-1, 0, 1024, MinChunkSize - 1, MaxChunkSize + 1} {
+				_, err := NewCheckedClient("https://hoist.test", nil, WithChunkSize(size))
+
+				Expect(err).To(MatchError(ErrInvalidChunkSize), strconv.FormatInt(size, 10))
+			}
+
+			c, err := NewCheckedClient("https://hoist.test", nil, WithChunkSize(MinChunkSize))
+
+			Expect(err).To(BeNil())
+			Expect(c.ChunkSize()).To(Equal(int64(MinChunkSize)))
+		})
+		It("Should keep the default chunk size for invalid sizes with NewClient", func() {
+			c := NewClient("https://hoist.test", nil, WithChunkSize(0))
+
+			Expect(c.ChunkSize()).To(Equal(int64(defaultChunkSize)))
+		})
+		It("Should use the configured chunk size for every chunk", func() {
+			upload := &testUploadServer{}
+
+			c, server := newTestClient(upload, WithChunkSize(MinChunkSize))
+			defer server.Close()
+
+			data := bytes.Repeat([]byte("x"), MinChunkSize*2+MinChunkSize/2)
+
+			_, err := c.ChunkedUpload(context.Background(), bytes.NewReader(data), "/a/file.bin", int64(len(data)))
+
+			Expect(err).To(BeNil())
+			Expect(upload.Chunks).To(HaveLen(3))
+
+			for i, chunk := range upload.Chunks {
+				Expect(chunk.Fields["resumableChunkSize"]).To(Equal(strconv.Itoa(MinChunkSize)))
+				Expect(chunk.Fields["resumableTotalChunks"]).To(Equal("3"))
+				Expect(chunk.Fields["resumableCurrentChunkSize"]).To(Equal(strconv.Itoa(len(chunk.Data))), strconv.Itoa(i))
+			}
+
+			Expect(upload.Chunks[2].Data).To(HaveLen(MinChunkSize / 2))
+			Expect(upload.Data()).To(Equal(data))
+		})
+		It("Should use the configured chunk size for concurrent and ReaderAt uploads", func() {
+			upload := &testUploadServer{}
+
+			c, server := newTestClient(upload, WithChunkSize(MinChunkSize))
+			defer server.Close()
+
+			data := bytes.Repeat([]byte("y"), MinChunkSize*3+1)
+
+			for _, uploadFn := range []func() (*File, error){
+				func() (*File, error) {
+					return c.ChunkedUpload(context.Background(), bytes.NewReader(data), "/a/file.bin", int64(len(data)),
+						WithChunkConcurrency(2), WithFinalChunkLast())
+				},
+				func() (*File, error) {
+					return c.ChunkedUploadAt(context.Background(), bytes.NewReader(data), "/a/file.bin", int64(len(data)),
+						WithChunkConcurrency(2), WithFinalChunkLast())
+				},
+			} {
+				upload.Chunks = nil
+
+				_, err := uploadFn()
+
+				Expect(err).To(BeNil())
+				Expect(upload.Chunks).To(HaveLen(4))
+
+				for _, chunk := range upload.Chunks {
+					Expect(chunk.Fields["resumableChunkSize"]).To(Equal(strconv.Itoa(MinChunkSize)))
+					Expect(chunk.Fields["resumableTotalChunks"]).To(Equal("4"))
+					Expect(chunk.Fields["resumableCurrentChunkSize"]).To(Equal(strconv.Itoa(len(chunk.Data))))
+				}
+			}
+		})
+		It("Should explain the allowed range when rejecting a chunk size", func() {
+			_, err := NewCheckedClient("https://hoist.test", nil, WithChunkSize(512<<10))
+
+			Expect(err).To(MatchError(ContainSubstring("524288 bytes, must be between 1048576 and 104857600")))
+		})
+	})
 })
@@ -0,0 +1,147 @@
+package hoist
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// WithGetFilesCoalescing batches GetFiles calls made within window of each other into a single request,
+// distributing the results to each caller. A batch is sent early once it holds maxBatch IDs.
+// Calls are only coalesced with others for the same user (see contextUsername).
+func WithGetFilesCoalescing(window time.Duration, maxBatch int) ClientOption {
+	return func(c *client) {
+		c.filesCoalescer = &filesCoalescer{
+			window:   window,
+			maxBatch: maxBatch,
+			pending:  make(map[string]*filesBatch),
+			fetch: func(ctx context.Context, ids []string) ([]File, error) {
+				return c.GetFilesWithFields(ctx, nil, ids...)
+			},
+		}
+	}
+}
+
+// filesBatch is a set of IDs waiting to be fetched together
+type filesBatch struct {
+	ctx   context.Context
+	timer *time.Timer
+	ids   []string
+	seen  map[string]struct{}
+	once  sync.Once
+	done  chan struct{}
+	files map[string]File
+	err   error
+}
+
+// filesCoalescer merges concurrent GetFiles calls into batches
+type filesCoalescer struct {
+	mu       sync.Mutex
+	window   time.Duration
+	maxBatch int
+	pending  map[string]*filesBatch
+	fetch    func(ctx context.Context, ids []string) ([]File, error)
+}
+
+// get adds ids to the pending batch for the context's user, waiting for the batch to be fetched
+func (f *filesCoalescer) get(ctx context.Context, ids []string) ([]File, error) {
+	username, err := contextUsername(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var batches []*filesBatch
+
+	f.mu.Lock()
+
+	for _, id := range ids {
+		batch := f.pending[username]
+
+		if batch == nil {
+			batch = &filesBatch{
+				// The batch outlives the first caller's cancellation, but keeps its values for authentication
+				ctx:  context.WithoutCancel(ctx),
+				seen: make(map[string]struct{}),
+				done: make(chan struct{}),
+			}
+
+			f.pending[username] = batch
+
+			batch.timer = time.AfterFunc(f.window, func() {
+				f.flush(username, batch)
+			})
+		}
+
+		if len(batches) == 0 || batches[len(batches)-1] != batch {
+			batches = append(batches, batch)
+		}
+
+		if _, ok := batch.seen[id]; ok {
+			continue
+		}
+
+		batch.seen[id] = struct{}{}
+		batch.ids = append(batch.ids, id)
+
+		if f.maxBatch > 0 && len(batch.ids) >= f.maxBatch {
+			delete(f.pending, username)
+
+			go f.flush(username, batch)
+		}
+	}
+
+	f.mu.Unlock()
+
+	var files []File
+
+	for _, batch := range batches {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-batch.done:
+		}
+
+		if batch.err != nil {
+			return nil, batch.err
+		}
+	}
+
+	for _, id := range ids {
+		for _, batch := range batches {
+			if file, ok := batch.files[id]; ok {
+				files = append(files, file)
+				break
+			}
+		}
+	}
+
+	return files, nil
+}
+
+// flush fetches the batch, if it hasn't been already
+func (f *filesCoalescer) flush(username string, batch *filesBatch) {
+	f.mu.Lock()
+
+	if f.pending[username] == batch {
+		delete(f.pending, username)
+	}
+
+	batch.timer.Stop()
+
+	f.mu.Unlock()
+
+	batch.once.Do(func() {
+		files, err := f.fetch(batch.ctx, batch.ids)
+
+		batch.files = make(map[string]File, len(files))
+
+		for _, file := range files {
+			batch.files[file.ID] = file
+		}
+
+		batch.err = err
+
+		close(batch.done)
+	})
+}
@@ -0,0 +1,77 @@
+package hoist
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("GetFiles coalescing tests", func() {
+	var calls atomic.Int32
+
+	filesHandler := func() http.Handler {
+		calls.Store(0)
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+
+			var req filesRequest
+
+			_ = json.NewDecoder(r.Body).Decode(&req)
+
+			var response ListResponse
+
+			for _, id := range req.FileIDs {
+				response.Files = append(response.Files, File{ID: id, Name: id + ".txt"})
+			}
+
+			_ = json.NewEncoder(w).Encode(response)
+		})
+	}
+
+	getConcurrently := func(c *client, n int) {
+		var wg sync.WaitGroup
+
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+
+			go func(id string) {
+				defer GinkgoRecover()
+				defer wg.Done()
+
+				files, err := c.GetFiles(context.Background(), id)
+
+				Expect(err).To(BeNil())
+				Expect(files).To(HaveLen(1))
+				Expect(files[0].Name).To(Equal(id + ".txt"))
+			}(strconv.Itoa(i % 10))
+		}
+
+		wg.Wait()
+	}
+
+	It("Should coalesce concurrent single ID requests", func() {
+		c, server := newTestClient(filesHandler(), WithGetFilesCoalescing(50*time.Millisecond, 100))
+		defer server.Close()
+
+		getConcurrently(c, 20)
+
+		Expect(calls.Load()).To(BeNumerically("<", 20))
+	})
+	It("Should send batches early once they are full", func() {
+		c, server := newTestClient(filesHandler(), WithGetFilesCoalescing(time.Hour, 2))
+		defer server.Close()
+
+		// Only 10 distinct IDs are requested, so five full batches are sent without waiting for the window
+		getConcurrently(c, 10)
+
+		Expect(calls.Load()).To(Equal(int32(5)))
+	})
+})
@@ -0,0 +1,137 @@
+package hoist
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+const gzipFileType = "application/gzip"
+
+// compressedExtensions are file types which are already compressed, and won't shrink further
+var compressedExtensions = map[string]bool{
+	".gz": true, ".tgz": true, ".zip": true, ".7z": true, ".rar": true, ".bz2": true, ".xz": true, ".zst": true,
+	".jpg": true, ".jpeg": true, ".png": true, ".gif": true, ".webp": true, ".heic": true,
+	".mp3": true, ".mp4": true, ".m4a": true, ".mkv": true, ".mov": true, ".webm": true, ".ogg": true,
+	".pdf": true, ".docx": true, ".xlsx": true, ".pptx": true,
+}
+
+// gzipUpload compresses fileSize bytes of in into a temporary file, returning a reader of the compressed data and its
+// size. The whole file is compressed before uploading, as the compressed size must be known up front, but it's
+// streamed through the temporary directory (see os.TempDir) so memory use doesn't grow with the file size.
+// If the file type is already compressed, or compression doesn't shrink the data, ok is false and the returned
+// reader yields the original data: in is rewound if it's an io.Seeker, otherwise it's spooled to a second temporary
+// file while compressing. cleanup removes the temporary files once the upload is done, and must always be called.
+func gzipUpload(in io.Reader, filePath string, fileSize int64) (out io.Reader, size int64, ok bool, cleanup func(), err error) {
+	var temps []*os.File
+
+	cleanup = func() {
+		for _, f := range temps {
+			_ = f.Close()
+			_ = os.Remove(f.Name())
+		}
+	}
+
+	if compressedExtensions[strings.ToLower(path.Ext(filePath))] {
+		return in, fileSize, false, cleanup, nil
+	}
+
+	defer func() {
+		if err != nil {
+			cleanup()
+		}
+	}()
+
+	createTemp := func() (*os.File, error) {
+		f, err := os.CreateTemp("", "hoist-gzip-*")
+
+		if err == nil {
+			temps = append(temps, f)
+		}
+
+		return f, err
+	}
+
+	compressedFile, err := createTemp()
+
+	if err != nil {
+		return nil, 0, false, cleanup, err
+	}
+
+	src := io.LimitReader(in, fileSize)
+
+	// Keep the original data for when compression doesn't help, without holding it in memory
+	seeker, seekable := in.(io.Seeker)
+
+	var start int64
+	var original *os.File
+
+	if seekable {
+		if start, err = seeker.Seek(0, io.SeekCurrent); err != nil {
+			seekable = false
+		}
+	}
+
+	if !seekable {
+		if original, err = createTemp(); err != nil {
+			return nil, 0, false, cleanup, err
+		}
+
+		src = io.TeeReader(src, original)
+	}
+
+	gz := gzip.NewWriter(compressedFile)
+
+	n, err := io.Copy(gz, src)
+
+	if err != nil {
+		return nil, 0, false, cleanup, err
+	}
+
+	if err := gz.Close(); err != nil {
+		return nil, 0, false, cleanup, err
+	}
+
+	compressedSize, err := compressedFile.Seek(0, io.SeekCurrent)
+
+	if err != nil {
+		return nil, 0, false, cleanup, err
+	}
+
+	if compressedSize >= n {
+		if seekable {
+			if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+				return nil, 0, false, cleanup, err
+			}
+
+			return io.LimitReader(in, n), n, false, cleanup, nil
+		}
+
+		if _, err := original.Seek(0, io.SeekStart); err != nil {
+			return nil, 0, false, cleanup, err
+		}
+
+		return original, n, false, cleanup, nil
+	}
+
+	if _, err := compressedFile.Seek(0, io.SeekStart); err != nil {
+		return nil, 0, false, cleanup, err
+	}
+
+	return compressedFile, compressedSize, true, cleanup, nil
+}
+
+// DecompressGzip wraps a download stream of a file uploaded with WithUploadGzip, decompressing it as it's read.
+// Closing the returned reader closes rc.
+func DecompressGzip(rc io.ReadCloser) (io.ReadCloser, error) {
+	gz, err := gzip.NewReader(rc)
+
+	if err != nil {
+		_ = rc.Close()
+		return nil, err
+	}
+
+	return &gzipBody{Reader: gz, body: rc}, nil
+}
@@ -0,0 +1,94 @@
+package hoist
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"io"
+	"net/http/httptest"
+	"os"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Compression tests", func() {
+	var upload *testUploadServer
+	var c *client
+
+	BeforeEach(func() {
+		upload = &testUploadServer{}
+
+		var server *httptest.Server
+
+		c, server = newTestClient(upload)
+		DeferCleanup(server.Close)
+	})
+
+	It("Should round trip a gzip compressed upload", func() {
+		data := bytes.Repeat([]byte("compress me please "), 1000)
+
+		file, err := c.ChunkedUpload(context.Background(), bytes.NewReader(data), "/notes.txt", int64(len(data)), WithUploadGzip())
+
+		Expect(err).To(BeNil())
+		Expect(file.Name).To(Equal("notes.txt.gz"))
+		Expect(file.Size).To(BeNumerically("<", len(data)))
+		Expect(upload.Chunks[0].Fields["resumableType"]).To(Equal(gzipFileType))
+
+		r, err := DecompressGzip(io.NopCloser(bytes.NewReader(upload.Data())))
+
+		Expect(err).To(BeNil())
+
+		decompressed, err := io.ReadAll(r)
+
+		Expect(err).To(BeNil())
+		Expect(r.Close()).To(Succeed())
+		Expect(decompressed).To(Equal(data))
+	})
+	It("Should skip already compressed file types", func() {
+		data := bytes.Repeat([]byte("not really a png "), 1000)
+
+		file, err := c.ChunkedUpload(context.Background(), bytes.NewReader(data), "/image.png", int64(len(data)), WithUploadGzip())
+
+		Expect(err).To(BeNil())
+		Expect(file.Name).To(Equal("image.png"))
+		Expect(upload.Data()).To(Equal(data))
+	})
+	It("Should skip compression when it doesn't shrink the data", func() {
+		data := make([]byte, 4096)
+
+		_, err := rand.Read(data)
+
+		Expect(err).To(BeNil())
+
+		file, err := c.ChunkedUpload(context.Background(), bytes.NewReader(data), "/random.bin", int64(len(data)), WithUploadGzip())
+
+		Expect(err).To(BeNil())
+		Expect(file.Name).To(Equal("random.bin"))
+		Expect(upload.Data()).To(Equal(data))
+	})
+	It("Should compress through temporary files and remove them afterwards", func() {
+		dir := GinkgoT().TempDir()
+
+		GinkgoT().Setenv("TMPDIR", dir)
+
+		random := make([]byte, 4096)
+
+		_, err := rand.Read(random)
+
+		Expect(err).To(BeNil())
+
+		// Readers which can't seek have their original data spooled in case compression doesn't help
+		file, err := c.ChunkedUpload(context.Background(), struct{ io.Reader }{bytes.NewReader(random)}, "/random.bin",
+			int64(len(random)), WithUploadGzip())
+
+		Expect(err).To(BeNil())
+		Expect(file.Name).To(Equal("random.bin"))
+		Expect(upload.Data()).To(Equal(random))
+
+		entries, err := os.ReadDir(dir)
+
+		Expect(err).To(BeNil())
+		Expect(entries).To(BeEmpty())
+	})
+})
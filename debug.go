@@ -0,0 +1,55 @@
+package hoist
+
+import (
+	"bytes"
+	"context"
+	"io"
+)
+
+// rawResponseKey is the context key for the WithRawResponses callback
+type rawResponseKey struct{}
+
+// RawResponse is a response body as received, for debugging decode mismatches
+type RawResponse struct {
+	Method     string
+	URL        string // Query parameters are redacted, as in NetworkError
+	StatusCode int
+	Body       []byte
+}
+
+// WithRawResponses returns a context which calls fn with the raw body of every response received by requests made
+// with it, before the body is decoded, so decode mismatches can be diagnosed without reproducing them with curl.
+// Each body is buffered in memory in full before fn is called, including file downloads, so only use it for debugging.
+// fn receives bodies after gzip decompression and may be called concurrently. Body also backs the response being
+// decoded, so it must not be modified.
+func WithRawResponses(ctx context.Context, fn func(RawResponse)) context.Context {
+	return context.WithValue(ctx, rawResponseKey{}, fn)
+}
+
+// captureRawResponse buffers the response body and passes it to the context's WithRawResponses callback, if any
+func captureRawResponse(ctx context.Context, res *Response) error {
+	fn, ok := ctx.Value(rawResponseKey{}).(func(RawResponse))
+
+	if !ok || fn == nil {
+		return nil
+	}
+
+	body, err := io.ReadAll(res.Body)
+
+	_ = res.Body.Close()
+
+	if err != nil {
+		return err
+	}
+
+	res.Body = io.NopCloser(bytes.NewReader(body))
+
+	fn(RawResponse{
+		Method:     res.Request.Method,
+		URL:        redactURL(res.Request.URL.String()),
+		StatusCode: res.StatusCode,
+		Body:       body,
+	})
+
+	return nil
+}
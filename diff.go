@@ -0,0 +1,109 @@
+package hoist
+
+import "path"
+
+// FileChange represents a file which exists in both snapshots, but differs between them
+type FileChange struct {
+	Old File
+	New File
+}
+
+// FolderChange represents a folder which exists in both snapshots, but differs between them
+type FolderChange struct {
+	Old Folder
+	New Folder
+}
+
+// FolderDiff is the result of DiffFolders.
+// Files are matched by ID (falling back to their full path when no ID is set) and are modified when their
+// path, size or DateAdded differ. Folders are matched by Path and are modified when their size, count or version
+// differ.
+type FolderDiff struct {
+	AddedFiles      []File
+	RemovedFiles    []File
+	ModifiedFiles   []FileChange
+	AddedFolders    []Folder
+	RemovedFolders  []Folder
+	ModifiedFolders []FolderChange
+}
+
+// Empty returns true if no changes were found
+func (d FolderDiff) Empty() bool {
+	return len(d.AddedFiles) == 0 && len(d.RemovedFiles) == 0 && len(d.ModifiedFiles) == 0 &&
+		len(d.AddedFolders) == 0 && len(d.RemovedFolders) == 0 && len(d.ModifiedFolders) == 0
+}
+
+// DiffFolders compares two loaded folder trees, reporting which files and folders were added, removed or modified
+func DiffFolders(old, new Folder) FolderDiff {
+	var diff FolderDiff
+
+	oldFolders := make(map[string]Folder)
+
+	for _, folder := range old.Flatten() {
+		oldFolders[folder.Path] = folder
+	}
+
+	newFolders := make(map[string]Folder)
+
+	for _, folder := range new.Flatten() {
+		newFolders[folder.Path] = folder
+
+		oldFolder, ok := oldFolders[folder.Path]
+
+		if !ok {
+			diff.AddedFolders = append(diff.AddedFolders, folder)
+		} else if oldFolder.Size != folder.Size || oldFolder.Count != folder.Count || oldFolder.Version != folder.Version {
+			diff.ModifiedFolders = append(diff.ModifiedFolders, FolderChange{Old: oldFolder, New: folder})
+		}
+	}
+
+	for _, folder := range old.Flatten() {
+		if _, ok := newFolders[folder.Path]; !ok {
+			diff.RemovedFolders = append(diff.RemovedFolders, folder)
+		}
+	}
+
+	oldFiles := make(map[string]File)
+
+	for _, file := range old.AllFiles() {
+		oldFiles[fileKey(file)] = file
+	}
+
+	newFiles := make(map[string]File)
+
+	for _, file := range new.AllFiles() {
+		key := fileKey(file)
+
+		newFiles[key] = file
+
+		oldFile, ok := oldFiles[key]
+
+		if !ok {
+			diff.AddedFiles = append(diff.AddedFiles, file)
+		} else if filePath(oldFile) != filePath(file) || oldFile.Size != file.Size || !oldFile.DateAdded.Equal(file.DateAdded) {
+			diff.ModifiedFiles = append(diff.ModifiedFiles, FileChange{Old: oldFile, New: file})
+		}
+	}
+
+	for _, file := range old.AllFiles() {
+		if _, ok := newFiles[fileKey(file)]; !ok {
+			diff.RemovedFiles = append(diff.RemovedFiles, file)
+		}
+	}
+
+	return diff
+}
+
+// fileKey identifies a file across snapshots
+func fileKey(file File) string {
+	if file.ID != "" {
+		return file.ID
+	}
+
+	return filePath(file)
+}
+
+// filePath returns the full path of a file
+func filePath(file File) string {
+	return path.Join("/", file.FolderPath, file.Name)
+}
@@ -0,0 +1,69 @@
+package hoist
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Diff tests", func() {
+	It("Should report no changes for identical trees", func() {
+		Expect(DiffFolders(testTree(), testTree()).Empty()).To(BeTrue())
+	})
+	It("Should report added, removed and modified files and folders", func() {
+		old := testTree()
+		new := testTree()
+
+		// Modify a.txt, remove b.txt along with /a/b, and add /c with c.txt
+		new.Subfolders[0].Files[0].Size = 100
+		new.Subfolders[0].Subfolders = nil
+		new.Subfolders = append(new.Subfolders, Folder{
+			Name:  "c",
+			Path:  "/c",
+			Files: []File{{ID: "4", Name: "c.txt"}},
+		})
+
+		diff := DiffFolders(old, new)
+
+		Expect(diff.AddedFolders).To(HaveLen(1))
+		Expect(diff.AddedFolders[0].Path).To(Equal("/c"))
+		Expect(diff.RemovedFolders).To(HaveLen(1))
+		Expect(diff.RemovedFolders[0].Path).To(Equal("/a/b"))
+
+		Expect(diff.AddedFiles).To(HaveLen(1))
+		Expect(diff.AddedFiles[0].ID).To(Equal("4"))
+		Expect(diff.AddedFiles[0].FolderPath).To(Equal("/c"))
+		Expect(diff.RemovedFiles).To(HaveLen(1))
+		Expect(diff.RemovedFiles[0].ID).To(Equal("3"))
+		Expect(diff.ModifiedFiles).To(HaveLen(1))
+		Expect(diff.ModifiedFiles[0].Old.Size).To(Equal(int64(0)))
+		Expect(diff.ModifiedFiles[0].New.Size).To(Equal(int64(100)))
+	})
+	It("Should report moved and re-dated files as modified", func() {
+		old := testTree()
+		new := testTree()
+
+		moved := new.Files[0]
+		new.Files = nil
+		new.Subfolders[0].Files = append(new.Subfolders[0].Files, moved)
+		new.Subfolders[0].Subfolders[0].Files[0].DateAdded = time.Now()
+
+		diff := DiffFolders(old, new)
+
+		Expect(diff.AddedFiles).To(BeEmpty())
+		Expect(diff.RemovedFiles).To(BeEmpty())
+		Expect(diff.ModifiedFiles).To(HaveLen(2))
+	})
+	It("Should report modified folders", func() {
+		old := testTree()
+		new := testTree()
+
+		new.Subfolders[0].Version = "2"
+
+		diff := DiffFolders(old, new)
+
+		Expect(diff.ModifiedFolders).To(HaveLen(1))
+		Expect(diff.ModifiedFolders[0].New.Path).To(Equal("/a"))
+	})
+})
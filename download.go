@@ -0,0 +1,188 @@
+package hoist
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// DefaultMaxReadSize is the largest file ReadFile reads unless changed with WithMaxReadSize
+const DefaultMaxReadSize int64 = 32 << 20
+
+var (
+	ErrTooLarge  = errors.New("file exceeds the maximum read size")
+	ErrShortRead = errors.New("download size doesn't match the expected size")
+)
+
+// Download is an open download along with the response metadata, such as for setting headers when proxying it
+type Download struct {
+	io.ReadCloser
+	// ContentType is the server's Content-Type, unless overridden with WithContentTypeOverride
+	ContentType string
+	// ContentLength is the number of bytes in the download, or -1 if unknown
+	ContentLength int64
+}
+
+type downloadOptions struct {
+	contentType  string
+	maxReadSize  int64
+	requestOpts  []RequestOpt
+	verifySize   bool
+	expectedSize int64
+}
+
+// DownloadOpt allows defining per-download options for OpenDownload
+type DownloadOpt func(o *downloadOptions)
+
+// WithContentTypeOverride reports contentType as the download's ContentType instead of the server's
+func WithContentTypeOverride(contentType string) DownloadOpt {
+	return func(o *downloadOptions) {
+		o.contentType = contentType
+	}
+}
+
+// WithDownloadRequestOpts applies request options to the download request, such as a Range header
+func WithDownloadRequestOpts(opts ...RequestOpt) DownloadOpt {
+	return func(o *downloadOptions) {
+		o.requestOpts = append(o.requestOpts, opts...)
+	}
+}
+
+// WithMaxReadSize sets the largest file ReadFile reads before failing with ErrTooLarge
+func WithMaxReadSize(n int64) DownloadOpt {
+	return func(o *downloadOptions) {
+		o.maxReadSize = n
+	}
+}
+
+// WithVerifySize checks the number of bytes read once the download reaches EOF, failing the final read with
+// ErrShortRead if it isn't size, such as when a dropped connection truncates the stream without an error.
+// Pass File.Size from GetFiles, or a negative size to use the response's Content-Length when there is one.
+// Ranged downloads (see WithDownloadRequestOpts) should pass the length of the range rather than the file size.
+func WithVerifySize(size int64) DownloadOpt {
+	return func(o *downloadOptions) {
+		o.verifySize = true
+		o.expectedSize = size
+	}
+}
+
+// NewDownload wraps an open download body, applying any WithContentTypeOverride and WithVerifySize in opts.
+// It's used by FileClient implementations of OpenDownload.
+func NewDownload(body io.ReadCloser, contentType string, contentLength int64, opts ...DownloadOpt) *Download {
+	o := applyDownloadOpts(opts)
+
+	if o.contentType != "" {
+		contentType = o.contentType
+	}
+
+	if o.verifySize {
+		expected := o.expectedSize
+
+		if expected < 0 {
+			expected = contentLength
+		}
+
+		if expected >= 0 {
+			body = &sizeVerifyingReader{ReadCloser: body, expected: expected}
+		}
+	}
+
+	return &Download{
+		ReadCloser:    body,
+		ContentType:   contentType,
+		ContentLength: contentLength,
+	}
+}
+
+// sizeVerifyingReader counts the bytes read, returning ErrShortRead instead of io.EOF if they don't match expected
+type sizeVerifyingReader struct {
+	io.ReadCloser
+	expected int64
+	read     int64
+}
+
+func (r *sizeVerifyingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+
+	r.read += int64(n)
+
+	if err == io.EOF && r.read != r.expected {
+		return n, fmt.Errorf("%w: read %d of %d bytes", ErrShortRead, r.read, r.expected)
+	}
+
+	return n, err
+}
+
+// DownloadRequestOpts returns the request options set with WithDownloadRequestOpts.
+// It's used by FileClient implementations of OpenDownload.
+func DownloadRequestOpts(opts ...DownloadOpt) []RequestOpt {
+	return applyDownloadOpts(opts).requestOpts
+}
+
+// ReadDownload reads the download fully and closes it, failing with ErrTooLarge past the WithMaxReadSize limit.
+// It's used by FileClient implementations of ReadFile.
+func ReadDownload(d *Download, opts ...DownloadOpt) ([]byte, error) {
+	defer d.Close()
+
+	limit := applyDownloadOpts(opts).maxReadSize
+
+	// Fail before reading anything when the server reports the size
+	if d.ContentLength > limit {
+		return nil, fmt.Errorf("%w: %d bytes, limit %d", ErrTooLarge, d.ContentLength, limit)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(d, limit+1))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("%w: limit %d", ErrTooLarge, limit)
+	}
+
+	return data, nil
+}
+
+func applyDownloadOpts(opts []DownloadOpt) downloadOptions {
+	o := downloadOptions{
+		maxReadSize: DefaultMaxReadSize,
+	}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
+
+// OpenDownload opens the specified file like DownloadFile, also returning the response's content type and length
+func (c *client) OpenDownload(ctx context.Context, id string, opts ...DownloadOpt) (*Download, error) {
+	requestOpts := append([]RequestOpt{WithURLParameter("fileId", id)}, DownloadRequestOpts(opts...)...)
+
+	res, err := c.doRequest(ctx, http.MethodGet, apiFileDownload, nil, requestOpts...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode != http.StatusOK && res.StatusCode != http.StatusPartialContent {
+		return nil, statusError(res)
+	}
+
+	return NewDownload(res.Body, res.Header.Get("Content-Type"), res.ContentLength, opts...), nil
+}
+
+// ReadFile downloads a small file fully into memory, such as a config file. Files larger than DefaultMaxReadSize, or
+// the limit set with WithMaxReadSize, fail with ErrTooLarge.
+func (c *client) ReadFile(ctx context.Context, fileID string, opts ...DownloadOpt) ([]byte, error) {
+	download, err := c.OpenDownload(ctx, fileID, opts...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return ReadDownload(download, opts...)
+}
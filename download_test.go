@@ -0,0 +1,162 @@
+package hoist
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Download tests", func() {
+	var rangeHeader string
+
+	downloadServer := func() http.Handler {
+		rangeHeader = ""
+
+		mux := http.NewServeMux()
+
+		mux.HandleFunc(strings.Replace(testPath(apiFileDownload), "{fileId}", "1", 1), func(w http.ResponseWriter, r *http.Request) {
+			rangeHeader = r.Header.Get("Range")
+
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = w.Write([]byte("png data"))
+		})
+
+		return mux
+	}
+
+	It("Should surface the server's content type and length", func() {
+		c, server := newTestClient(downloadServer())
+		defer server.Close()
+
+		download, err := c.OpenDownload(context.Background(), "1")
+
+		Expect(err).To(BeNil())
+		defer download.Close()
+
+		Expect(download.ContentType).To(Equal("image/png"))
+		Expect(download.ContentLength).To(BeEquivalentTo(8))
+
+		data, err := io.ReadAll(download)
+
+		Expect(err).To(BeNil())
+		Expect(string(data)).To(Equal("png data"))
+	})
+	It("Should accept partial content for ranged downloads", func() {
+		mux := http.NewServeMux()
+
+		mux.HandleFunc(strings.Replace(testPath(apiFileDownload), "{fileId}", "1", 1), func(w http.ResponseWriter, r *http.Request) {
+			Expect(r.Header.Get("Range")).To(Equal("bytes=4-7"))
+
+			w.WriteHeader(http.StatusPartialContent)
+			_, _ = w.Write([]byte("data"))
+		})
+
+		c, server := newTestClient(mux)
+		defer server.Close()
+
+		body, err := c.DownloadFile(context.Background(), "1", WithHeader("Range", "bytes=4-7"))
+
+		Expect(err).To(BeNil())
+
+		data, err := io.ReadAll(body)
+
+		Expect(err).To(BeNil())
+		Expect(string(data)).To(Equal("data"))
+		Expect(body.Close()).To(Succeed())
+	})
+	It("Should override the content type", func() {
+		c, server := newTestClient(downloadServer())
+		defer server.Close()
+
+		download, err := c.OpenDownload(context.Background(), "1",
+			WithContentTypeOverride("application/octet-stream"),
+			WithDownloadRequestOpts(WithHeader("Range", "bytes=0-2")))
+
+		Expect(err).To(BeNil())
+		defer download.Close()
+
+		Expect(download.ContentType).To(Equal("application/octet-stream"))
+		Expect(rangeHeader).To(Equal("bytes=0-2"))
+	})
+	It("Should read a small file fully", func() {
+		c, server := newTestClient(downloadServer())
+		defer server.Close()
+
+		data, err := c.ReadFile(context.Background(), "1")
+
+		Expect(err).To(BeNil())
+		Expect(string(data)).To(Equal("png data"))
+
+		data, err = c.ReadFile(context.Background(), "1", WithMaxReadSize(8))
+
+		Expect(err).To(BeNil())
+		Expect(string(data)).To(Equal("png data"))
+	})
+	It("Should reject files over the limit", func() {
+		c, server := newTestClient(downloadServer())
+		defer server.Close()
+
+		_, err := c.ReadFile(context.Background(), "1", WithMaxReadSize(7))
+
+		Expect(err).To(MatchError(ErrTooLarge))
+	})
+	It("Should enforce the limit without a content length, closing the body", func() {
+		body := &closeRecorder{Reader: strings.NewReader(strings.Repeat("x", 100))}
+
+		_, err := ReadDownload(NewDownload(body, "", -1), WithMaxReadSize(99))
+
+		Expect(err).To(MatchError(ErrTooLarge))
+		Expect(body.closed).To(BeTrue())
+
+		body = &closeRecorder{Reader: strings.NewReader(strings.Repeat("x", 100))}
+
+		data, err := ReadDownload(NewDownload(body, "", -1), WithMaxReadSize(100))
+
+		Expect(err).To(BeNil())
+		Expect(data).To(HaveLen(100))
+		Expect(body.closed).To(BeTrue())
+	})
+	It("Should fail a truncated download when verifying the size", func() {
+		c, server := newTestClient(downloadServer())
+		defer server.Close()
+
+		// The server sends all 8 bytes, but the file is listed as 10
+		_, err := c.ReadFile(context.Background(), "1", WithVerifySize(10))
+
+		Expect(err).To(MatchError(ErrShortRead))
+
+		data, err := c.ReadFile(context.Background(), "1", WithVerifySize(8))
+
+		Expect(err).To(BeNil())
+		Expect(string(data)).To(Equal("png data"))
+	})
+	It("Should verify against the content length of a truncated stream", func() {
+		body := io.NopCloser(strings.NewReader("trunc"))
+
+		_, err := io.ReadAll(NewDownload(body, "", 10, WithVerifySize(-1)))
+
+		Expect(err).To(MatchError(ErrShortRead))
+
+		// Without a content length there's nothing to verify against
+		data, err := io.ReadAll(NewDownload(io.NopCloser(strings.NewReader("trunc")), "", -1, WithVerifySize(-1)))
+
+		Expect(err).To(BeNil())
+		Expect(string(data)).To(Equal("trunc"))
+	})
+})
+
+// closeRecorder records whether it was closed
+type closeRecorder struct {
+	io.Reader
+	closed bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+
+	return nil
+}
@@ -0,0 +1,292 @@
+package hoist
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// ErrUnsafeName is reported by DownloadDir for remote file or folder names which could write outside the local
+// directory, such as "..", or names containing a path separator
+var ErrUnsafeName = errors.New("unsafe file name")
+
+// downloadDirOptions are the options for DownloadDir
+type downloadDirOptions struct {
+	concurrency int
+	failFast    bool
+	manifest    string
+}
+
+// DownloadDirOpt allows defining options for DownloadDir
+type DownloadDirOpt func(o *downloadDirOptions)
+
+// WithDownloadConcurrency sets how many files are downloaded at once, defaulting to 4
+func WithDownloadConcurrency(n int) DownloadDirOpt {
+	return func(o *downloadDirOptions) {
+		o.concurrency = n
+	}
+}
+
+// WithDownloadFailFast stops downloading remaining files after the first failure, which are then reported as
+// failed with context.Canceled
+func WithDownloadFailFast() DownloadDirOpt {
+	return func(o *downloadDirOptions) {
+		o.failFast = true
+	}
+}
+
+// WithDownloadManifest records completed downloads in the transfer manifest at name, skipping files it lists as
+// already downloaded when the remote file's ID, size and DateAdded are unchanged and the local file still has the
+// same size. Use the same manifest to resume an interrupted DownloadDir.
+func WithDownloadManifest(name string) DownloadDirOpt {
+	return func(o *downloadDirOptions) {
+		o.manifest = name
+	}
+}
+
+// DownloadDir mirrors remoteDir into a local directory. See DownloadDir.
+func (c *client) DownloadDir(ctx context.Context, remoteDir, localDir string, opts ...DownloadDirOpt) (map[string]*File, map[string]error) {
+	return DownloadDir(ctx, c, remoteDir, localDir, opts...)
+}
+
+// DownloadDir downloads every file below remoteDir into localDir, creating the mirrored directories and downloading
+// files with bounded concurrency. Results are keyed by the slash-separated path relative to remoteDir: downloaded
+// files are returned in the first map and failures in the second. Errors which prevent the download entirely are
+// keyed by ".".
+func DownloadDir(ctx context.Context, c FileClient, remoteDir, localDir string, opts ...DownloadDirOpt) (map[string]*File, map[string]error) {
+	o := downloadDirOptions{
+		concurrency: defaultDirConcurrency,
+	}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if o.concurrency < 1 {
+		o.concurrency = 1
+	}
+
+	files := make(map[string]*File)
+	failed := make(map[string]error)
+
+	var manifest *transferManifest
+
+	if o.manifest != "" {
+		var err error
+
+		if manifest, err = openTransferManifest(o.manifest); err != nil {
+			failed["."] = err
+			return files, failed
+		}
+
+		defer manifest.Close()
+	}
+
+	folders, err := c.GetFolders(ctx)
+
+	if err == nil && len(folders) == 0 {
+		err = ErrNoFolder
+	}
+
+	if err != nil {
+		failed["."] = err
+		return files, failed
+	}
+
+	remoteDir = path.Clean("/" + remoteDir)
+
+	var downloads []File
+	var found bool
+
+	for _, folder := range folders {
+		folderPath := path.Clean("/" + folder.Path)
+
+		rel, ok := relativePath(remoteDir, folderPath)
+
+		if !ok {
+			continue
+		}
+
+		found = true
+
+		localFolder, err := localDirPath(localDir, rel)
+
+		if err != nil {
+			failed[rel] = err
+			continue
+		}
+
+		if err := os.MkdirAll(localFolder, 0755); err != nil {
+			failed[rel] = err
+			continue
+		}
+
+		for _, file := range folder.Files {
+			if !safeName(file.Name) {
+				failed[path.Join(rel, file.Name)] = fmt.Errorf("%w: %q", ErrUnsafeName, file.Name)
+				continue
+			}
+
+			file.FolderPath = folderPath
+			downloads = append(downloads, file)
+		}
+	}
+
+	if !found {
+		failed["."] = ErrNoFolder
+		return files, failed
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	sem := make(chan struct{}, o.concurrency)
+
+	for _, file := range downloads {
+		dir, _ := relativePath(remoteDir, file.FolderPath)
+		rel := path.Join(dir, file.Name)
+
+		localFile, err := localDirPath(localDir, rel)
+
+		if err != nil {
+			failed[rel] = err
+			continue
+		}
+
+		if !acquireDirWorker(ctx, sem, &mu, failed, dir, rel) {
+			continue
+		}
+
+		wg.Add(1)
+
+		go func(file File, rel string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			err := downloadDirResumable(ctx, c, manifest, rel, file, localFile)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if err != nil {
+				failed[rel] = err
+
+				if o.failFast {
+					cancel()
+				}
+			} else {
+				files[rel] = &file
+			}
+		}(file, rel)
+	}
+
+	wg.Wait()
+
+	return files, failed
+}
+
+// safeName reports whether a remote name is a single path segment, which can't escape the directory it's joined onto
+func safeName(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.ContainsAny(name, `/\`)
+}
+
+// localDirPath joins the slash-separated rel onto localDir, failing with ErrUnsafeName if any segment isn't a safe
+// name or the result wouldn't lie below localDir
+func localDirPath(localDir, rel string) (string, error) {
+	root := filepath.Clean(localDir)
+
+	if rel == "." {
+		return root, nil
+	}
+
+	for _, segment := range strings.Split(rel, "/") {
+		if !safeName(segment) {
+			return "", fmt.Errorf("%w: %q", ErrUnsafeName, rel)
+		}
+	}
+
+	joined := filepath.Join(root, filepath.FromSlash(rel))
+
+	if within, err := filepath.Rel(root, joined); err != nil || within == ".." || strings.HasPrefix(within, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%w: %q", ErrUnsafeName, rel)
+	}
+
+	return joined, nil
+}
+
+// relativePath returns p relative to base, if p is base or below it
+func relativePath(base, p string) (string, bool) {
+	if p == base {
+		return ".", true
+	}
+
+	prefix := strings.TrimSuffix(base, "/") + "/"
+
+	if !strings.HasPrefix(p, prefix) {
+		return "", false
+	}
+
+	return strings.TrimPrefix(p, prefix), true
+}
+
+// downloadDirResumable downloads a file unless the manifest lists it as complete and the local copy is intact,
+// recording it once downloaded
+func downloadDirResumable(ctx context.Context, c FileClient, manifest *transferManifest, rel string, file File, localPath string) error {
+	if manifest == nil {
+		return downloadDirFile(ctx, c, file.ID, localPath)
+	}
+
+	if entry, ok := manifest.done(rel, file.Size, file.DateAdded); ok && entry.ID == file.ID {
+		if stat, err := os.Stat(localPath); err == nil && stat.Size() == file.Size {
+			return nil
+		}
+	}
+
+	if err := downloadDirFile(ctx, c, file.ID, localPath); err != nil {
+		return err
+	}
+
+	return manifest.record(TransferEntry{
+		Path:     rel,
+		ID:       file.ID,
+		Size:     file.Size,
+		Modified: file.DateAdded,
+	})
+}
+
+// downloadDirFile downloads a single file to localPath, removing the partial file on failure
+func downloadDirFile(ctx context.Context, c FileClient, id, localPath string) error {
+	body, err := c.DownloadFile(ctx, id)
+
+	if err != nil {
+		return err
+	}
+
+	defer body.Close()
+
+	f, err := os.Create(localPath)
+
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(f, body); err != nil {
+		_ = f.Close()
+		_ = os.Remove(localPath)
+
+		return err
+	}
+
+	return f.Close()
+}
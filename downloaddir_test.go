@@ -0,0 +1,176 @@
+package hoist
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DownloadDir tests", func() {
+	// downloadDirServer serves a folder of count files below /docs, tracking the peak number of concurrent downloads
+	downloadDirServer := func(count int, fail map[string]bool) (http.Handler, func() int) {
+		var mu sync.Mutex
+		var inFlight, peak int
+
+		docs := Folder{Name: "docs", Path: "/docs"}
+
+		for i := 1; i <= count; i++ {
+			id := strconv.Itoa(i)
+
+			docs.Files = append(docs.Files, File{ID: id, Name: id + ".txt", Size: int64(len("file " + id))})
+		}
+
+		tree := testTree()
+		tree.Subfolders = append(tree.Subfolders, docs)
+
+		mux := http.NewServeMux()
+
+		mux.HandleFunc(testPath(apiFolders), func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewEncoder(w).Encode(FolderResponse{
+				defaultResponse: defaultResponse{Success: true},
+				Folder:          tree,
+			})
+		})
+
+		for _, file := range docs.Files {
+			mux.HandleFunc(strings.Replace(testPath(apiFileDownload), "{fileId}", file.ID, 1), func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				inFlight++
+				peak = max(peak, inFlight)
+				mu.Unlock()
+
+				time.Sleep(20 * time.Millisecond)
+
+				mu.Lock()
+				inFlight--
+				mu.Unlock()
+
+				if fail[file.ID] {
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+
+				_, _ = w.Write([]byte("file " + file.ID))
+			})
+		}
+
+		return mux, func() int {
+			mu.Lock()
+			defer mu.Unlock()
+
+			return peak
+		}
+	}
+
+	It("Should mirror the folder with bounded concurrency", func() {
+		handler, peak := downloadDirServer(12, nil)
+
+		c, server := newTestClient(handler)
+		defer server.Close()
+
+		dir := GinkgoT().TempDir()
+
+		files, failed := c.DownloadDir(context.Background(), "/docs", dir, WithDownloadConcurrency(3))
+
+		Expect(failed).To(BeEmpty())
+		Expect(files).To(HaveLen(12))
+		Expect(files).To(HaveKey("7.txt"))
+		Expect(peak()).To(BeNumerically("<=", 3))
+		Expect(peak()).To(BeNumerically(">", 1))
+
+		data, err := os.ReadFile(filepath.Join(dir, "7.txt"))
+
+		Expect(err).To(BeNil())
+		Expect(string(data)).To(Equal("file 7"))
+	})
+	It("Should stop remaining downloads after the first failure", func() {
+		handler, _ := downloadDirServer(4, map[string]bool{"1": true})
+
+		c, server := newTestClient(handler)
+		defer server.Close()
+
+		files, failed := c.DownloadDir(context.Background(), "/docs", GinkgoT().TempDir(),
+			WithDownloadConcurrency(1), WithDownloadFailFast())
+
+		Expect(files).To(BeEmpty())
+		Expect(failed).To(HaveLen(4))
+		Expect(failed["1.txt"]).ToNot(MatchError(context.Canceled))
+		Expect(failed["4.txt"]).To(MatchError(context.Canceled))
+	})
+	It("Should resume an interrupted download from the manifest", func() {
+		fail := map[string]bool{"3": true, "4": true, "5": true}
+
+		handler, _ := downloadDirServer(5, fail)
+
+		var mu sync.Mutex
+		var downloads int
+
+		c, server := newTestClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if strings.HasSuffix(r.URL.Path, "/download") {
+				mu.Lock()
+				downloads++
+				mu.Unlock()
+			}
+
+			handler.ServeHTTP(w, r)
+		}))
+		defer server.Close()
+
+		dir := GinkgoT().TempDir()
+		manifest := filepath.Join(GinkgoT().TempDir(), "download.manifest")
+
+		files, failed := c.DownloadDir(context.Background(), "/docs", dir,
+			WithDownloadManifest(manifest), WithDownloadConcurrency(1), WithDownloadFailFast())
+
+		Expect(files).To(HaveLen(2))
+		Expect(failed).To(HaveLen(3))
+
+		clear(fail)
+
+		mu.Lock()
+		downloads = 0
+		mu.Unlock()
+
+		files, failed = c.DownloadDir(context.Background(), "/docs", dir, WithDownloadManifest(manifest))
+
+		Expect(failed).To(BeEmpty())
+		Expect(files).To(HaveLen(5))
+		Expect(downloads).To(Equal(3))
+
+		data, err := os.ReadFile(filepath.Join(dir, "5.txt"))
+
+		Expect(err).To(BeNil())
+		Expect(string(data)).To(Equal("file 5"))
+
+		// Missing local copies are downloaded again
+		Expect(os.Remove(filepath.Join(dir, "1.txt"))).To(Succeed())
+
+		mu.Lock()
+		downloads = 0
+		mu.Unlock()
+
+		_, failed = c.DownloadDir(context.Background(), "/docs", dir, WithDownloadManifest(manifest))
+
+		Expect(failed).To(BeEmpty())
+		Expect(downloads).To(Equal(1))
+	})
+	It("Should report a missing remote folder", func() {
+		handler, _ := downloadDirServer(1, nil)
+
+		c, server := newTestClient(handler)
+		defer server.Close()
+
+		_, failed := c.DownloadDir(context.Background(), "/missing", GinkgoT().TempDir())
+
+		Expect(failed).To(HaveKeyWithValue(".", MatchError(ErrNoFolder)))
+	})
+})
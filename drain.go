@@ -0,0 +1,46 @@
+package hoist
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrDraining is returned by the upload methods (Upload, ChunkedUpload, ChunkedUploadAt and ResumeUpload) once
+// Drain has been called.
+var ErrDraining = errors.New("client is draining")
+
+// beginUpload registers an in-flight upload, returning the function to call once it completes, or ErrDraining.
+func (c *client) beginUpload() (func(), error) {
+	c.uploadsMu.Lock()
+	defer c.uploadsMu.Unlock()
+
+	if c.draining {
+		return nil, ErrDraining
+	}
+
+	c.uploads.Add(1)
+
+	return c.uploads.Done, nil
+}
+
+// Drain waits for in-flight uploads to complete for a graceful shutdown, returning the context's error if it expires
+// first. Uploads started after Drain has been called fail with ErrDraining, while other requests are unaffected.
+func (c *client) Drain(ctx context.Context) error {
+	c.uploadsMu.Lock()
+	c.draining = true
+	c.uploadsMu.Unlock()
+
+	done := make(chan struct{})
+
+	go func() {
+		c.uploads.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,70 @@
+package hoist
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Drain tests", func() {
+	It("Should wait for in-flight uploads and reject new ones", func() {
+		upload := &testUploadServer{}
+		started := make(chan struct{})
+		release := make(chan struct{})
+
+		c, server := newTestClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			<-release
+
+			upload.ServeHTTP(w, r)
+		}))
+		defer server.Close()
+
+		type result struct {
+			file *File
+			err  error
+		}
+
+		results := make(chan result, 1)
+
+		go func() {
+			file, err := c.ChunkedUpload(context.Background(), strings.NewReader("data"), "/a/file.txt", 4)
+
+			results <- result{file, err}
+		}()
+
+		<-started
+
+		// The upload is blocked, so draining times out
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		Expect(c.Drain(ctx)).To(MatchError(context.DeadlineExceeded))
+
+		_, err := c.ChunkedUpload(context.Background(), strings.NewReader("data"), "/a/other.txt", 4)
+
+		Expect(err).To(MatchError(ErrDraining))
+
+		close(release)
+
+		Expect(c.Drain(context.Background())).To(Succeed())
+
+		// Drain only returns once the upload has completed
+		Expect(upload.Chunks).To(HaveLen(1))
+		Eventually(results).Should(Receive(WithTransform(func(r result) error { return r.err }, BeNil())))
+	})
+	It("Should return immediately without uploads", func() {
+		c, server := newTestClient(&testUploadServer{})
+		defer server.Close()
+
+		Expect(c.Drain(context.Background())).To(Succeed())
+
+		_, err := c.ChunkedUploadAt(context.Background(), strings.NewReader("data"), "/a/file.txt", 4)
+
+		Expect(err).To(MatchError(ErrDraining))
+	})
+})
@@ -0,0 +1,172 @@
+package hoist
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// maxErrorMessage limits how much of a non-JSON response body is included in an APIError
+const maxErrorMessage = 512
+
+// NetworkError is returned when a request couldn't be sent or no response was received, such as DNS or connection
+// failures. These are usually safe to retry.
+type NetworkError struct {
+	Method string
+	URL    string
+	Err    error
+}
+
+func (e *NetworkError) Error() string {
+	return fmt.Sprintf("failed to execute %s %s request: %v", e.Method, e.URL, e.Err)
+}
+
+func (e *NetworkError) Unwrap() error {
+	return e.Err
+}
+
+// AuthError is returned when the request couldn't be authenticated, either because no valid token could be obtained
+// or because the API rejected it. Status is 0 when the request wasn't sent.
+type AuthError struct {
+	Status int
+	Err    error
+}
+
+func (e *AuthError) Error() string {
+	if e.Status != 0 {
+		return fmt.Sprintf("authentication failed, status %d: %v", e.Status, e.Err)
+	}
+
+	return fmt.Sprintf("authentication failed: %v", e.Err)
+}
+
+func (e *AuthError) Unwrap() error {
+	return e.Err
+}
+
+// APIError is returned when the API responds with an unexpected status.
+// It matches ErrUnexpectedStatus with errors.Is.
+type APIError struct {
+	Status  int
+	Message string
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("%s: %d", ErrUnexpectedStatus, e.Status)
+	}
+
+	return fmt.Sprintf("%s: %d (%s)", ErrUnexpectedStatus, e.Status, e.Message)
+}
+
+func (e *APIError) Is(target error) bool {
+	return target == ErrUnexpectedStatus
+}
+
+// statusError builds the categorized error for an unexpected response status, reading the message from the body.
+// The response is closed.
+func statusError(res *Response) error {
+	defer res.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(res.Body, 64*1024))
+
+	var response defaultResponse
+
+	message := strings.TrimSpace(string(body))
+
+	if err := json.Unmarshal(body, &response); err == nil && response.Message != "" {
+		message = response.Message
+	} else if len(message) > maxErrorMessage {
+		message = message[:maxErrorMessage]
+	}
+
+	apiErr := &APIError{
+		Status:  res.StatusCode,
+		Message: message,
+	}
+
+	if res.StatusCode == http.StatusUnauthorized || res.StatusCode == http.StatusForbidden {
+		return &AuthError{
+			Status: res.StatusCode,
+			Err:    apiErr,
+		}
+	}
+
+	return apiErr
+}
+
+// OperationError is returned when the API responds with a successful status but reports success:false in the body.
+// It matches ErrOperationFailed with errors.Is, along with ErrNoFolder, ErrNoFile or ErrPermissionDenied when the
+// message is recognized.
+type OperationError struct {
+	Op      string
+	Status  int
+	Message string
+	// Err is the error mapped from Message, or nil if it wasn't recognized
+	Err error
+}
+
+func (e *OperationError) Error() string {
+	return fmt.Sprintf("failed to %s, status: %d, response: %s", e.Op, e.Status, e.Message)
+}
+
+func (e *OperationError) Is(target error) bool {
+	return target == ErrOperationFailed
+}
+
+func (e *OperationError) Unwrap() error {
+	return e.Err
+}
+
+// failureError builds the OperationError for a response reporting success:false
+func failureError(op string, res *Response, message string) error {
+	return &OperationError{
+		Op:      op,
+		Status:  res.StatusCode,
+		Message: message,
+		Err:     mapFailureMessage(message),
+	}
+}
+
+// mapFailureMessage maps a failure message from the API to a sentinel error, or nil if it isn't recognized
+func mapFailureMessage(message string) error {
+	message = strings.ToLower(message)
+
+	switch {
+	case strings.Contains(message, "permission") || strings.Contains(message, "access denied") ||
+		strings.Contains(message, "forbidden") || strings.Contains(message, "not allowed"):
+		return ErrPermissionDenied
+	case strings.Contains(message, "folder not found"):
+		return ErrNoFolder
+	case strings.Contains(message, "file not found"):
+		return ErrNoFile
+	}
+
+	return nil
+}
+
+// checkSuccess decodes the response body for operations which only return success and message, returning an
+// OperationError if the API reported a failure. An empty body is treated as success. The response is closed.
+func checkSuccess(op string, res *Response) error {
+	var response struct {
+		Success *bool  `json:"success"`
+		Message string `json:"message"`
+	}
+
+	if err := res.Decode(&response); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		return err
+	}
+
+	if response.Success != nil && !*response.Success {
+		return failureError(op, res, response.Message)
+	}
+
+	return nil
+}
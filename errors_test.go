@@ -0,0 +1,159 @@
+package hoist
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Error category tests", func() {
+	It("Should return a NetworkError when the server can't be reached", func() {
+		c, server := newTestClient(http.NotFoundHandler())
+
+		server.Close()
+
+		_, err := c.GetFolders(context.Background())
+
+		var netErr *NetworkError
+
+		Expect(errors.As(err, &netErr)).To(BeTrue())
+		Expect(netErr.Method).To(Equal(http.MethodGet))
+		Expect(netErr.URL).To(HavePrefix(server.URL))
+	})
+	It("Should return an AuthError when no token is available", func() {
+		c, server := newTestClient(http.NotFoundHandler())
+		defer server.Close()
+
+		c.authManager = NewAuthManager(server.URL)
+
+		_, err := c.GetFolders(context.Background())
+
+		var authErr *AuthError
+
+		Expect(errors.As(err, &authErr)).To(BeTrue())
+		Expect(authErr.Status).To(Equal(0))
+		Expect(errors.Is(err, ErrNoToken)).To(BeTrue())
+	})
+	It("Should return an AuthError when the API rejects the token", func() {
+		c, server := newTestClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"success":false,"message":"token expired"}`))
+		}))
+		defer server.Close()
+
+		_, err := c.GetFolders(context.Background())
+
+		var authErr *AuthError
+		var apiErr *APIError
+
+		Expect(errors.As(err, &authErr)).To(BeTrue())
+		Expect(authErr.Status).To(Equal(http.StatusUnauthorized))
+		Expect(errors.As(err, &apiErr)).To(BeTrue())
+		Expect(apiErr.Message).To(Equal("token expired"))
+	})
+	It("Should return an APIError for unexpected statuses", func() {
+		c, server := newTestClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte("backend unavailable"))
+		}))
+		defer server.Close()
+
+		_, err := c.GetFolders(context.Background())
+
+		var apiErr *APIError
+
+		Expect(errors.As(err, &apiErr)).To(BeTrue())
+		Expect(apiErr.Status).To(Equal(http.StatusInternalServerError))
+		Expect(apiErr.Message).To(Equal("backend unavailable"))
+		Expect(errors.Is(err, ErrUnexpectedStatus)).To(BeTrue())
+	})
+})
+
+var _ = Describe("Logical failure tests", func() {
+	failingServer := func(body string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(body))
+		})
+	}
+
+	DescribeTable("Should report success:false with a 200 status",
+		func(call func(c *client) error) {
+			c, server := newTestClient(failingServer(`{"success":false,"message":"Permission denied"}`))
+			defer server.Close()
+
+			err := call(c)
+
+			Expect(err).To(MatchError(ErrOperationFailed))
+			Expect(err).To(MatchError(ErrPermissionDenied))
+			Expect(err.Error()).To(ContainSubstring("Permission denied"))
+
+			var opErr *OperationError
+
+			Expect(errors.As(err, &opErr)).To(BeTrue())
+			Expect(opErr.Status).To(Equal(http.StatusOK))
+		},
+		Entry("DeleteFiles", func(c *client) error {
+			return c.DeleteFiles(context.Background(), "1")
+		}),
+		Entry("DeleteFolder", func(c *client) error {
+			return c.DeleteFolder(context.Background(), "/a")
+		}),
+		Entry("ListShared", func(c *client) error {
+			_, err := c.ListShared(context.Background())
+			return err
+		}),
+		Entry("FilesModifiedSince", func(c *client) error {
+			_, err := c.FilesModifiedSince(context.Background(), time.Now())
+			return err
+		}),
+		Entry("CreateFolder", func(c *client) error {
+			_, err := c.CreateFolder(context.Background(), "/a")
+			return err
+		}),
+		Entry("CreateSubfolder", func(c *client) error {
+			_, err := c.CreateSubfolder(context.Background(), "/", "a")
+			return err
+		}),
+		Entry("EnsureRoot", func(c *client) error {
+			_, err := c.EnsureRoot(context.Background())
+			return err
+		}),
+		Entry("MoveFiles", func(c *client) error {
+			return c.MoveFiles(context.Background(), "/a", "1")
+		}),
+		Entry("MoveFolder", func(c *client) error {
+			return c.MoveFolder(context.Background(), "/a", "/b", "a")
+		}),
+		Entry("RenameFile", func(c *client) error {
+			return c.RenameFile(context.Background(), "1", "b.txt")
+		}),
+		Entry("EditFile", func(c *client) error {
+			return c.EditFile(context.Background(), "1", EditFileParams{})
+		}),
+		Entry("PatchFile", func(c *client) error {
+			return c.PatchFile(context.Background(), "1", strings.NewReader("data"), 0, 4)
+		}),
+	)
+
+	It("Should map not found messages", func() {
+		c, server := newTestClient(failingServer(`{"success":false,"message":"Folder not found"}`))
+		defer server.Close()
+
+		err := c.DeleteFolder(context.Background(), "/missing")
+
+		Expect(err).To(MatchError(ErrNoFolder))
+		Expect(err).ToNot(MatchError(ErrPermissionDenied))
+	})
+	It("Should treat an empty body as success", func() {
+		c, server := newTestClient(failingServer(""))
+		defer server.Close()
+
+		Expect(c.DeleteFiles(context.Background(), "1")).To(Succeed())
+		Expect(c.PatchFile(context.Background(), "1", strings.NewReader("data"), 0, 4)).To(Succeed())
+	})
+})
@@ -3,58 +3,241 @@ package hoist
 import (
 	"context"
 	"errors"
+	"fmt"
 	"github.com/namecrane/hoist/events"
 	"github.com/philippseith/signalr"
+	log "github.com/sirupsen/logrus"
+	"runtime/debug"
+	"sync"
 	"time"
 )
 
-var ErrAuthFailed = errors.New("auth failed")
+var (
+	ErrAuthFailed   = errors.New("auth failed")
+	ErrMissedPing   = errors.New("missed keep-alive ping")
+	ErrNotConnected = errors.New("events not connected")
+)
+
+// hubClient is the subset of signalr.Client used by Events, allowing the connection to be stubbed
+type hubClient interface {
+	Start()
+	Stop()
+	Invoke(method string, arguments ...interface{}) <-chan signalr.InvokeResult
+}
+
+// EventsOption configures an Events client
+type EventsOption func(*Events)
+
+// WithKeepAlive sends a ping every interval to keep idle connections alive behind load balancers.
+// If a ping fails or doesn't return within the interval, the connection is re-established.
+func WithKeepAlive(interval time.Duration) EventsOption {
+	return func(e *Events) {
+		e.keepAlive = interval
+	}
+}
+
+// WithReconnectBackoff sets the delay between failed reconnect attempts, doubling from initial after each failure
+// up to max. Defaults to 1 second, up to 1 minute.
+func WithReconnectBackoff(initial, max time.Duration) EventsOption {
+	return func(e *Events) {
+		e.backoffInitial = initial
+		e.backoffMax = max
+	}
+}
+
+// recoverHandler recovers from a panic in a user registered event handler, logging it rather than letting it kill
+// the SignalR read loop and with it every later event. It must be deferred directly by the dispatching function.
+func recoverHandler(event string) {
+	if r := recover(); r != nil {
+		log.WithFields(log.Fields{
+			"event": event,
+			"panic": r,
+			"stack": string(debug.Stack()),
+		}).Error("Event handler panicked")
+	}
+}
 
 // Events is a helper for managing SignalR events from the server
 type Events struct {
+	mu          sync.Mutex
 	r           *events.Receiver
-	client      signalr.Client
+	client      hubClient
 	apiUrl      string
 	authManager AuthManager
+
+	// dial opens and returns a new hub client, defaulting to a SignalR HTTP connection
+	dial func(ctx context.Context) (hubClient, error)
+
+	keepAlive     time.Duration
+	stopKeepAlive chan struct{}
+
+	// closed is set by Close, so a connection dialled concurrently is stopped instead of kept
+	closed bool
+
+	// Reconnect state, reset once connected
+	backoffInitial    time.Duration
+	backoffMax        time.Duration
+	reconnectAttempts int
+	lastErr           error
+
+	// connected is closed once Connect completes or a reconnect succeeds, with connectErr holding Connect's error
+	connected  chan struct{}
+	connectErr error
+
+	folderChange         FolderChangeHandler
+	folderResolver       FileClient
+	folderResolveTimeout time.Duration
 }
 
 // NewEventsClient creates a new event client, with apiUrl and authManager similar to client.
 // Note that you must call Events.Connect yourself.
-func NewEventsClient(apiUrl string, authManager AuthManager) *Events {
-	return &Events{
-		r:           &events.Receiver{},
-		apiUrl:      apiUrl,
-		authManager: authManager,
+func NewEventsClient(apiUrl string, authManager AuthManager, opts ...EventsOption) *Events {
+	e := &Events{
+		r:              &events.Receiver{},
+		apiUrl:         apiUrl,
+		authManager:    authManager,
+		backoffInitial: time.Second,
+		backoffMax:     time.Minute,
+		connected:      make(chan struct{}),
 	}
-}
 
-// Connect opens a SignalR client and authenticates via Authenticate call
-func (c *Events) Connect() error {
-	ctx := context.Background()
+	e.dial = e.dialSignalR
+
+	for _, opt := range opts {
+		opt(e)
+	}
 
+	if e.folderChange != nil {
+		e.r.OnFsFolderChange = e.onFolderChange
+	}
+
+	return e
+}
+
+// dialSignalR opens a SignalR connection to the mail hub
+func (c *Events) dialSignalR(ctx context.Context) (hubClient, error) {
 	creationCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	conn, err := signalr.NewHTTPConnection(creationCtx, c.apiUrl+"/hubs/mail")
 
 	if err != nil {
-		return err
+		return nil, err
 	}
-	// Create the client and set a receiver for callbacks from the server
-	client, err := signalr.NewClient(ctx,
+
+	opts := []func(signalr.Party) error{
 		signalr.WithConnection(conn),
-		signalr.WithReceiver(c.r))
+		signalr.WithReceiver(c.r),
+	}
+
+	if c.keepAlive > 0 {
+		opts = append(opts, signalr.KeepAliveInterval(c.keepAlive))
+	}
+
+	// Create the client and set a receiver for callbacks from the server
+	return signalr.NewClient(ctx, opts...)
+}
+
+// Connect opens a SignalR client and authenticates via Authenticate call
+func (c *Events) Connect() error {
+	c.setDisconnected()
+
+	err := c.connect()
+
+	c.setConnected(err)
+
+	return err
+}
+
+// connect dials and authenticates, starting the keep-alive loop if enabled
+func (c *Events) connect() error {
+	c.mu.Lock()
+	c.closed = false
+	c.mu.Unlock()
+
+	client, err := c.dial(context.Background())
 
 	if err != nil {
 		return err
 	}
 
-	c.client = client
-
-	client.Start()
+	if err := c.setClient(client); err != nil {
+		return err
+	}
 
 	// Authenticate
-	return c.Authenticate()
+	if err := c.Authenticate(); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.keepAlive > 0 && c.stopKeepAlive == nil {
+		c.stopKeepAlive = make(chan struct{})
+
+		go c.keepAliveLoop(c.stopKeepAlive)
+	}
+
+	return nil
+}
+
+// WaitForConnection blocks until the connection is up and authenticated, returning the error if Connect failed.
+// While reconnecting it waits for the reconnect to succeed; if ctx expires first the last reconnect error is included.
+func (c *Events) WaitForConnection(ctx context.Context) error {
+	c.mu.Lock()
+	connected := c.connected
+	c.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		if c.lastErr != nil {
+			return fmt.Errorf("%w: %w", ctx.Err(), c.lastErr)
+		}
+
+		return ctx.Err()
+	case <-connected:
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		return c.connectErr
+	}
+}
+
+// setConnected records the outcome of a connection attempt, releasing WaitForConnection callers
+func (c *Events) setConnected(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.connectErr = err
+
+	if err == nil {
+		c.reconnectAttempts = 0
+		c.lastErr = nil
+	}
+
+	select {
+	case <-c.connected:
+	default:
+		close(c.connected)
+	}
+}
+
+// setDisconnected makes WaitForConnection block until the next connection attempt completes
+func (c *Events) setDisconnected() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.connectErr = nil
+
+	select {
+	case <-c.connected:
+		c.connected = make(chan struct{})
+	default:
+	}
 }
 
 // Authenticate will send a `connect` method with the bearer token to the server
@@ -65,7 +248,16 @@ func (c *Events) Authenticate() error {
 		return err
 	}
 
-	res := <-c.client.Invoke("connect", token)
+	c.mu.Lock()
+	client := c.client
+	c.mu.Unlock()
+
+	// The connection may have been closed concurrently, such as by a keep-alive ping racing Close
+	if client == nil {
+		return ErrNotConnected
+	}
+
+	res := <-client.Invoke("connect", token)
 
 	if b, ok := res.Value.(bool); !ok || !b {
 		return ErrAuthFailed
@@ -73,3 +265,177 @@ func (c *Events) Authenticate() error {
 
 	return nil
 }
+
+// Close stops the keep-alive loop and the underlying connection
+func (c *Events) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.closed = true
+
+	if c.stopKeepAlive != nil {
+		close(c.stopKeepAlive)
+		c.stopKeepAlive = nil
+	}
+
+	if c.client != nil {
+		c.client.Stop()
+		c.client = nil
+	}
+}
+
+// keepAliveLoop pings the server every keepAlive interval until stop is closed, reconnecting on a missed ping
+func (c *Events) keepAliveLoop(stop <-chan struct{}) {
+	ticker := time.NewTicker(c.keepAlive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			err := c.ping()
+
+			if err == nil {
+				continue
+			}
+
+			// Don't reconnect if the ping failed because the connection was closed
+			select {
+			case <-stop:
+				return
+			default:
+			}
+
+			log.WithError(err).Warning("Events keep-alive failed, reconnecting")
+
+			c.setDisconnected()
+			c.reconnectWithBackoff(stop)
+		}
+	}
+}
+
+// reconnectWithBackoff retries reconnect until it succeeds or stop is closed, backing off exponentially between
+// attempts. Attempts and the last error are recorded for ReconnectAttempts and LastError.
+func (c *Events) reconnectWithBackoff(stop <-chan struct{}) {
+	for {
+		err := c.reconnect()
+
+		if err == nil {
+			c.setConnected(nil)
+
+			return
+		}
+
+		// The connection was closed while reconnecting
+		select {
+		case <-stop:
+			return
+		default:
+		}
+
+		c.mu.Lock()
+
+		c.reconnectAttempts++
+		c.lastErr = err
+		delay := c.backoff(c.reconnectAttempts)
+
+		c.mu.Unlock()
+
+		log.WithError(err).WithField("delay", delay).Warning("Failed to reconnect events")
+
+		select {
+		case <-stop:
+			return
+		case <-time.After(delay):
+		}
+	}
+}
+
+// backoff returns the delay after the given number of failed attempts
+func (c *Events) backoff(attempts int) time.Duration {
+	delay := c.backoffInitial
+
+	for i := 1; i < attempts && delay < c.backoffMax; i++ {
+		delay *= 2
+	}
+
+	return min(delay, c.backoffMax)
+}
+
+// ReconnectAttempts returns how many reconnect attempts have failed in a row, reset to zero once connected
+func (c *Events) ReconnectAttempts() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.reconnectAttempts
+}
+
+// LastError returns the error from the most recent failed reconnect attempt, or nil once connected
+func (c *Events) LastError() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.lastErr
+}
+
+// ping re-authenticates the connection, which acts as an application-level ping.
+// The ping is considered missed if it fails or takes longer than the keep-alive interval.
+func (c *Events) ping() error {
+	done := make(chan error, 1)
+
+	go func() {
+		done <- c.Authenticate()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(c.keepAlive):
+		return ErrMissedPing
+	}
+}
+
+// reconnect replaces the current connection with a new one
+func (c *Events) reconnect() error {
+	c.mu.Lock()
+	old := c.client
+	c.mu.Unlock()
+
+	if old != nil {
+		old.Stop()
+	}
+
+	client, err := c.dial(context.Background())
+
+	if err != nil {
+		return err
+	}
+
+	if err := c.setClient(client); err != nil {
+		return err
+	}
+
+	return c.Authenticate()
+}
+
+// setClient makes a newly dialled client the current connection and starts it. If Close was called while dialling,
+// the client is stopped and ErrNotConnected is returned instead.
+func (c *Events) setClient(client hubClient) error {
+	c.mu.Lock()
+
+	if c.closed {
+		c.mu.Unlock()
+
+		client.Stop()
+
+		return ErrNotConnected
+	}
+
+	c.client = client
+	c.mu.Unlock()
+
+	client.Start()
+
+	return nil
+}
@@ -4,6 +4,9 @@ import "github.com/philippseith/signalr"
 
 type Receiver struct {
 	signalr.Hub
+
+	// OnFsFolderChange is called for each FsFolderChange event when set
+	OnFsFolderChange func(folder *FolderChange)
 }
 
 type SelfTest struct {
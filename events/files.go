@@ -15,8 +15,12 @@ type FolderChange struct {
 }
 
 func (r *Receiver) FsFolderChange(folder *FolderChange) {
-	fmt.Println("Folder changed:", folder)
+	if r.OnFsFolderChange != nil {
+		r.OnFsFolderChange(folder)
+		return
+	}
 
+	fmt.Println("Folder changed:", folder)
 }
 
 type File struct {
@@ -19,6 +19,8 @@ type Mail struct {
 	OwnerEmailAddress string `json:"ownerEmailAddress"`
 	Folder            string `json:"folder"`
 	New               bool   `json:"isNew"`
+	// Date is when the message was received, or zero if the event doesn't include it
+	Date Timestamp `json:"date"`
 }
 
 func (r *Receiver) MailAdded(mail []Mail) {
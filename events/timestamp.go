@@ -0,0 +1,9 @@
+package events
+
+import (
+	"github.com/namecrane/hoist/internal/timestamp"
+)
+
+// Timestamp is a server timestamp in an event payload, accepting RFC3339 strings and Unix seconds or milliseconds,
+// decoded the same way as timestamps in the hoist package
+type Timestamp = timestamp.Time
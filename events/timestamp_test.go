@@ -0,0 +1,49 @@
+package events
+
+import (
+	"encoding/json"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Timestamp tests", func() {
+	expected := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+
+	DescribeTable("Should decode each timestamp format",
+		func(input string) {
+			var payload struct {
+				At Timestamp `json:"at"`
+			}
+
+			Expect(json.Unmarshal([]byte(`{"at":`+input+`}`), &payload)).To(Succeed())
+			Expect(payload.At.Time).To(BeTemporally("==", expected))
+		},
+		Entry("RFC3339", `"2024-05-06T07:08:09Z"`),
+		Entry("RFC3339 with an offset", `"2024-05-06T09:08:09+02:00"`),
+		Entry("RFC3339 without a zone", `"2024-05-06T07:08:09"`),
+		Entry("Unix seconds", `1714979289`),
+		Entry("Unix milliseconds", `1714979289000`),
+		Entry("Unix seconds as a string", `"1714979289"`),
+	)
+
+	It("Should decode timestamps in event payloads", func() {
+		var mail []Mail
+
+		Expect(json.Unmarshal([]byte(`[{"uid":1,"date":1714979289000},{"uid":2}]`), &mail)).To(Succeed())
+		Expect(mail[0].Date.Time).To(BeTemporally("==", expected))
+		Expect(mail[1].Date.IsZero()).To(BeTrue())
+	})
+	It("Should decode null as the zero time", func() {
+		var t Timestamp
+
+		Expect(json.Unmarshal([]byte(`null`), &t)).To(Succeed())
+		Expect(t.IsZero()).To(BeTrue())
+	})
+	It("Should reject invalid timestamps", func() {
+		var t Timestamp
+
+		Expect(json.Unmarshal([]byte(`"yesterday"`), &t)).ToNot(Succeed())
+	})
+})
@@ -0,0 +1,68 @@
+package hoist
+
+import (
+	"context"
+	"errors"
+	"github.com/namecrane/hoist/events"
+	"time"
+)
+
+// defaultFolderResolveTimeout bounds each GetFolder call made by WithFolderResolution
+const defaultFolderResolveTimeout = 30 * time.Second
+
+// FolderChangeHandler receives folder change events.
+// When folder resolution is enabled (see WithFolderResolution), folder is the changed folder as returned by
+// GetFolder, or nil if it no longer exists (for example, it was deleted). err is set if resolving the folder failed.
+// Without resolution, folder and err are always nil.
+type FolderChangeHandler func(change *events.FolderChange, folder *Folder, err error)
+
+// WithFolderChangeHandler registers a handler for folder change events, replacing the default logging
+func WithFolderChangeHandler(handler FolderChangeHandler) EventsOption {
+	return func(e *Events) {
+		e.folderChange = handler
+	}
+}
+
+// WithFolderResolution fetches the changed folder with client.GetFolder before calling the folder change handler,
+// at the cost of an extra API call per event. Events are delivered one at a time, so each call is limited to 30
+// seconds by default (see WithFolderResolutionTimeout), after which the handler gets the error instead.
+func WithFolderResolution(client FileClient) EventsOption {
+	return func(e *Events) {
+		e.folderResolver = client
+	}
+}
+
+// WithFolderResolutionTimeout changes how long WithFolderResolution waits for each folder
+func WithFolderResolutionTimeout(timeout time.Duration) EventsOption {
+	return func(e *Events) {
+		e.folderResolveTimeout = timeout
+	}
+}
+
+// onFolderChange dispatches a folder change event to the registered handler, resolving the folder if enabled
+func (c *Events) onFolderChange(change *events.FolderChange) {
+	defer recoverHandler("FsFolderChange")
+
+	if c.folderResolver == nil {
+		c.folderChange(change, nil, nil)
+		return
+	}
+
+	timeout := c.folderResolveTimeout
+
+	if timeout <= 0 {
+		timeout = defaultFolderResolveTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	folder, err := c.folderResolver.GetFolder(ctx, change.Folder)
+
+	if errors.Is(err, ErrNoFolder) {
+		// The folder was deleted (or moved away) before it could be fetched
+		folder, err = nil, nil
+	}
+
+	c.folderChange(change, folder, err)
+}
@@ -0,0 +1,391 @@
+package hoist
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/namecrane/hoist/events"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/philippseith/signalr"
+)
+
+// stubHubClient is a hubClient which records invocations and replies with a configurable result
+type stubHubClient struct {
+	mu          sync.Mutex
+	invocations []string
+	reply       func() signalr.InvokeResult
+	stopped     bool
+}
+
+func (s *stubHubClient) Start() {}
+
+func (s *stubHubClient) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.stopped = true
+}
+
+func (s *stubHubClient) Invoke(method string, arguments ...interface{}) <-chan signalr.InvokeResult {
+	s.mu.Lock()
+	s.invocations = append(s.invocations, method)
+	reply := s.reply
+	s.mu.Unlock()
+
+	ch := make(chan signalr.InvokeResult, 1)
+
+	if reply == nil {
+		ch <- signalr.InvokeResult{Value: true}
+	} else {
+		ch <- reply()
+	}
+
+	return ch
+}
+
+func (s *stubHubClient) Invocations() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.invocations)
+}
+
+var _ = Describe("Events tests", func() {
+	It("Should send pings at the keep-alive interval", func() {
+		stub := &stubHubClient{}
+
+		e := NewEventsClient("http://localhost", &testAuthManager{token: "token"}, WithKeepAlive(20*time.Millisecond))
+
+		e.dial = func(ctx context.Context) (hubClient, error) {
+			return stub, nil
+		}
+
+		Expect(e.Connect()).To(Succeed())
+		defer e.Close()
+
+		// The first invocation is the initial authentication
+		Eventually(stub.Invocations).WithTimeout(time.Second).Should(BeNumerically(">=", 4))
+	})
+	It("Should reconnect when a ping is missed", func() {
+		var mu sync.Mutex
+		var dials int
+
+		failing := &stubHubClient{}
+		healthy := &stubHubClient{}
+
+		e := NewEventsClient("http://localhost", &testAuthManager{token: "token"}, WithKeepAlive(20*time.Millisecond))
+
+		e.dial = func(ctx context.Context) (hubClient, error) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			dials++
+
+			if dials == 1 {
+				return failing, nil
+			}
+
+			return healthy, nil
+		}
+
+		Expect(e.Connect()).To(Succeed())
+		defer e.Close()
+
+		failing.mu.Lock()
+		failing.reply = func() signalr.InvokeResult {
+			return signalr.InvokeResult{Value: false}
+		}
+		failing.mu.Unlock()
+
+		Eventually(healthy.Invocations).WithTimeout(time.Second).Should(BeNumerically(">=", 1))
+
+		failing.mu.Lock()
+		defer failing.mu.Unlock()
+
+		Expect(failing.stopped).To(BeTrue())
+	})
+	It("Should back off exponentially up to the maximum", func() {
+		e := NewEventsClient("http://localhost", &testAuthManager{token: "token"},
+			WithReconnectBackoff(10*time.Millisecond, 50*time.Millisecond))
+
+		Expect(e.backoff(1)).To(Equal(10 * time.Millisecond))
+		Expect(e.backoff(2)).To(Equal(20 * time.Millisecond))
+		Expect(e.backoff(3)).To(Equal(40 * time.Millisecond))
+		Expect(e.backoff(4)).To(Equal(50 * time.Millisecond))
+		Expect(e.backoff(100)).To(Equal(50 * time.Millisecond))
+	})
+	It("Should count failed reconnects and reset once connected", func() {
+		var mu sync.Mutex
+		var dials int
+		var recovered bool
+
+		dialErr := errors.New("connection refused")
+
+		failing := &stubHubClient{}
+		healthy := &stubHubClient{}
+
+		e := NewEventsClient("http://localhost", &testAuthManager{token: "token"},
+			WithKeepAlive(20*time.Millisecond), WithReconnectBackoff(time.Millisecond, 5*time.Millisecond))
+
+		e.dial = func(ctx context.Context) (hubClient, error) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			dials++
+
+			if dials == 1 {
+				return failing, nil
+			} else if !recovered {
+				return nil, dialErr
+			}
+
+			return healthy, nil
+		}
+
+		Expect(e.Connect()).To(Succeed())
+		defer e.Close()
+
+		failing.mu.Lock()
+		failing.reply = func() signalr.InvokeResult {
+			return signalr.InvokeResult{Value: false}
+		}
+		failing.mu.Unlock()
+
+		Eventually(e.ReconnectAttempts).WithTimeout(time.Second).Should(BeNumerically(">=", 3))
+		Expect(errors.Is(e.LastError(), dialErr)).To(BeTrue())
+
+		mu.Lock()
+		recovered = true
+		mu.Unlock()
+
+		Eventually(e.ReconnectAttempts).WithTimeout(time.Second).Should(BeZero())
+		Expect(e.LastError()).To(BeNil())
+	})
+	It("Should stop a connection dialled while closing", func() {
+		var mu sync.Mutex
+		var dials int
+
+		failing := &stubHubClient{}
+		late := &stubHubClient{}
+
+		dialling := make(chan struct{})
+		release := make(chan struct{})
+
+		e := NewEventsClient("http://localhost", &testAuthManager{token: "token"}, WithKeepAlive(20*time.Millisecond))
+
+		e.dial = func(ctx context.Context) (hubClient, error) {
+			mu.Lock()
+			dials++
+			first := dials == 1
+			mu.Unlock()
+
+			if first {
+				return failing, nil
+			}
+
+			close(dialling)
+			<-release
+
+			return late, nil
+		}
+
+		Expect(e.Connect()).To(Succeed())
+
+		failing.mu.Lock()
+		failing.reply = func() signalr.InvokeResult {
+			return signalr.InvokeResult{Value: false}
+		}
+		failing.mu.Unlock()
+
+		Eventually(dialling).WithTimeout(time.Second).Should(BeClosed())
+
+		e.Close()
+		close(release)
+
+		Eventually(func() bool {
+			late.mu.Lock()
+			defer late.mu.Unlock()
+
+			return late.stopped
+		}).WithTimeout(time.Second).Should(BeTrue())
+
+		e.mu.Lock()
+		defer e.mu.Unlock()
+
+		Expect(e.client).To(BeNil())
+		Expect(late.Invocations()).To(BeZero())
+	})
+	It("Should wait until the connection is authenticated", func() {
+		release := make(chan struct{})
+		stub := &stubHubClient{}
+
+		e := NewEventsClient("http://localhost", &testAuthManager{token: "token"})
+
+		e.dial = func(ctx context.Context) (hubClient, error) {
+			<-release
+			return stub, nil
+		}
+
+		waited := make(chan error, 1)
+
+		go func() {
+			waited <- e.WaitForConnection(context.Background())
+		}()
+
+		go func() {
+			defer GinkgoRecover()
+			Expect(e.Connect()).To(Succeed())
+		}()
+
+		Consistently(waited).WithTimeout(50 * time.Millisecond).ShouldNot(Receive())
+
+		close(release)
+
+		Eventually(waited).WithTimeout(time.Second).Should(Receive(BeNil()))
+		Expect(stub.Invocations()).To(Equal(1))
+
+		e.Close()
+	})
+	It("Should return the connection error", func() {
+		e := NewEventsClient("http://localhost", &testAuthManager{token: "token"})
+
+		e.dial = func(ctx context.Context) (hubClient, error) {
+			return &stubHubClient{reply: func() signalr.InvokeResult {
+				return signalr.InvokeResult{Value: false}
+			}}, nil
+		}
+
+		Expect(e.Connect()).To(MatchError(ErrAuthFailed))
+		Expect(e.WaitForConnection(context.Background())).To(MatchError(ErrAuthFailed))
+	})
+	It("Should stop waiting when the context expires", func() {
+		e := NewEventsClient("http://localhost", &testAuthManager{token: "token"})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		Expect(e.WaitForConnection(ctx)).To(MatchError(context.DeadlineExceeded))
+	})
+	Context("Folder changes", func() {
+		var (
+			change     *events.FolderChange
+			resolved   *Folder
+			resolveErr error
+			calls      int
+		)
+
+		handler := func(c *events.FolderChange, folder *Folder, err error) {
+			calls++
+			change, resolved, resolveErr = c, folder, err
+		}
+
+		folderServer := func(found bool) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if !found {
+					_ = json.NewEncoder(w).Encode(defaultResponse{Success: false, Message: "Folder not found"})
+					return
+				}
+
+				_ = json.NewEncoder(w).Encode(FolderResponse{
+					defaultResponse: defaultResponse{Success: true},
+					Folder:          Folder{Name: "docs", Path: "/docs"},
+				})
+			})
+		}
+
+		BeforeEach(func() {
+			change, resolved, resolveErr, calls = nil, nil, nil, 0
+		})
+
+		It("Should keep delivering events after a handler panics", func() {
+			e := NewEventsClient("http://localhost", &testAuthManager{token: "token"},
+				WithFolderChangeHandler(func(c *events.FolderChange, folder *Folder, err error) {
+					handler(c, folder, err)
+
+					if c.Folder == "/bad" {
+						panic("handler failed")
+					}
+				}))
+
+			Expect(func() {
+				e.r.FsFolderChange(&events.FolderChange{Folder: "/bad"})
+			}).ToNot(Panic())
+
+			e.r.FsFolderChange(&events.FolderChange{Folder: "/good"})
+
+			Expect(calls).To(Equal(2))
+			Expect(change.Folder).To(Equal("/good"))
+		})
+		It("Should pass raw events without resolving the folder", func() {
+			e := NewEventsClient("http://localhost", &testAuthManager{token: "token"}, WithFolderChangeHandler(handler))
+
+			e.r.FsFolderChange(&events.FolderChange{Folder: "/docs", ParentFolder: "/"})
+
+			Expect(calls).To(Equal(1))
+			Expect(change.Folder).To(Equal("/docs"))
+			Expect(resolved).To(BeNil())
+			Expect(resolveErr).To(BeNil())
+		})
+
+		It("Should resolve the changed folder", func() {
+			c, server := newTestClient(folderServer(true))
+			defer server.Close()
+
+			e := NewEventsClient("http://localhost", &testAuthManager{token: "token"},
+				WithFolderChangeHandler(handler), WithFolderResolution(c))
+
+			e.r.FsFolderChange(&events.FolderChange{Folder: "/docs", ParentFolder: "/"})
+
+			Expect(calls).To(Equal(1))
+			Expect(resolveErr).To(BeNil())
+			Expect(resolved).ToNot(BeNil())
+			Expect(resolved.Path).To(Equal("/docs"))
+		})
+
+		It("Should pass a nil folder when it was deleted", func() {
+			c, server := newTestClient(folderServer(false))
+			defer server.Close()
+
+			e := NewEventsClient("http://localhost", &testAuthManager{token: "token"},
+				WithFolderChangeHandler(handler), WithFolderResolution(c))
+
+			e.r.FsFolderChange(&events.FolderChange{Folder: "/docs", ParentFolder: "/"})
+
+			Expect(calls).To(Equal(1))
+			Expect(change.Folder).To(Equal("/docs"))
+			Expect(resolved).To(BeNil())
+			Expect(resolveErr).To(BeNil())
+		})
+
+		It("Should give up resolving a folder after the timeout", func() {
+			mux := http.NewServeMux()
+
+			// The body must be read for the server to notice the client giving up
+			mux.HandleFunc(testPath(apiFolder), func(w http.ResponseWriter, r *http.Request) {
+				_, _ = io.Copy(io.Discard, r.Body)
+
+				<-r.Context().Done()
+			})
+
+			c, server := newTestClient(mux)
+			defer server.Close()
+
+			e := NewEventsClient("http://localhost", &testAuthManager{token: "token"},
+				WithFolderChangeHandler(handler), WithFolderResolution(c),
+				WithFolderResolutionTimeout(20*time.Millisecond))
+
+			e.r.FsFolderChange(&events.FolderChange{Folder: "/docs", ParentFolder: "/"})
+
+			Expect(calls).To(Equal(1))
+			Expect(resolved).To(BeNil())
+			Expect(resolveErr).To(MatchError(context.DeadlineExceeded))
+		})
+	})
+})
@@ -3,56 +3,115 @@ package hoist
 import (
 	"bytes"
 	"context"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"github.com/google/uuid"
+	"github.com/namecrane/hoist/internal/timestamp"
+	"github.com/namecrane/hoist/internal/validate"
+	log "github.com/sirupsen/logrus"
+	"hash"
 	"io"
-	"math"
+	"iter"
 	"mime/multipart"
 	"net/http"
+	"net/url"
 	"path"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
 const (
 	defaultFileType    = "application/octet-stream"
 	contextFileStorage = "file-storage"
-	maxChunkSize       = 15 * 1024 * 1024 // 15 MB
-
-	apiUpload       = "api/upload"
-	apiDiskUsage    = "api/v1/filestorage/disk-usage-summary"
-	apiFiles        = "api/v1/filestorage/files"
-	apiDeleteFiles  = "api/v1/filestorage/delete-files"
-	apiMoveFiles    = "api/v1/filestorage/move-files"
-	apiEditFile     = "api/v1/filestorage/{fileId}/edit"
-	apiGetFileLink  = "api/v1/filestorage/{fileId}/getlink"
-	apiFolder       = "api/v1/filestorage/folder"
-	apiFolders      = "api/v1/filestorage/folders"
-	apiPutFolder    = "api/v1/filestorage/folder-put"
-	apiDeleteFolder = "api/v1/filestorage/delete-folder"
-	apiPatchFolder  = "api/v1/filestorage/folder-patch"
-	apiFileDownload = "api/v1/filestorage/%s/download"
+	defaultChunkSize   = 15 * 1024 * 1024 // 15 MB, see WithChunkSize
+	maxCombineAttempts = 3
+	maxChunkAttempts   = 3
+	combineRetryDelay  = 2 * time.Second
+	editConcurrency    = 4
+
+	// Endpoints containing {version} use the client's API version (see WithAPIVersion), apiUpload is unversioned
+	apiUpload        = "api/upload"
+	apiDiskUsage     = "api/{version}/filestorage/disk-usage-summary"
+	apiFiles         = "api/{version}/filestorage/files"
+	apiDeleteFiles   = "api/{version}/filestorage/delete-files"
+	apiMoveFiles     = "api/{version}/filestorage/move-files"
+	apiEditFile      = "api/{version}/filestorage/{fileId}/edit"
+	apiGetFileLink   = "api/{version}/filestorage/{fileId}/getlink"
+	apiFolder        = "api/{version}/filestorage/folder"
+	apiFolders       = "api/{version}/filestorage/folders"
+	apiPutFolder     = "api/{version}/filestorage/folder-put"
+	apiDeleteFolder  = "api/{version}/filestorage/delete-folder"
+	apiPatchFolder   = "api/{version}/filestorage/folder-patch"
+	apiFileDownload  = "api/{version}/filestorage/{fileId}/download"
+	apiPatchFile     = "api/{version}/filestorage/{fileId}/patch"
+	apiSharedFiles   = "api/{version}/filestorage/shared-files"
+	apiModifiedFiles = "api/{version}/filestorage/modified-files"
+)
+
+var (
+	ErrCombineFailed       = errors.New("failed to combine uploaded file")
+	ErrShortChunk          = errors.New("chunk is shorter than its declared size")
+	ErrInvalidResume       = validate.ErrInvalidResume
+	ErrInvalidMove         = validate.ErrInvalidMove
+	ErrConflict            = errors.New("file was modified concurrently")
+	ErrInvalidMaxDownloads = errors.New("invalid max downloads")
+	ErrInvalidFolderName   = validate.ErrInvalidFolderName
+	ErrUploadTooLarge      = validate.ErrUploadTooLarge
 )
 
 type FileClient interface {
 	DiskUsageSummary(ctx context.Context) (*DiskUsage, error)
-	ChunkedUpload(ctx context.Context, in io.Reader, filePath string, fileSize int64) (*File, error)
+	Upload(ctx context.Context, in io.Reader, filePath string, fileSize int64) (*File, error)
+	ChunkedUpload(ctx context.Context, in io.Reader, filePath string, fileSize int64, opts ...UploadOpt) (*File, error)
+	ChunkedUploadAt(ctx context.Context, ra io.ReaderAt, filePath string, fileSize int64, opts ...UploadOpt) (*File, error)
+	ResumeUpload(ctx context.Context, ra io.ReaderAt, filePath string, fileSize int64, identifier string, startChunk int, opts ...UploadOpt) (*File, error)
+	UploadReadSeeker(ctx context.Context, rs io.ReadSeeker, remotePath string, opts ...UploadOpt) (*File, error)
+	WriteFile(ctx context.Context, remotePath string, data []byte, opts ...UploadOpt) (*File, error)
+	UploadChatFile(ctx context.Context, in io.Reader, conversationID, fileName string, size int64) (*File, error)
 	ParsePath(path string) (basePath, lastSegment string)
 	GetFolders(ctx context.Context) ([]Folder, error)
+	EnsureRoot(ctx context.Context) (*Folder, error)
+	AllFiles(ctx context.Context, opts ...TraversalOpt) ([]File, error)
+	Manifest(ctx context.Context, root string) ([]FileEntry, error)
+	Diff(ctx context.Context, localDir, remoteRoot string, opts ...DiffOpt) (toUpload, toDownload, toDelete []string, err error)
 	GetFolder(ctx context.Context, folder string, opts ...FolderOpt) (*Folder, error)
+	StreamFolder(ctx context.Context, folder string, fn func(file File) error, opts ...FolderOpt) (*Folder, error)
+	GetFolderMeta(ctx context.Context, folderPath string) (*Folder, error)
+	HasChildren(ctx context.Context, folderPath string) (bool, error)
 	GetFiles(ctx context.Context, ids ...string) ([]File, error)
+	Files(ctx context.Context, folder string) iter.Seq2[File, error]
+	Folders(ctx context.Context) iter.Seq2[Folder, error]
+	GetFilesWithFields(ctx context.Context, fields []string, ids ...string) ([]File, error)
+	Ancestry(ctx context.Context, fileID string) ([]Folder, *File, error)
 	DeleteFiles(ctx context.Context, ids ...string) error
 	DownloadFile(ctx context.Context, id string, opts ...RequestOpt) (io.ReadCloser, error)
+	OpenDownload(ctx context.Context, id string, opts ...DownloadOpt) (*Download, error)
+	ReadFile(ctx context.Context, fileID string, opts ...DownloadOpt) ([]byte, error)
+	DownloadURL(ctx context.Context, fileID string) (string, error)
+	PatchFile(ctx context.Context, fileID string, r io.Reader, offset, length int64) error
 	GetFileID(ctx context.Context, dir, fileName string) (string, error)
 	Find(ctx context.Context, file string) (*Folder, *File, error)
+	GetFileByPath(ctx context.Context, filePath string) (*File, error)
 	CreateFolder(ctx context.Context, folder string) (*Folder, error)
+	CreateSubfolder(ctx context.Context, parentPath, name string) (*Folder, error)
+	MkdirAll(ctx context.Context, folder string, opts ...MkdirOpt) (*Folder, error)
+	UploadDir(ctx context.Context, localDir, remoteDir string, opts ...UploadDirOpt) (map[string]*File, map[string]error)
+	DownloadDir(ctx context.Context, remoteDir, localDir string, opts ...DownloadDirOpt) (map[string]*File, map[string]error)
 	DeleteFolder(ctx context.Context, folder string) error
 	MoveFiles(ctx context.Context, folder string, fileIDs ...string) error
+	MoveFilesResult(ctx context.Context, folder string, fileIDs ...string) ([]File, error)
 	RenameFile(ctx context.Context, fileID string, name string) error
 	EditFile(ctx context.Context, fileID string, params EditFileParams) error
+	EditFileIfMatch(ctx context.Context, fileID, version string, params EditFileParams) error
+	EditFiles(ctx context.Context, ids []string, patch EditFilePatch) (map[string]error, error)
 	GetLink(ctx context.Context, fileID string) (string, string, error)
+	GetLinkStatus(ctx context.Context, fileID string) (*LinkStatus, error)
+	ListShared(ctx context.Context) ([]SharedFile, error)
+	FilesModifiedSince(ctx context.Context, since time.Time) ([]File, error)
 	MoveFolder(ctx context.Context, folder, newParentFolder, newName string) error
 }
 
@@ -83,7 +142,7 @@ func (c *client) DiskUsageSummary(ctx context.Context) (*DiskUsage, error) {
 	}
 
 	if res.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("%w: %d", ErrUnexpectedStatus, res.StatusCode)
+		return nil, statusError(res)
 	}
 
 	var response diskUsageResponse
@@ -98,12 +157,33 @@ func (c *client) DiskUsageSummary(ctx context.Context) (*DiskUsage, error) {
 
 // uploadChunk uploads a chunk, then waits for it to be accepted.
 // When the last chunk is uploaded, the backend will combine the file, then return a 200 with a body.
+// The chunk is read before sending, failing with ErrShortChunk if reader yields fewer than chunkSize bytes, as the
+// server would otherwise reject it with a confusing error or combine a corrupt file.
 func (c *client) uploadChunk(ctx context.Context, reader io.Reader, fileName string, fileSize, chunkSize int64, fields map[string]string) (*Response, error) {
+	var data bytes.Buffer
+
+	data.Grow(int(chunkSize))
+
+	n, err := io.CopyN(&data, reader, chunkSize)
+
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to copy chunk data: %w", err)
+	}
+
+	if n != chunkSize {
+		return nil, fmt.Errorf("%w: chunk %s expected %d bytes, read %d", ErrShortChunk, fields["resumableChunkNumber"], chunkSize, n)
+	}
+
 	// Send POST request to upload
 	var requestBody bytes.Buffer
 	writer := multipart.NewWriter(&requestBody)
 
 	for key, value := range fields {
+		// The declared size always matches the bytes sent
+		if key == "resumableCurrentChunkSize" {
+			value = strconv.FormatInt(n, 10)
+		}
+
 		if err := writer.WriteField(key, value); err != nil {
 			return nil, fmt.Errorf("failed to write field %s: %w", key, err)
 		}
@@ -116,7 +196,7 @@ func (c *client) uploadChunk(ctx context.Context, reader io.Reader, fileName str
 		return nil, fmt.Errorf("failed to create form file: %w", err)
 	}
 
-	if _, err = io.CopyN(part, reader, chunkSize); err != nil && err != io.EOF {
+	if _, err = data.WriteTo(part); err != nil {
 		return nil, fmt.Errorf("failed to copy chunk data: %w", err)
 	}
 
@@ -137,12 +217,51 @@ func (c *client) uploadChunk(ctx context.Context, reader io.Reader, fileName str
 	return resp, err
 }
 
+// ChunkSize returns how many bytes ChunkedUpload sends per chunk. Files up to this size fit in a single request.
+// It's the WithChunkSize size, capped at the backend's Capabilities.MaxChunkSize once Capabilities has been called.
+func (c *client) ChunkSize() int64 {
+	if limit := c.maxChunkSize.Load(); limit > 0 {
+		return min(c.chunkSize, limit)
+	}
+
+	return c.chunkSize
+}
+
+// Upload pushes a file to the client API in a single request. The API has no single-shot upload endpoint, so this
+// is a resumable upload with one chunk, saving the overhead of ChunkedUpload for small files. Files larger than
+// ChunkSize fail with ErrUploadTooLarge, and must be sent with ChunkedUpload instead.
 func (c *client) Upload(ctx context.Context, in io.Reader, filePath string, fileSize int64) (*File, error) {
-	return nil, nil
+	chunkSize := c.ChunkSize()
+
+	if err := validate.Upload(fileSize, chunkSize); err != nil {
+		return nil, err
+	}
+
+	done, err := c.beginUpload()
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer done()
+
+	fields, filePath, _, err := c.uploadFields(filePath, fileSize, chunkSize, defaultFileType)
+
+	if err != nil {
+		return nil, err
+	}
+
+	fields["resumableChunkSize"] = strconv.FormatInt(chunkSize, 10)
+	fields["resumableTotalChunks"] = "1"
+	fields["resumableChunkNumber"] = "1"
+	fields["resumableCurrentChunkSize"] = strconv.FormatInt(fileSize, 10)
+
+	return c.uploadFinalChunk(ctx, in, filePath, fileSize, fileSize, fields, nil, c.finalChunkRetry(uploadOptions{}))
 }
 
-// ChunkedUpload will push a file to the client API
-func (c *client) ChunkedUpload(ctx context.Context, in io.Reader, filePath string, fileSize int64) (*File, error) {
+// uploadFields returns the resumable upload fields shared by every chunk of an upload to filePath in chunks of
+// chunkSize, along with the normalized absolute path and the number of chunks
+func (c *client) uploadFields(filePath string, fileSize, chunkSize int64, fileType string) (map[string]string, string, int, error) {
 	fileName := path.Base(filePath)
 
 	// encode brackets, fixing bug within uploader
@@ -160,34 +279,25 @@ func (c *client) ChunkedUpload(ctx context.Context, in io.Reader, filePath strin
 	})
 
 	if err != nil {
-		return nil, err
+		return nil, "", 0, err
 	}
 
 	contextData := string(contextBytes)
 
-	// Calculate total chunks
-	var totalChunks int
+	// Empty files are still uploaded as a single chunk, allowing their creation
+	totalChunks := ChunkCount(fileSize, chunkSize)
 
-	// Allow creation of empty files
-	if fileSize == 0 {
-		totalChunks = 1
-	} else {
-		totalChunks = int(math.Ceil(float64(fileSize) / maxChunkSize))
-	}
-
-	remaining := fileSize
-
-	id, err := uuid.NewV7()
+	id, err := c.newID()
 
 	if err != nil {
-		return nil, err
+		return nil, "", 0, err
 	}
 
 	fields := map[string]string{
-		"resumableChunkSize":    strconv.FormatInt(maxChunkSize, 10),
+		"resumableChunkSize":    strconv.FormatInt(chunkSize, 10),
 		"resumableTotalSize":    strconv.FormatInt(fileSize, 10),
-		"resumableIdentifier":   id.String(),
-		"resumableType":         defaultFileType,
+		"resumableIdentifier":   id,
+		"resumableType":         fileType,
 		"resumableFilename":     fileName,
 		"resumableRelativePath": fileName,
 		"resumableTotalChunks":  strconv.Itoa(totalChunks),
@@ -195,54 +305,230 @@ func (c *client) ChunkedUpload(ctx context.Context, in io.Reader, filePath strin
 		"contextData":           contextData,
 	}
 
-	var res *Response
+	return fields, path.Join(basePath, fileName), totalChunks, nil
+}
+
+// ChunkedUpload will push a file to the client API
+func (c *client) ChunkedUpload(ctx context.Context, in io.Reader, filePath string, fileSize int64, opts ...UploadOpt) (*File, error) {
+	done, err := c.beginUpload()
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer done()
 
-	for chunk := 1; chunk <= totalChunks; chunk++ {
-		chunkSize := int64(maxChunkSize)
+	return c.chunkedUpload(ctx, in, filePath, fileSize, opts...)
+}
+
+func (c *client) chunkedUpload(ctx context.Context, in io.Reader, filePath string, fileSize int64, opts ...UploadOpt) (*File, error) {
+	o := c.newUploadOptions(opts)
+
+	fileType := defaultFileType
+
+	if o.gzip {
+		var compressed bool
+		var err error
 
-		if remaining < maxChunkSize {
-			chunkSize = remaining
+		var cleanup func()
+
+		in, fileSize, compressed, cleanup, err = gzipUpload(in, filePath, fileSize)
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to compress upload: %w", err)
 		}
 
+		defer cleanup()
+
+		if compressed {
+			filePath += ".gz"
+			fileType = gzipFileType
+		}
+	}
+
+	if o.checksum != nil {
+		o.checksum.Reset()
+
+		in = io.TeeReader(in, o.checksum)
+	}
+
+	fields, filePath, totalChunks, err := c.uploadFields(filePath, fileSize, o.chunkSize, fileType)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := o.applyContext(fields); err != nil {
+		return nil, err
+	}
+
+	o.applyIdentifier(fields)
+
+	fileName := path.Base(filePath)
+
+	progress := o.newProgress(fileSize)
+
+	if o.concurrency > 1 && totalChunks > 1 {
+		return c.uploadChunksConcurrently(ctx, in, filePath, fileSize, totalChunks, fields, o, progress)
+	}
+
+	retry := o.chunkRetry(c.defaultChunkRetry(chunkRetry{attempts: 1}))
+
+	for chunk := 1; chunk <= totalChunks; chunk++ {
+		_, chunkSize := ChunkBounds(chunk-1, fileSize, o.chunkSize)
+
 		// strconv.FormatInt is pretty much fmt.Sprintf but without needing to parse the format, replace things, etc.
 		// base 10 is the default, see strconv.Itoa
 		fields["resumableChunkNumber"] = strconv.Itoa(chunk)
 		fields["resumableCurrentChunkSize"] = strconv.FormatInt(chunkSize, 10)
 
-		// --- Prepare the chunk payload ---
-		res, err = c.uploadChunk(ctx, in, fileName, fileSize, chunkSize, fields)
+		if chunk == totalChunks {
+			file, err := c.uploadFinalChunk(ctx, in, filePath, fileSize, chunkSize, fields, o.checksum, c.finalChunkRetry(o))
+
+			if err != nil {
+				return nil, err
+			}
+
+			progress.add(chunkSize)
+
+			return file, nil
+		}
+
+		// Buffer the chunk so that retries re-send the same bytes. A short read is sent as is, for uploadChunk to reject.
+		buf := make([]byte, chunkSize)
+
+		n, err := io.ReadFull(in, buf)
+
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return nil, fmt.Errorf("chunk upload failed, error: failed to copy chunk data: %w", err)
+		}
+
+		err = c.retryChunk(ctx, filePath, fields, retry, func() (*Response, error) {
+			return c.uploadChunk(ctx, bytes.NewReader(buf[:n]), fileName, fileSize, chunkSize, fields)
+		})
 
 		if err != nil {
-			return nil, fmt.Errorf("chunk upload failed, error: %w", err)
+			return nil, err
 		}
 
-		if res.StatusCode != http.StatusOK {
-			var status defaultResponse
+		progress.add(chunkSize)
+	}
+
+	return nil, errors.New("no response from endpoint")
+}
 
-			if err := res.Decode(&status); err != nil {
-				return nil, fmt.Errorf("chunk %d upload failed, status: %d, response: %s", chunk, res.StatusCode, string(res.Data()))
-			}
+// uploadFinalChunk uploads the last chunk, which triggers the server to combine the file.
+// The combine step occasionally times out even though all chunks are present, so the final chunk is buffered
+// and re-sent as allowed by retry after a network error, a 5xx response or when the combined file isn't returned.
+// If the server accepts the chunk but returns an empty or non-JSON body, the file is looked up by path instead.
+// When checksum is set, it has already hashed the earlier chunks and its digest is sent with the final chunk.
+func (c *client) uploadFinalChunk(ctx context.Context, in io.Reader, filePath string, fileSize, chunkSize int64, fields map[string]string, checksum hash.Hash, retry chunkRetry) (*File, error) {
+	fileName := path.Base(filePath)
+
+	var buf bytes.Buffer
+
+	if _, err := io.CopyN(&buf, in, chunkSize); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to copy chunk data: %w", err)
+	}
+
+	var digest string
+
+	if checksum != nil {
+		digest = hex.EncodeToString(checksum.Sum(nil))
+
+		fields[checksumField] = digest
+	}
 
-			return nil, fmt.Errorf("chunk %d upload failed, status: %d, message: %s", chunk, res.StatusCode, status.Message)
+	chunk := fields["resumableChunkNumber"]
+
+	var lastErr error
+
+	for attempt := 1; attempt <= max(retry.attempts, 1); attempt++ {
+		if attempt > 1 {
+			log.WithFields(log.Fields{
+				"file":    fileName,
+				"attempt": attempt,
+				"error":   lastErr,
+			}).Debug("Retrying upload combine")
+
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(retry.delay(attempt)):
+			}
 		}
 
-		if chunk == totalChunks {
-			var file File
+		res, err := c.uploadChunk(ctx, bytes.NewReader(buf.Bytes()), fileName, fileSize, chunkSize, fields)
 
-			if err := res.Decode(&file); err != nil {
-				return nil, err
+		if err != nil {
+			if !retryableSend(ctx, err) {
+				return nil, fmt.Errorf("chunk upload failed, error: %w", err)
 			}
 
+			lastErr = err
+			continue
+		}
+
+		if res.StatusCode >= http.StatusInternalServerError {
+			lastErr = chunkError(chunk, res)
+			continue
+		} else if res.StatusCode != http.StatusOK {
+			return nil, chunkError(chunk, res)
+		}
+
+		var file File
+
+		if err := res.Decode(&file); err == nil && file.ID != "" {
+			file.Checksum = digest
+
 			return &file, nil
-		} else {
-			_ = res.Close()
 		}
 
-		// Update progress
-		remaining -= chunkSize
+		// Files outside of file storage, such as chat files, can't be looked up by path
+		if fields["context"] != contextFileStorage {
+			return nil, fmt.Errorf("%w: no file in response", ErrCombineFailed)
+		}
+
+		// The final chunk was accepted, but the combined file wasn't returned in the body
+		found, err := c.GetFileByPath(ctx, filePath)
+
+		if err == nil {
+			found.Checksum = digest
+
+			return found, nil
+		}
+
+		lastErr = fmt.Errorf("no file in response, lookup failed: %w", err)
 	}
 
-	return nil, errors.New("no response from endpoint")
+	return nil, fmt.Errorf("%w: %w", ErrCombineFailed, lastErr)
+}
+
+// chunkError builds an error from a failed chunk upload response, including the server's message if available
+func chunkError(chunk string, res *Response) error {
+	return fmt.Errorf("chunk %s upload failed: %w", chunk, statusError(res))
+}
+
+// UploadReadSeeker uploads the remainder of rs (from its current offset) to remotePath.
+// The size is determined by seeking, so an already-open *os.File or in-memory reader can be uploaded without buffering.
+func (c *client) UploadReadSeeker(ctx context.Context, rs io.ReadSeeker, remotePath string, opts ...UploadOpt) (*File, error) {
+	start, err := rs.Seek(0, io.SeekCurrent)
+
+	if err != nil {
+		return nil, err
+	}
+
+	end, err := rs.Seek(0, io.SeekEnd)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := rs.Seek(start, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	return c.ChunkedUpload(ctx, rs, remotePath, end-start, opts...)
 }
 
 type ListResponse struct {
@@ -254,26 +540,45 @@ type FolderResponse struct {
 	Folder Folder `json:"folder"`
 }
 
-// GetFolders returns all folders at the root level
+// GetFolders returns the root folder followed by every folder below it, or no folders if the account has no root
+// folder (see WithEnsureRoot)
 func (c *client) GetFolders(ctx context.Context) ([]Folder, error) {
-	res, err := c.doRequest(ctx, http.MethodGet, apiFolders, nil)
+	root, err := c.getRoot(ctx)
 
 	if err != nil {
 		return nil, err
 	}
 
-	if res.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("%w: %d", ErrUnexpectedStatus, res.StatusCode)
+	if root == nil && c.ensureRoot {
+		root, err = c.EnsureRoot(ctx)
+
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	var response FolderResponse
+	// No root folder, see WithEnsureRoot
+	if root == nil {
+		return []Folder{}, nil
+	}
 
-	if err := res.Decode(&response); err != nil {
+	return root.Flatten(), nil
+}
+
+// AllFiles returns every file in the account, with FolderPath populated from the containing folder
+func (c *client) AllFiles(ctx context.Context, opts ...TraversalOpt) ([]File, error) {
+	folders, err := c.GetFolders(ctx)
+
+	if err != nil {
 		return nil, err
 	}
 
-	// Root folder is response.Folder
-	return response.Folder.Flatten(), nil
+	if len(folders) == 0 {
+		return nil, ErrNoFolder
+	}
+
+	// folders[0] is always the root folder
+	return folders[0].AllFiles(opts...), nil
 }
 
 // FolderOpt allows defining folder request options
@@ -286,7 +591,8 @@ func WithStartIndex(index int) FolderOpt {
 	}
 }
 
-// WithCount specifies the number of items to return in a folder request
+// WithCount specifies the number of files to return in a folder request. A count of 0, the default, doesn't limit
+// them, returning every file in the folder.
 func WithCount(count int) FolderOpt {
 	return func(f *folderRequest) {
 		f.Count = &count
@@ -295,6 +601,23 @@ func WithCount(count int) FolderOpt {
 
 // GetFolder returns a single folder
 func (c *client) GetFolder(ctx context.Context, folder string, opts ...FolderOpt) (*Folder, error) {
+	res, err := c.requestFolder(ctx, folder, opts)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var folderResponse FolderResponse
+
+	if err := res.Decode(&folderResponse); err != nil {
+		return nil, err
+	}
+
+	return folderResponse.result()
+}
+
+// requestFolder requests the folder with opts applied, returning the response once its status is checked
+func (c *client) requestFolder(ctx context.Context, folder string, opts []FolderOpt) (*Response, error) {
 	var zero int
 
 	req := folderRequest{
@@ -316,52 +639,136 @@ func (c *client) GetFolder(ctx context.Context, folder string, opts ...FolderOpt
 	}
 
 	if res.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("%w: %d", ErrUnexpectedStatus, res.StatusCode)
+		return nil, statusError(res)
 	}
 
-	var folderResponse FolderResponse
+	return res, nil
+}
 
-	if err := res.Decode(&folderResponse); err != nil {
+// result returns the decoded folder, or the error reported by the API
+func (r *FolderResponse) result() (*Folder, error) {
+	if !r.Success {
+		if r.Message == "Folder not found" {
+			return nil, ErrNoFolder
+		}
+
+		return nil, fmt.Errorf("received error from API: %s", r.Message)
+	}
+
+	return &r.Folder, nil
+}
+
+// GetFolderMeta returns a folder's metadata (size, version, count and subfolders) without its file list.
+// Backends supporting metadataOnly skip the files entirely. Others are asked for a single file, as a count of 0
+// returns every file, and any returned are stripped client-side.
+func (c *client) GetFolderMeta(ctx context.Context, folderPath string) (*Folder, error) {
+	folder, err := c.folderMeta(ctx, folderPath)
+
+	if err != nil {
 		return nil, err
 	}
 
-	if !folderResponse.Success {
-		if folderResponse.Message == "Folder not found" {
-			return nil, ErrNoFolder
-		}
+	folder.Files = nil
+
+	return folder, nil
+}
 
-		return nil, fmt.Errorf("received error from API: %s", folderResponse.Message)
+// folderMeta requests a folder's metadata, along with at most one file from backends which don't support
+// metadataOnly
+func (c *client) folderMeta(ctx context.Context, folderPath string) (*Folder, error) {
+	return c.GetFolder(ctx, folderPath, WithCount(1), func(f *folderRequest) {
+		f.MetadataOnly = true
+	})
+}
+
+// HasChildren returns true if the folder contains any files or subfolders.
+// Only the folder's metadata is requested (see GetFolderMeta), relying on the reported count and subfolders, or
+// the single file returned by backends which don't support metadataOnly.
+func (c *client) HasChildren(ctx context.Context, folderPath string) (bool, error) {
+	folder, err := c.folderMeta(ctx, folderPath)
+
+	if err != nil {
+		return false, err
 	}
 
-	return &folderResponse.Folder, nil
+	return folder.Count > 0 || len(folder.Subfolders) > 0 || len(folder.Files) > 0, nil
 }
 
 // filesRequest is a struct containing the appropriate fields for making a `GetFiles` request
 type filesRequest struct {
 	FileIDs []string `json:"fileIds"`
+	Fields  []string `json:"fields,omitempty"`
 }
 
 // GetFiles returns file data of the specified files
 func (c *client) GetFiles(ctx context.Context, ids ...string) ([]File, error) {
-	res, err := c.doRequest(ctx, http.MethodPost, apiFiles, filesRequest{
-		FileIDs: ids,
-	})
+	if c.filesCoalescer != nil {
+		return c.filesCoalescer.get(ctx, ids)
+	}
+
+	return c.GetFilesWithFields(ctx, nil, ids...)
+}
+
+// Ancestry returns the chain of folders from the root to the folder containing the file, along with the file itself
+func (c *client) Ancestry(ctx context.Context, fileID string) ([]Folder, *File, error) {
+	files, err := c.GetFiles(ctx, fileID)
 
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	if res.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("%w: %d", ErrUnexpectedStatus, res.StatusCode)
+	if len(files) == 0 {
+		return nil, nil, ErrNoFile
 	}
 
-	var response ListResponse
+	file := files[0]
 
-	if err := res.Decode(&response); err != nil {
-		return nil, err
+	folders, err := c.GetFolders(ctx)
+
+	if err != nil {
+		return nil, nil, err
 	}
 
-	return response.Files, nil
+	byPath := make(map[string]Folder)
+
+	for _, folder := range folders {
+		byPath[path.Clean("/"+folder.Path)] = folder
+	}
+
+	// Build each path from the root down, e.g. /, /a, /a/b
+	paths := []string{"/"}
+
+	current := "/"
+
+	for _, segment := range strings.Split(strings.Trim(path.Clean("/"+file.FolderPath), "/"), "/") {
+		if segment == "" {
+			continue
+		}
+
+		current = path.Join(current, segment)
+
+		paths = append(paths, current)
+	}
+
+	ancestry := make([]Folder, 0, len(paths))
+
+	for _, p := range paths {
+		folder, ok := byPath[p]
+
+		if !ok {
+			fetched, err := c.GetFolder(ctx, p)
+
+			if err != nil {
+				return nil, nil, err
+			}
+
+			folder = *fetched
+		}
+
+		ancestry = append(ancestry, folder)
+	}
+
+	return ancestry, &file, nil
 }
 
 // DeleteFiles deletes the remote files specified by ids
@@ -375,33 +782,88 @@ func (c *client) DeleteFiles(ctx context.Context, ids ...string) error {
 	}
 
 	if res.StatusCode != http.StatusOK {
-		return fmt.Errorf("%w: %d", ErrUnexpectedStatus, res.StatusCode)
+		return statusError(res)
 	}
 
-	var response defaultResponse
-
-	if err := res.Decode(&response); err != nil {
-		return err
-	}
-
-	return nil
+	return checkSuccess("delete files", res)
 }
 
 // DownloadFile opens the specified file as an io.ReadCloser, with optional `opts` (range header, etc)
 func (c *client) DownloadFile(ctx context.Context, id string, opts ...RequestOpt) (io.ReadCloser, error) {
-	res, err := c.doRequest(ctx, http.MethodGet, fmt.Sprintf(apiFileDownload, id), nil, opts...)
+	opts = append([]RequestOpt{WithURLParameter("fileId", id)}, opts...)
+
+	res, err := c.doRequest(ctx, http.MethodGet, apiFileDownload, nil, opts...)
 
 	if err != nil {
 		return nil, err
 	}
 
-	if res.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("%w: %d", ErrUnexpectedStatus, res.StatusCode)
+	// Ranged requests are answered with 206 Partial Content
+	if res.StatusCode != http.StatusOK && res.StatusCode != http.StatusPartialContent {
+		return nil, statusError(res)
 	}
 
 	return res.Body, nil
 }
 
+// PatchFile overwrites length bytes of the file starting at offset with data read from r, without re-uploading the
+// rest of the file. The range is sent in a Content-Range header. Backends without ranged updates return
+// ErrNotSupported, in which case the whole file must be re-uploaded instead.
+func (c *client) PatchFile(ctx context.Context, fileID string, r io.Reader, offset, length int64) error {
+	if offset < 0 || length <= 0 {
+		return fmt.Errorf("invalid patch range: offset %d, length %d", offset, length)
+	}
+
+	res, err := c.doFeatureRequest(ctx, http.MethodPost, apiPatchFile, io.LimitReader(r, length),
+		WithURLParameter("fileId", fileID),
+		WithContentType(defaultFileType),
+		WithHeader("Content-Range", fmt.Sprintf("bytes %d-%d/*", offset, offset+length-1)),
+		func(req *http.Request) {
+			req.ContentLength = length
+		},
+	)
+
+	if err != nil {
+		return err
+	}
+
+	if res.StatusCode == http.StatusNoContent {
+		return res.Close()
+	} else if res.StatusCode != http.StatusOK {
+		return statusError(res)
+	}
+
+	return checkSuccess("patch file", res)
+}
+
+// DownloadURL returns a fully-qualified download URL for the file which can be handed off to browsers or other
+// processes. The current access token is embedded in the URL as the `access_token` query parameter, so the URL is as
+// sensitive as the token itself: anyone holding it can read the file (and use the token) until the token expires.
+// Avoid logging it or exposing it anywhere it may be cached, such as browser history or proxy logs.
+func (c *client) DownloadURL(ctx context.Context, fileID string) (string, error) {
+	token, err := c.authManager.GetToken(ctx)
+
+	if err != nil {
+		return "", fmt.Errorf("failed to retrieve token: %w", err)
+	}
+
+	apiUrl, err := c.apiUrl(strings.Replace(apiFileDownload, "{fileId}", url.PathEscape(fileID), 1))
+
+	if err != nil {
+		return "", err
+	}
+
+	u, err := url.Parse(apiUrl)
+
+	if err != nil {
+		return "", err
+	}
+
+	u.RawQuery = url.Values{"access_token": {token}}.Encode()
+
+	return u.String(), nil
+}
+
 // GetFileID gets a file id from a specified directory and file name
 func (c *client) GetFileID(ctx context.Context, dir, fileName string) (string, error) {
 	var folder *Folder
@@ -413,6 +875,10 @@ func (c *client) GetFileID(ctx context.Context, dir, fileName string) (string, e
 			return "", err
 		}
 
+		if len(folders) == 0 {
+			return "", ErrNoFolder
+		}
+
 		folder = &folders[0]
 	} else {
 		var err error
@@ -446,6 +912,10 @@ func (c *client) Find(ctx context.Context, file string) (*Folder, *File, error)
 			return nil, nil, err
 		}
 
+		if len(folders) == 0 {
+			return nil, nil, ErrNoFolder
+		}
+
 		folder = &folders[0]
 
 		if name == "" {
@@ -476,18 +946,51 @@ func (c *client) Find(ctx context.Context, file string) (*Folder, *File, error)
 	return nil, nil, ErrNoFile
 }
 
+// GetFileByPath returns the file at filePath, or ErrNoFile if it doesn't exist or is a folder
+func (c *client) GetFileByPath(ctx context.Context, filePath string) (*File, error) {
+	_, file, err := c.Find(ctx, filePath)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if file == nil {
+		return nil, ErrNoFile
+	}
+
+	return file, nil
+}
+
 // folderRequest is used for creating and deleting folders
 type folderRequest struct {
-	ParentFolder string `json:"parentFolder,omitempty"`
-	Folder       string `json:"folder"`
-	StartIndex   *int   `json:"startIndex,omitempty"`
-	Count        *int   `json:"count,omitempty"`
+	ParentFolder string   `json:"parentFolder,omitempty"`
+	Folder       string   `json:"folder"`
+	StartIndex   *int     `json:"startIndex,omitempty"`
+	Count        *int     `json:"count,omitempty"`
+	Fields       []string `json:"fields,omitempty"`
+	MetadataOnly bool     `json:"metadataOnly,omitempty"`
 }
 
 // CreateFolder creates a new remote folder
 func (c *client) CreateFolder(ctx context.Context, folder string) (*Folder, error) {
 	parent, subfolder := c.ParsePath(folder)
 
+	return c.createFolder(ctx, parent, subfolder)
+}
+
+// CreateSubfolder creates the folder name inside parentPath. Unlike CreateFolder, the name is used as-is rather than
+// being split from a path, so names such as `a\b` which ParsePath would normalize are preserved. Names which can't
+// be a single folder, such as "", ".." or ones containing a slash, fail with ErrInvalidFolderName.
+func (c *client) CreateSubfolder(ctx context.Context, parentPath, name string) (*Folder, error) {
+	if err := validate.FolderName(name); err != nil {
+		return nil, err
+	}
+
+	return c.createFolder(ctx, path.Clean("/"+parentPath), name)
+}
+
+// createFolder creates subfolder inside parent
+func (c *client) createFolder(ctx context.Context, parent, subfolder string) (*Folder, error) {
 	res, err := c.doRequest(ctx, http.MethodPost, apiPutFolder, folderRequest{
 		ParentFolder: parent,
 		Folder:       subfolder,
@@ -498,7 +1001,7 @@ func (c *client) CreateFolder(ctx context.Context, folder string) (*Folder, erro
 	}
 
 	if res.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("%w: %d", ErrUnexpectedStatus, res.StatusCode)
+		return nil, statusError(res)
 	}
 
 	var response FolderResponse
@@ -508,7 +1011,7 @@ func (c *client) CreateFolder(ctx context.Context, folder string) (*Folder, erro
 	}
 
 	if !response.Success {
-		return nil, fmt.Errorf("failed to create directory, status: %d, response: %s", res.StatusCode, response.Message)
+		return nil, failureError("create directory", res, response.Message)
 	}
 
 	return &response.Folder, nil
@@ -516,6 +1019,10 @@ func (c *client) CreateFolder(ctx context.Context, folder string) (*Folder, erro
 
 // DeleteFolder deletes a specified folder by name
 func (c *client) DeleteFolder(ctx context.Context, folder string) error {
+	if IsRoot(folder) {
+		return ErrRootOperation
+	}
+
 	parent, subfolder := c.ParsePath(folder)
 
 	res, err := c.doRequest(ctx, http.MethodPost, apiDeleteFolder, folderRequest{
@@ -528,7 +1035,7 @@ func (c *client) DeleteFolder(ctx context.Context, folder string) error {
 	}
 
 	if res.StatusCode != http.StatusOK {
-		return fmt.Errorf("%w: %d", ErrUnexpectedStatus, res.StatusCode)
+		return statusError(res)
 	}
 
 	var status defaultResponse
@@ -538,7 +1045,7 @@ func (c *client) DeleteFolder(ctx context.Context, folder string) error {
 	}
 
 	if !status.Success {
-		return fmt.Errorf("failed to remove directory, status: %d, response: %s", res.StatusCode, status.Message)
+		return failureError("remove directory", res, status.Message)
 	}
 
 	return nil
@@ -549,32 +1056,61 @@ type moveFilesRequest struct {
 	FileIDs   []string `json:"fileIDs"`
 }
 
+// moveFilesResponse is the move-files response, where newer backends include the moved files
+type moveFilesResponse struct {
+	defaultResponse
+	Files []File `json:"files"`
+}
+
 // MoveFiles moves files to the specified folder
 func (c *client) MoveFiles(ctx context.Context, folder string, fileIDs ...string) error {
+	_, err := c.moveFiles(ctx, folder, fileIDs)
+
+	return err
+}
+
+// MoveFilesResult moves files to the specified folder, returning the moved files with their new FolderPath.
+// The files are taken from the response when the backend includes them, otherwise they are re-fetched with GetFiles.
+func (c *client) MoveFilesResult(ctx context.Context, folder string, fileIDs ...string) ([]File, error) {
+	files, err := c.moveFiles(ctx, folder, fileIDs)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(files) > 0 {
+		return files, nil
+	}
+
+	return c.GetFiles(ctx, fileIDs...)
+}
+
+// moveFiles performs the move-files request, returning any files included in the response
+func (c *client) moveFiles(ctx context.Context, folder string, fileIDs []string) ([]File, error) {
 	res, err := c.doRequest(ctx, http.MethodPost, apiMoveFiles, moveFilesRequest{
 		NewFolder: folder,
 		FileIDs:   fileIDs,
 	})
 
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if res.StatusCode != http.StatusOK {
-		return fmt.Errorf("%w: %d", ErrUnexpectedStatus, res.StatusCode)
+		return nil, statusError(res)
 	}
 
-	var response FolderResponse
+	var response moveFilesResponse
 
 	if err := res.Decode(&response); err != nil {
-		return err
+		return nil, err
 	}
 
 	if !response.Success {
-		return fmt.Errorf("failed to create directory, status: %d, response: %s", res.StatusCode, response.Message)
+		return nil, failureError("move files", res, response.Message)
 	}
 
-	return nil
+	return response.Files, nil
 }
 
 type editFileRequest struct {
@@ -592,7 +1128,7 @@ func (c *client) RenameFile(ctx context.Context, fileID string, name string) err
 	}
 
 	if res.StatusCode != http.StatusOK {
-		return fmt.Errorf("%w: %d", ErrUnexpectedStatus, res.StatusCode)
+		return statusError(res)
 	}
 
 	var response defaultResponse
@@ -602,7 +1138,7 @@ func (c *client) RenameFile(ctx context.Context, fileID string, name string) err
 	}
 
 	if !response.Success {
-		return fmt.Errorf("failed to create directory, status: %d, response: %s", res.StatusCode, response.Message)
+		return failureError("rename file", res, response.Message)
 	}
 
 	return nil
@@ -614,18 +1150,119 @@ type EditFileParams struct {
 	PublishedUntil     time.Time `json:"publishedUntil"`
 	ShortLink          string    `json:"shortLink"`
 	PublicDownloadLink string    `json:"publicDownloadLink"`
+	// MaxDownloads limits how many times a published link can be downloaded, where supported by the backend.
+	// Zero leaves the link unlimited and isn't sent.
+	MaxDownloads int `json:"maxDownloads,omitempty"`
+}
+
+// Validate checks the params for values the backend would reject, failing with ErrInvalidMaxDownloads for a negative
+// MaxDownloads
+func (p EditFileParams) Validate() error {
+	if p.MaxDownloads < 0 {
+		return fmt.Errorf("%w: %d, must be positive or zero for unlimited", ErrInvalidMaxDownloads, p.MaxDownloads)
+	}
+
+	return nil
+}
+
+// EditFilePatch is a partial update for EditFiles, so one field can be changed on many files without resetting the
+// rest. Nil fields are left out of the request, keeping each file's current value.
+type EditFilePatch struct {
+	Password           *string    `json:"password,omitempty"`
+	Published          *bool      `json:"published,omitempty"`
+	PublishedUntil     *time.Time `json:"publishedUntil,omitempty"`
+	ShortLink          *string    `json:"shortLink,omitempty"`
+	PublicDownloadLink *string    `json:"publicDownloadLink,omitempty"`
+	MaxDownloads       *int       `json:"maxDownloads,omitempty"`
+}
+
+// Validate checks the set fields for values the backend would reject. A set MaxDownloads must be positive, failing
+// with ErrInvalidMaxDownloads otherwise, as leaving it nil is how a patch keeps the current limit.
+func (p EditFilePatch) Validate() error {
+	if p.MaxDownloads != nil && *p.MaxDownloads <= 0 {
+		return fmt.Errorf("%w: %d, must be positive when set", ErrInvalidMaxDownloads, *p.MaxDownloads)
+	}
+
+	return nil
+}
+
+// Apply returns params with the patch's set fields applied
+func (p EditFilePatch) Apply(params EditFileParams) EditFileParams {
+	if p.Password != nil {
+		params.Password = *p.Password
+	}
+
+	if p.Published != nil {
+		params.Published = *p.Published
+	}
+
+	if p.PublishedUntil != nil {
+		params.PublishedUntil = *p.PublishedUntil
+	}
+
+	if p.ShortLink != nil {
+		params.ShortLink = *p.ShortLink
+	}
+
+	if p.PublicDownloadLink != nil {
+		params.PublicDownloadLink = *p.PublicDownloadLink
+	}
+
+	if p.MaxDownloads != nil {
+		params.MaxDownloads = *p.MaxDownloads
+	}
+
+	return params
 }
 
 // EditFile updates a file on the backend
 func (c *client) EditFile(ctx context.Context, fileID string, params EditFileParams) error {
-	res, err := c.doRequest(ctx, http.MethodPost, apiEditFile, params, WithURLParameter("fileId", fileID))
+	return c.editFile(ctx, fileID, params)
+}
+
+// EditFileIfMatch updates a file like EditFile, but only if it's still at version (File.Version), so concurrent edits
+// can't silently overwrite each other. ErrConflict is returned if the file has changed since, in which case the file
+// should be re-read and the edit retried. Files without a version (from backends which don't provide one) return
+// ErrNotSupported.
+func (c *client) EditFileIfMatch(ctx context.Context, fileID, version string, params EditFileParams) error {
+	if version == "" {
+		return fmt.Errorf("%w: file has no version", ErrNotSupported)
+	}
+
+	// Versions are sent as entity tags, which are quoted
+	if !strings.HasPrefix(version, `"`) && !strings.HasPrefix(version, `W/"`) {
+		version = strconv.Quote(version)
+	}
+
+	return c.editFile(ctx, fileID, params, WithHeader("If-Match", version))
+}
+
+func (c *client) editFile(ctx context.Context, fileID string, params EditFileParams, opts ...RequestOpt) error {
+	if err := params.Validate(); err != nil {
+		return err
+	}
+
+	params.PublishedUntil = c.serverExpiry(params.PublishedUntil)
+
+	return c.sendEdit(ctx, fileID, params, opts...)
+}
+
+// sendEdit sends body, either EditFileParams or EditFilePatch, to the edit endpoint for the file
+func (c *client) sendEdit(ctx context.Context, fileID string, body any, opts ...RequestOpt) error {
+	opts = append(opts, WithURLParameter("fileId", fileID))
+
+	res, err := c.doRequest(ctx, http.MethodPost, apiEditFile, body, opts...)
 
 	if err != nil {
 		return err
 	}
 
+	if res.StatusCode == http.StatusPreconditionFailed || res.StatusCode == http.StatusConflict {
+		return fmt.Errorf("%w: %w", ErrConflict, statusError(res))
+	}
+
 	if res.StatusCode != http.StatusOK {
-		return fmt.Errorf("%w: %d", ErrUnexpectedStatus, res.StatusCode)
+		return statusError(res)
 	}
 
 	var response defaultResponse
@@ -635,43 +1272,129 @@ func (c *client) EditFile(ctx context.Context, fileID string, params EditFilePar
 	}
 
 	if !response.Success {
-		return fmt.Errorf("failed to create directory, status: %d, response: %s", res.StatusCode, response.Message)
+		return failureError("edit file", res, response.Message)
 	}
 
 	return nil
 }
 
+// EditFiles applies the same patch to multiple files, editing up to editConcurrency files at once. Only the patch's
+// set fields are sent, so each file keeps its other settings, such as its password and links.
+// The returned map contains the error for each file which failed to update; if any failed, ErrPartialFailure is
+// returned.
+func (c *client) EditFiles(ctx context.Context, ids []string, patch EditFilePatch) (map[string]error, error) {
+	if err := patch.Validate(); err != nil {
+		return nil, err
+	}
+
+	if patch.PublishedUntil != nil {
+		until := c.serverExpiry(*patch.PublishedUntil)
+		patch.PublishedUntil = &until
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	failed := make(map[string]error)
+	sem := make(chan struct{}, editConcurrency)
+
+	for _, id := range ids {
+		select {
+		case <-ctx.Done():
+			mu.Lock()
+			failed[id] = ctx.Err()
+			mu.Unlock()
+			continue
+		case sem <- struct{}{}:
+		}
+
+		wg.Add(1)
+
+		go func(id string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			if err := c.sendEdit(ctx, id, patch); err != nil {
+				mu.Lock()
+				failed[id] = err
+				mu.Unlock()
+			}
+		}(id)
+	}
+
+	wg.Wait()
+
+	if len(failed) > 0 {
+		return failed, fmt.Errorf("%w: %d of %d files failed to update", ErrPartialFailure, len(failed), len(ids))
+	}
+
+	return failed, nil
+}
+
 type linkResponse struct {
 	defaultResponse
-	PublicLink string `json:"publicLink"`
-	ShortLink  string `json:"shortLink"`
-	IsPublic   bool   `json:"isPublic"`
+	PublicLink         string         `json:"publicLink"`
+	ShortLink          string         `json:"shortLink"`
+	IsPublic           bool           `json:"isPublic"`
+	PublishedUntil     timestamp.Time `json:"publishedUntil"`
+	RemainingDownloads *int           `json:"remainingDownloads"`
+}
+
+// LinkStatus describes a file's sharing links
+type LinkStatus struct {
+	ShortLink  string
+	PublicLink string
+	IsPublic   bool
+	// PublishedUntil is when the public link expires, or zero if it doesn't or the backend doesn't report it
+	PublishedUntil time.Time
+	// RemainingDownloads is the number of downloads left on a link published with MaxDownloads,
+	// or nil if the link is unlimited or the backend doesn't report it.
+	RemainingDownloads *int
 }
 
 // GetLink creates a short link and public link to a file
 // This is combined with EditFile to make it public
 func (c *client) GetLink(ctx context.Context, fileID string) (string, string, error) {
-	res, err := c.doRequest(ctx, http.MethodGet, apiGetFileLink, nil, WithURLParameter("fileId", fileID))
+	status, err := c.GetLinkStatus(ctx, fileID)
 
 	if err != nil {
 		return "", "", err
 	}
 
+	return status.ShortLink, status.PublicLink, nil
+}
+
+// GetLinkStatus returns a file's links along with whether it's public and how many downloads remain
+func (c *client) GetLinkStatus(ctx context.Context, fileID string) (*LinkStatus, error) {
+	res, err := c.doRequest(ctx, http.MethodGet, apiGetFileLink, nil, WithURLParameter("fileId", fileID))
+
+	if err != nil {
+		return nil, err
+	}
+
 	if res.StatusCode != http.StatusOK {
-		return "", "", fmt.Errorf("%w: %d", ErrUnexpectedStatus, res.StatusCode)
+		return nil, statusError(res)
 	}
 
 	var response linkResponse
 
 	if err := res.Decode(&response); err != nil {
-		return "", "", err
+		return nil, err
 	}
 
 	if !response.Success {
-		return "", "", fmt.Errorf("failed to create directory, status: %d, response: %s", res.StatusCode, response.Message)
+		return nil, failureError("get link status", res, response.Message)
 	}
 
-	return response.ShortLink, response.PublicLink, nil
+	return &LinkStatus{
+		ShortLink:          response.ShortLink,
+		PublicLink:         response.PublicLink,
+		IsPublic:           response.IsPublic,
+		PublishedUntil:     response.PublishedUntil.Time,
+		RemainingDownloads: response.RemainingDownloads,
+	}, nil
 }
 
 type patchFolderRequest struct {
@@ -683,7 +1406,16 @@ type patchFolderRequest struct {
 }
 
 // MoveFolder moves/renames a folder. If you do not wish to move the folder, send newParentFolder as ""
+// Moving a folder into itself or one of its subfolders fails with ErrInvalidMove, without sending a request.
 func (c *client) MoveFolder(ctx context.Context, folder, newParentFolder, newName string) error {
+	if IsRoot(folder) {
+		return ErrRootOperation
+	}
+
+	if err := validate.Move(folder, newParentFolder); err != nil {
+		return err
+	}
+
 	if newName == "" {
 		_, subfolder := c.ParsePath(newParentFolder)
 
@@ -702,7 +1434,7 @@ func (c *client) MoveFolder(ctx context.Context, folder, newParentFolder, newNam
 	}
 
 	if res.StatusCode != http.StatusOK {
-		return fmt.Errorf("%w: %d (%s)", ErrUnexpectedStatus, res.StatusCode, string(res.Data()))
+		return statusError(res)
 	}
 
 	var response defaultResponse
@@ -712,7 +1444,7 @@ func (c *client) MoveFolder(ctx context.Context, folder, newParentFolder, newNam
 	}
 
 	if !response.Success {
-		return fmt.Errorf("failed to move directory, status: %d, response: %s", res.StatusCode, response.Message)
+		return failureError("move directory", res, response.Message)
 	}
 
 	return nil
@@ -726,6 +1458,10 @@ type File struct {
 	Size       int64     `json:"size"`
 	DateAdded  time.Time `json:"dateAdded"`
 	FolderPath string    `json:"folderPath"`
+	// Version identifies the file's current state for EditFileIfMatch, when the backend provides it
+	Version string `json:"version,omitempty"`
+	// Checksum is the hex digest computed by WithUploadChecksum, only set on the File returned by the upload
+	Checksum string `json:"-"`
 }
 
 // Folder represents a folder object on the remote server
@@ -739,17 +1475,96 @@ type Folder struct {
 	Files      []File   `json:"files"`
 }
 
-// Flatten takes all folders and subfolders, returning them as a single slice
-func (f Folder) Flatten() []Folder {
-	folders := []Folder{f}
+// traversalOptions controls how far tree operations descend
+type traversalOptions struct {
+	maxDepth int
+}
+
+// TraversalOpt allows defining options for tree operations such as Flatten, WalkFolders and AllFiles
+type TraversalOpt func(o *traversalOptions)
+
+// WithMaxDepth limits how deep tree operations descend.
+// Depth 0 is the folder itself, 1 includes its immediate children, etc. A negative depth is unlimited (the default).
+func WithMaxDepth(depth int) TraversalOpt {
+	return func(o *traversalOptions) {
+		o.maxDepth = depth
+	}
+}
+
+func newTraversalOptions(opts []TraversalOpt) traversalOptions {
+	o := traversalOptions{
+		maxDepth: -1,
+	}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
+
+// WalkFolders calls fn for this folder and all subfolders depth-first, along with their depth relative to this folder.
+// Returning an error from fn stops the walk and returns the error.
+func (f Folder) WalkFolders(fn func(folder Folder, depth int) error, opts ...TraversalOpt) error {
+	return f.walkFolders(fn, 0, newTraversalOptions(opts))
+}
+
+func (f Folder) walkFolders(fn func(folder Folder, depth int) error, depth int, o traversalOptions) error {
+	if err := fn(f, depth); err != nil {
+		return err
+	}
+
+	if o.maxDepth >= 0 && depth >= o.maxDepth {
+		return nil
+	}
 
 	for _, folder := range f.Subfolders {
-		folders = append(folders, folder.Flatten()...)
+		if err := folder.walkFolders(fn, depth+1, o); err != nil {
+			return err
+		}
 	}
 
+	return nil
+}
+
+// Flatten takes all folders and subfolders, returning them as a single slice
+func (f Folder) Flatten(opts ...TraversalOpt) []Folder {
+	var folders []Folder
+
+	_ = f.WalkFolders(func(folder Folder, depth int) error {
+		folders = append(folders, folder)
+		return nil
+	}, opts...)
+
 	return folders
 }
 
+// FlattenSorted returns the same folders as Flatten, sorted by Path for stable output
+func (f Folder) FlattenSorted(opts ...TraversalOpt) []Folder {
+	folders := f.Flatten(opts...)
+
+	sort.SliceStable(folders, func(i, j int) bool {
+		return folders[i].Path < folders[j].Path
+	})
+
+	return folders
+}
+
+// AllFiles returns the files of this folder and all subfolders as a single slice, populating FolderPath on each
+func (f Folder) AllFiles(opts ...TraversalOpt) []File {
+	var files []File
+
+	for _, folder := range f.Flatten(opts...) {
+		for _, file := range folder.Files {
+			file.FolderPath = folder.Path
+
+			files = append(files, file)
+		}
+	}
+
+	return files
+}
+
 func (f Folder) Subfolder(name string) *Folder {
 	for _, folder := range f.Subfolders {
 		if folder.Name == name {
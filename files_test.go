@@ -0,0 +1,790 @@
+package hoist
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("File tests", func() {
+	It("Should build a download URL containing the access token", func() {
+		c := NewClient("https://example.com/base", &testAuthManager{token: "secret"})
+
+		downloadUrl, err := c.DownloadURL(context.Background(), "abc123")
+
+		Expect(err).To(BeNil())
+
+		u, err := url.Parse(downloadUrl)
+
+		Expect(err).To(BeNil())
+		Expect(u.Host).To(Equal("example.com"))
+		Expect(u.Path).To(Equal("/base/api/v1/filestorage/abc123/download"))
+		Expect(u.Query().Get("access_token")).To(Equal("secret"))
+	})
+	It("Should list all files with their folder paths", func() {
+		mux := http.NewServeMux()
+
+		mux.HandleFunc(testPath(apiFolders), func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewEncoder(w).Encode(FolderResponse{
+				defaultResponse: defaultResponse{Success: true},
+				Folder:          testTree(),
+			})
+		})
+
+		c, server := newTestClient(mux)
+		defer server.Close()
+
+		files, err := c.AllFiles(context.Background())
+
+		Expect(err).To(BeNil())
+		Expect(files).To(HaveLen(3))
+
+		paths := make(map[string]string)
+
+		for _, file := range files {
+			paths[file.Name] = file.FolderPath
+		}
+
+		Expect(paths).To(Equal(map[string]string{
+			"root.txt": "/",
+			"a.txt":    "/a",
+			"b.txt":    "/a/b",
+		}))
+	})
+	It("Should upload the remainder of a ReadSeeker", func() {
+		upload := &testUploadServer{}
+
+		c, server := newTestClient(upload)
+		defer server.Close()
+
+		rs := bytes.NewReader([]byte("skip:hello world"))
+
+		_, err := rs.Seek(5, io.SeekStart)
+
+		Expect(err).To(BeNil())
+
+		file, err := c.UploadReadSeeker(context.Background(), rs, "/folder/hello.txt")
+
+		Expect(err).To(BeNil())
+		Expect(file.Name).To(Equal("hello.txt"))
+		Expect(file.Size).To(Equal(int64(11)))
+		Expect(string(upload.Data())).To(Equal("hello world"))
+		Expect(upload.Chunks[0].Fields["contextData"]).To(ContainSubstring(`"/folder"`))
+	})
+	It("Should retry the combine step when the final chunk fails", func() {
+		upload := &testUploadServer{}
+
+		var attempts int
+
+		c, server := newTestClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			attempts++
+
+			if attempts == 1 {
+				w.WriteHeader(http.StatusGatewayTimeout)
+				return
+			}
+
+			upload.ServeHTTP(w, r)
+		}))
+		defer server.Close()
+
+		c.combineRetryDelay = 0
+
+		file, err := c.ChunkedUpload(context.Background(), bytes.NewReader([]byte("hello")), "/hello.txt", 5)
+
+		Expect(err).To(BeNil())
+		Expect(file.Name).To(Equal("hello.txt"))
+		Expect(attempts).To(Equal(2))
+		Expect(string(upload.Data())).To(Equal("hello"))
+	})
+	It("Should fail when the combine step never returns a file", func() {
+		c, server := newTestClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewEncoder(w).Encode(defaultResponse{Success: true})
+		}))
+		defer server.Close()
+
+		c.combineRetryDelay = 0
+
+		_, err := c.ChunkedUpload(context.Background(), bytes.NewReader([]byte("hello")), "/hello.txt", 5)
+
+		Expect(errors.Is(err, ErrCombineFailed)).To(BeTrue())
+	})
+	It("Should not retry the combine step on client errors", func() {
+		var attempts int
+
+		c, server := newTestClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			attempts++
+			w.WriteHeader(http.StatusBadRequest)
+		}))
+		defer server.Close()
+
+		c.combineRetryDelay = 0
+
+		_, err := c.ChunkedUpload(context.Background(), bytes.NewReader([]byte("hello")), "/hello.txt", 5)
+
+		Expect(err).ToNot(BeNil())
+		Expect(attempts).To(Equal(1))
+	})
+	It("Should return the ancestry of a file from the root", func() {
+		mux := http.NewServeMux()
+
+		mux.HandleFunc(testPath(apiFiles), func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewEncoder(w).Encode(ListResponse{
+				Files: []File{{ID: "3", Name: "b.txt", FolderPath: "/a/b"}},
+			})
+		})
+
+		mux.HandleFunc(testPath(apiFolders), func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewEncoder(w).Encode(FolderResponse{
+				defaultResponse: defaultResponse{Success: true},
+				Folder:          testTree(),
+			})
+		})
+
+		c, server := newTestClient(mux)
+		defer server.Close()
+
+		folders, file, err := c.Ancestry(context.Background(), "3")
+
+		Expect(err).To(BeNil())
+		Expect(file.Name).To(Equal("b.txt"))
+
+		var paths []string
+
+		for _, folder := range folders {
+			paths = append(paths, folder.Path)
+		}
+
+		Expect(paths).To(Equal([]string{"/", "/a", "/a/b"}))
+	})
+	It("Should use the configured upload identifier", func() {
+		upload := &testUploadServer{}
+
+		c, server := newTestClient(upload, WithIDGenerator(func() (string, error) {
+			return "fixed-id", nil
+		}))
+		defer server.Close()
+
+		_, err := c.ChunkedUpload(context.Background(), bytes.NewReader([]byte("hello")), "/hello.txt", 5)
+
+		Expect(err).To(BeNil())
+		Expect(upload.Chunks[0].Fields["resumableIdentifier"]).To(Equal("fixed-id"))
+	})
+	It("Should send and return a running checksum when requested", func() {
+		upload := &testUploadServer{}
+
+		c, server := newTestClient(upload)
+		defer server.Close()
+
+		file, err := c.ChunkedUpload(context.Background(), bytes.NewReader([]byte("hello")), "/hello.txt", 5, WithUploadChecksum(sha256.New()))
+
+		sum := sha256.Sum256([]byte("hello"))
+
+		Expect(err).To(BeNil())
+		Expect(file.Checksum).To(Equal(hex.EncodeToString(sum[:])))
+		Expect(upload.Chunks[0].Fields).To(HaveKeyWithValue("resumableChecksum", file.Checksum))
+
+		file, err = c.ChunkedUpload(context.Background(), bytes.NewReader([]byte("hello")), "/hello.txt", 5)
+
+		Expect(err).To(BeNil())
+		Expect(file.Checksum).To(BeEmpty())
+		Expect(upload.Chunks[1].Fields).ToNot(HaveKey("resumableChecksum"))
+	})
+	It("Should request only the projected fields", func() {
+		var folderReq, filesReq map[string]any
+
+		mux := http.NewServeMux()
+
+		mux.HandleFunc(testPath(apiFolder), func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewDecoder(r.Body).Decode(&folderReq)
+			_ = json.NewEncoder(w).Encode(FolderResponse{
+				defaultResponse: defaultResponse{Success: true},
+				Folder:          testTree(),
+			})
+		})
+
+		mux.HandleFunc(testPath(apiFiles), func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewDecoder(r.Body).Decode(&filesReq)
+			_, _ = w.Write([]byte(`{"files":[{"id":"1","fileName":"root.txt","size":4}]}`))
+		})
+
+		c, server := newTestClient(mux)
+		defer server.Close()
+
+		_, err := c.GetFolder(context.Background(), "/", WithFields(FileFieldSize))
+
+		Expect(err).To(BeNil())
+		Expect(folderReq["fields"]).To(ConsistOf("id", "fileName", "size"))
+
+		files, err := c.GetFilesWithFields(context.Background(), []string{FileFieldSize}, "1")
+
+		Expect(err).To(BeNil())
+		Expect(filesReq["fields"]).To(ConsistOf("id", "fileName", "size"))
+		Expect(files[0]).To(Equal(File{ID: "1", Name: "root.txt", Size: 4}))
+
+		filesReq = nil
+
+		_, err = c.GetFiles(context.Background(), "1")
+
+		Expect(err).To(BeNil())
+		Expect(filesReq).ToNot(HaveKey("fields"))
+	})
+	It("Should report whether a folder has children", func() {
+		folders := map[string]Folder{
+			"/empty":   {Name: "empty", Path: "/empty"},
+			"/files":   {Name: "files", Path: "/files", Count: 2},
+			"/folders": {Name: "folders", Path: "/folders", Subfolders: []Folder{{Name: "sub", Path: "/folders/sub"}}},
+			// A backend which ignores metadataOnly and doesn't report the count
+			"/listed": {Name: "listed", Path: "/listed", Files: []File{{ID: "1", Name: "a.txt"}}},
+		}
+
+		c, server := newTestClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var req folderRequest
+
+			_ = json.NewDecoder(r.Body).Decode(&req)
+
+			Expect(req.MetadataOnly).To(BeTrue())
+			Expect(*req.Count).To(Equal(1))
+
+			_ = json.NewEncoder(w).Encode(FolderResponse{
+				defaultResponse: defaultResponse{Success: true},
+				Folder:          folders[req.Folder],
+			})
+		}))
+		defer server.Close()
+
+		for folder, expected := range map[string]bool{"/empty": false, "/files": true, "/folders": true, "/listed": true} {
+			hasChildren, err := c.HasChildren(context.Background(), folder)
+
+			Expect(err).To(BeNil())
+			Expect(hasChildren).To(Equal(expected), folder)
+		}
+	})
+	It("Should send a ranged patch", func() {
+		var contentRange string
+		var body []byte
+
+		c, server := newTestClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			Expect(r.URL.Path).To(Equal(strings.Replace(testPath(apiPatchFile), "{fileId}", "1", 1)))
+
+			contentRange = r.Header.Get("Content-Range")
+			body, _ = io.ReadAll(r.Body)
+
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer server.Close()
+
+		Expect(c.PatchFile(context.Background(), "1", strings.NewReader("patched data"), 100, 7)).To(Succeed())
+		Expect(contentRange).To(Equal("bytes 100-106/*"))
+		Expect(string(body)).To(Equal("patched"))
+	})
+	It("Should return ErrNotSupported when patching isn't available", func() {
+		c, server := newTestClient(http.NotFoundHandler())
+		defer server.Close()
+
+		err := c.PatchFile(context.Background(), "1", strings.NewReader("data"), 0, 4)
+
+		Expect(errors.Is(err, ErrNotSupported)).To(BeTrue())
+	})
+	It("Should fetch folder metadata without files", func() {
+		var req map[string]any
+
+		c, server := newTestClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewDecoder(r.Body).Decode(&req)
+
+			// Respond as a backend which ignores metadataOnly
+			_ = json.NewEncoder(w).Encode(FolderResponse{
+				defaultResponse: defaultResponse{Success: true},
+				Folder:          testTree(),
+			})
+		}))
+		defer server.Close()
+
+		folder, err := c.GetFolderMeta(context.Background(), "/")
+
+		Expect(err).To(BeNil())
+		Expect(req).To(HaveKeyWithValue("metadataOnly", true))
+		Expect(req).To(HaveKeyWithValue("count", BeNumerically("==", 1)))
+		Expect(folder.Files).To(BeNil())
+		Expect(folder.Subfolders).To(HaveLen(1))
+	})
+	It("Should send the final chunk last when uploading concurrently", func() {
+		upload := &testUploadServer{}
+
+		var mu sync.Mutex
+		inFlight, maxInFlight := 0, 0
+
+		c, server := newTestClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			inFlight++
+			maxInFlight = max(maxInFlight, inFlight)
+			mu.Unlock()
+
+			// Hold earlier chunks so a prematurely sent final chunk would be recorded first
+			if r.FormValue("resumableChunkNumber") != r.FormValue("resumableTotalChunks") {
+				time.Sleep(20 * time.Millisecond)
+			}
+
+			upload.ServeHTTP(w, r)
+
+			mu.Lock()
+			inFlight--
+			mu.Unlock()
+		}))
+		defer server.Close()
+
+		c.chunkSize = 4
+
+		data := []byte("0123456789abcdefghij")
+
+		file, err := c.ChunkedUpload(context.Background(), bytes.NewReader(data), "/file.txt", int64(len(data)),
+			WithChunkConcurrency(3), WithFinalChunkLast())
+
+		Expect(err).To(BeNil())
+		Expect(file.Size).To(Equal(int64(len(data))))
+		Expect(upload.Chunks).To(HaveLen(5))
+		Expect(upload.Chunks[4].Fields["resumableChunkNumber"]).To(Equal("5"))
+		Expect(maxInFlight).To(BeNumerically(">", 1))
+
+		chunks := make([]string, 5)
+
+		for _, chunk := range upload.Chunks {
+			n, _ := strconv.Atoi(chunk.Fields["resumableChunkNumber"])
+			chunks[n-1] = string(chunk.Data)
+		}
+
+		Expect(strings.Join(chunks, "")).To(Equal(string(data)))
+	})
+	It("Should edit multiple files, reporting per-file failures", func() {
+		var mu sync.Mutex
+		edited := make(map[string]bool)
+		published := true
+
+		c, server := newTestClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/v1/filestorage/"), "/")[0]
+
+			if id == "bad" {
+				_ = json.NewEncoder(w).Encode(defaultResponse{Success: false, Message: "Permission denied"})
+				return
+			}
+
+			mu.Lock()
+			edited[id] = true
+			mu.Unlock()
+
+			_ = json.NewEncoder(w).Encode(defaultResponse{Success: true})
+		}))
+		defer server.Close()
+
+		failed, err := c.EditFiles(context.Background(), []string{"1", "2", "3"}, EditFilePatch{Published: &published})
+
+		Expect(err).To(BeNil())
+		Expect(failed).To(BeEmpty())
+		Expect(edited).To(HaveLen(3))
+
+		failed, err = c.EditFiles(context.Background(), []string{"4", "bad"}, EditFilePatch{Published: &published})
+
+		Expect(errors.Is(err, ErrPartialFailure)).To(BeTrue())
+		Expect(failed).To(HaveLen(1))
+		Expect(failed).To(HaveKey("bad"))
+		Expect(edited).To(HaveKey("4"))
+	})
+	It("Should only send the set fields when editing multiple files", func() {
+		var mu sync.Mutex
+		var sent []map[string]any
+
+		c, server := newTestClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var body map[string]any
+
+			_ = json.NewDecoder(r.Body).Decode(&body)
+
+			mu.Lock()
+			sent = append(sent, body)
+			mu.Unlock()
+
+			_ = json.NewEncoder(w).Encode(defaultResponse{Success: true})
+		}))
+		defer server.Close()
+
+		published := true
+
+		_, err := c.EditFiles(context.Background(), []string{"1", "2"}, EditFilePatch{Published: &published})
+
+		Expect(err).To(BeNil())
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		Expect(sent).To(HaveLen(2))
+
+		for _, body := range sent {
+			Expect(body).To(Equal(map[string]any{"published": true}))
+		}
+	})
+	It("Should publish with a download limit and report the remaining count", func() {
+		var sent map[string]any
+
+		mux := http.NewServeMux()
+
+		mux.HandleFunc(strings.Replace(testPath(apiEditFile), "{fileId}", "1", 1), func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewDecoder(r.Body).Decode(&sent)
+			_ = json.NewEncoder(w).Encode(defaultResponse{Success: true})
+		})
+
+		mux.HandleFunc(strings.Replace(testPath(apiGetFileLink), "{fileId}", "1", 1), func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"success":true,"shortLink":"s","publicLink":"p","isPublic":true,"remainingDownloads":3}`))
+		})
+
+		c, server := newTestClient(mux)
+		defer server.Close()
+
+		Expect(c.EditFile(context.Background(), "1", EditFileParams{Published: true, MaxDownloads: 5})).To(Succeed())
+		Expect(sent).To(HaveKeyWithValue("maxDownloads", BeNumerically("==", 5)))
+
+		status, err := c.GetLinkStatus(context.Background(), "1")
+
+		Expect(err).To(BeNil())
+		Expect(status.IsPublic).To(BeTrue())
+		Expect(status.RemainingDownloads).ToNot(BeNil())
+		Expect(*status.RemainingDownloads).To(Equal(3))
+
+		err = c.EditFile(context.Background(), "1", EditFileParams{Published: true, MaxDownloads: -1})
+
+		Expect(errors.Is(err, ErrInvalidMaxDownloads)).To(BeTrue())
+
+		// Zero is unlimited, so it's left out of the request
+		sent = nil
+
+		Expect(c.EditFile(context.Background(), "1", EditFileParams{Published: true})).To(Succeed())
+		Expect(sent).ToNot(HaveKey("maxDownloads"))
+
+		// A patch leaves the limit alone by omitting it, so an explicit zero is rejected
+		zero := 0
+
+		Expect(EditFilePatch{MaxDownloads: &zero}.Validate()).To(MatchError(ErrInvalidMaxDownloads))
+	})
+	It("Should send the file version with EditFileIfMatch", func() {
+		var ifMatch string
+
+		c, server := newTestClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ifMatch = r.Header.Get("If-Match")
+
+			_ = json.NewEncoder(w).Encode(defaultResponse{Success: true})
+		}))
+		defer server.Close()
+
+		Expect(c.EditFileIfMatch(context.Background(), "1", "3", EditFileParams{Published: true})).To(Succeed())
+		Expect(ifMatch).To(Equal(`"3"`))
+
+		err := c.EditFileIfMatch(context.Background(), "1", "", EditFileParams{Published: true})
+
+		Expect(errors.Is(err, ErrNotSupported)).To(BeTrue())
+	})
+	It("Should return ErrConflict when the file version has changed", func() {
+		c, server := newTestClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusPreconditionFailed)
+		}))
+		defer server.Close()
+
+		err := c.EditFileIfMatch(context.Background(), "1", "3", EditFileParams{Published: true})
+
+		Expect(errors.Is(err, ErrConflict)).To(BeTrue())
+	})
+	It("Should look up the file when the combine response is empty", func() {
+		upload := &testUploadServer{}
+
+		mux := http.NewServeMux()
+
+		mux.HandleFunc("/"+apiUpload, func(w http.ResponseWriter, r *http.Request) {
+			// Record the chunk, but discard the response
+			upload.ServeHTTP(httptest.NewRecorder(), r)
+		})
+
+		mux.HandleFunc(testPath(apiFolder), func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewEncoder(w).Encode(FolderResponse{
+				defaultResponse: defaultResponse{Success: true},
+				Folder: Folder{
+					Name:  "docs",
+					Path:  "/docs",
+					Files: []File{{ID: "combined", Name: "hello.txt", Size: 5}},
+				},
+			})
+		})
+
+		c, server := newTestClient(mux)
+		defer server.Close()
+
+		file, err := c.ChunkedUpload(context.Background(), bytes.NewReader([]byte("hello")), "/docs/hello.txt", 5)
+
+		Expect(err).To(BeNil())
+		Expect(file.ID).To(Equal("combined"))
+		Expect(upload.Chunks).To(HaveLen(1))
+	})
+	It("Should use the file from a JSON combine response", func() {
+		upload := &testUploadServer{}
+
+		c, server := newTestClient(upload, WithIDGenerator(func() (string, error) {
+			return "from-body", nil
+		}))
+		defer server.Close()
+
+		file, err := c.ChunkedUpload(context.Background(), bytes.NewReader([]byte("hello")), "/docs/hello.txt", 5)
+
+		Expect(err).To(BeNil())
+		Expect(file.ID).To(Equal("from-body"))
+	})
+	It("Should return moved files from the move response", func() {
+		mux := http.NewServeMux()
+
+		mux.HandleFunc(testPath(apiMoveFiles), func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"success":true,"files":[{"id":"1","fileName":"a.txt","folderPath":"/dest"}]}`))
+		})
+
+		mux.HandleFunc(testPath(apiFiles), func(w http.ResponseWriter, r *http.Request) {
+			Fail("files should not be re-fetched")
+		})
+
+		c, server := newTestClient(mux)
+		defer server.Close()
+
+		files, err := c.MoveFilesResult(context.Background(), "/dest", "1")
+
+		Expect(err).To(BeNil())
+		Expect(files).To(HaveLen(1))
+		Expect(files[0].FolderPath).To(Equal("/dest"))
+	})
+	It("Should re-fetch moved files when the move response has none", func() {
+		mux := http.NewServeMux()
+
+		mux.HandleFunc(testPath(apiMoveFiles), func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"success":true}`))
+		})
+
+		mux.HandleFunc(testPath(apiFiles), func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"files":[{"id":"1","fileName":"a.txt","folderPath":"/dest"}]}`))
+		})
+
+		c, server := newTestClient(mux)
+		defer server.Close()
+
+		files, err := c.MoveFilesResult(context.Background(), "/dest", "1")
+
+		Expect(err).To(BeNil())
+		Expect(files).To(HaveLen(1))
+		Expect(files[0].FolderPath).To(Equal("/dest"))
+	})
+	It("Should find files in the folder tree when the files endpoint isn't supported", func() {
+		var filesRequests, treeRequests, folderRequests int
+
+		mux := http.NewServeMux()
+
+		mux.HandleFunc(testPath(apiFiles), func(w http.ResponseWriter, r *http.Request) {
+			filesRequests++
+			http.NotFound(w, r)
+		})
+
+		mux.HandleFunc(testPath(apiFolders), func(w http.ResponseWriter, r *http.Request) {
+			treeRequests++
+
+			_ = json.NewEncoder(w).Encode(FolderResponse{
+				defaultResponse: defaultResponse{Success: true},
+				Folder:          testTree(),
+			})
+		})
+
+		mux.HandleFunc(testPath(apiFolder), func(w http.ResponseWriter, r *http.Request) {
+			folderRequests++
+
+			var req folderRequest
+
+			_ = json.NewDecoder(r.Body).Decode(&req)
+
+			Expect(req.Folder).To(Equal("/a"))
+
+			_ = json.NewEncoder(w).Encode(FolderResponse{
+				defaultResponse: defaultResponse{Success: true},
+				Folder:          testTree().Subfolders[0],
+			})
+		})
+
+		c, server := newTestClient(mux)
+		defer server.Close()
+
+		files, err := c.GetFiles(context.Background(), "3", "missing", "1")
+
+		Expect(err).To(BeNil())
+		Expect(files).To(HaveLen(2))
+		Expect(files[0].Name).To(Equal("b.txt"))
+		Expect(files[1].Name).To(Equal("root.txt"))
+
+		// The unsupported endpoint and each file's folder are remembered, so later calls fetch only that folder
+		files, err = c.GetFilesWithFields(context.Background(), []string{FileFieldFolderPath}, "2")
+
+		Expect(err).To(BeNil())
+		Expect(files).To(HaveLen(1))
+		Expect(files[0].FolderPath).To(Equal("/a"))
+		Expect(files[0].Size).To(BeZero())
+		Expect(filesRequests).To(Equal(1))
+		Expect(treeRequests).To(Equal(1))
+		Expect(folderRequests).To(Equal(1))
+	})
+	Context("Creating subfolders", func() {
+		var sent folderRequest
+
+		putFolderServer := func() http.Handler {
+			sent = folderRequest{}
+
+			mux := http.NewServeMux()
+
+			mux.HandleFunc(testPath(apiPutFolder), func(w http.ResponseWriter, r *http.Request) {
+				_ = json.NewDecoder(r.Body).Decode(&sent)
+
+				_ = json.NewEncoder(w).Encode(FolderResponse{
+					defaultResponse: defaultResponse{Success: true},
+					Folder:          Folder{Name: sent.Folder, Path: strings.TrimSuffix(sent.ParentFolder, "/") + "/" + sent.Folder},
+				})
+			})
+
+			return mux
+		}
+
+		It("Should send the parent and name separately", func() {
+			c, server := newTestClient(putFolderServer())
+			defer server.Close()
+
+			folder, err := c.CreateSubfolder(context.Background(), "a/b/", "reports")
+
+			Expect(err).To(BeNil())
+			Expect(sent.ParentFolder).To(Equal("/a/b"))
+			Expect(sent.Folder).To(Equal("reports"))
+			Expect(folder.Path).To(Equal("/a/b/reports"))
+		})
+		It("Should preserve names which ParsePath would split", func() {
+			c, server := newTestClient(putFolderServer())
+			defer server.Close()
+
+			// ParsePath treats backslashes as separators
+			parent, name := c.ParsePath("/a/back\\slash")
+
+			Expect(parent).To(Equal("/a/back"))
+			Expect(name).To(Equal("slash"))
+
+			_, err := c.CreateSubfolder(context.Background(), "/a", "back\\slash")
+
+			Expect(err).To(BeNil())
+			Expect(sent.ParentFolder).To(Equal("/a"))
+			Expect(sent.Folder).To(Equal("back\\slash"))
+		})
+		It("Should reject invalid names without a request", func() {
+			c, server := newTestClient(putFolderServer())
+			defer server.Close()
+
+			for _, name := range []string{"", ".", "..", "a/b", "/", "nul\x00"} {
+				_, err := c.CreateSubfolder(context.Background(), "/", name)
+
+				Expect(err).To(MatchError(ErrInvalidFolderName), name)
+			}
+
+			Expect(sent.Folder).To(BeEmpty())
+		})
+	})
+	Context("Root folder operations", func() {
+		It("Should reject deleting, moving or renaming the root without a request", func() {
+			var requests int
+
+			c, server := newTestClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+
+				_, _ = w.Write([]byte(`{"success":true}`))
+			}))
+			defer server.Close()
+
+			for _, root := range []string{"/", "", "//", "\\", "/a/.."} {
+				Expect(c.DeleteFolder(context.Background(), root)).To(MatchError(ErrRootOperation), root)
+				Expect(c.MoveFolder(context.Background(), root, "/b", "")).To(MatchError(ErrRootOperation), root)
+				Expect(c.MoveFolder(context.Background(), root, "/", "renamed")).To(MatchError(ErrRootOperation), root)
+			}
+
+			Expect(requests).To(BeZero())
+
+			// Moving a folder into the root is still allowed
+			Expect(c.MoveFolder(context.Background(), "/a/b", "/", "b")).To(Succeed())
+			Expect(requests).To(Equal(1))
+		})
+	})
+
+	Context("Moving folders", func() {
+		It("Should reject moving a folder into itself or a subfolder without a request", func() {
+			var requests int
+
+			c, server := newTestClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+
+				_, _ = w.Write([]byte(`{"success":true}`))
+			}))
+			defer server.Close()
+
+			for _, target := range []string{"/a", "/a/", "/a/b", "/a/b/c", "a/b"} {
+				err := c.MoveFolder(context.Background(), "/a", target, "moved")
+
+				Expect(err).To(MatchError(ErrInvalidMove), target)
+			}
+
+			Expect(requests).To(BeZero())
+
+			// Similarly named siblings and renames in place are fine
+			Expect(c.MoveFolder(context.Background(), "/a", "/ab", "a")).To(Succeed())
+			Expect(c.MoveFolder(context.Background(), "/a", "", "renamed")).To(Succeed())
+			Expect(requests).To(Equal(2))
+		})
+	})
+
+	Context("Deleting files", func() {
+		deleteServer := func(body string, sent *filesRequest) http.Handler {
+			mux := http.NewServeMux()
+
+			mux.HandleFunc(testPath(apiDeleteFiles), func(w http.ResponseWriter, r *http.Request) {
+				_ = json.NewDecoder(r.Body).Decode(sent)
+
+				_, _ = w.Write([]byte(body))
+			})
+
+			return mux
+		}
+
+		It("Should delete the files", func() {
+			var sent filesRequest
+
+			c, server := newTestClient(deleteServer(`{"success":true}`, &sent))
+			defer server.Close()
+
+			Expect(c.DeleteFiles(context.Background(), "1", "2")).To(Succeed())
+			Expect(sent.FileIDs).To(Equal([]string{"1", "2"}))
+		})
+		It("Should report a logical failure with the server's message", func() {
+			var sent filesRequest
+
+			c, server := newTestClient(deleteServer(`{"success":false,"message":"You do not have permission to delete this file"}`, &sent))
+			defer server.Close()
+
+			err := c.DeleteFiles(context.Background(), "1")
+
+			Expect(err).To(MatchError(ErrPermissionDenied))
+			Expect(err.Error()).To(ContainSubstring("You do not have permission to delete this file"))
+		})
+	})
+})
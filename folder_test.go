@@ -0,0 +1,79 @@
+package hoist
+
+import (
+	"path"
+	"strconv"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// deepTree creates a chain of folders /0/1/2/... with a file in each
+func deepTree(levels int) Folder {
+	root := Folder{Path: "/", Files: []File{{ID: "root", Name: "root.txt"}}}
+
+	current := &root
+
+	for i := 0; i < levels; i++ {
+		name := strconv.Itoa(i)
+
+		current.Subfolders = []Folder{{
+			Name:  name,
+			Path:  path.Join(current.Path, name),
+			Files: []File{{ID: name, Name: name + ".txt"}},
+		}}
+
+		current = &current.Subfolders[0]
+	}
+
+	return root
+}
+
+var _ = Describe("Folder tests", func() {
+	It("Should flatten the whole tree by default", func() {
+		Expect(deepTree(5).Flatten()).To(HaveLen(6))
+	})
+	It("Should only return the folder itself at depth 0", func() {
+		folders := deepTree(5).Flatten(WithMaxDepth(0))
+
+		Expect(folders).To(HaveLen(1))
+		Expect(folders[0].Path).To(Equal("/"))
+	})
+	It("Should stop descending at the max depth", func() {
+		Expect(deepTree(5).Flatten(WithMaxDepth(2))).To(HaveLen(3))
+		Expect(deepTree(5).AllFiles(WithMaxDepth(1))).To(HaveLen(2))
+	})
+	It("Should report depth while walking", func() {
+		var depths []int
+
+		err := deepTree(5).WalkFolders(func(folder Folder, depth int) error {
+			depths = append(depths, depth)
+			return nil
+		}, WithMaxDepth(3))
+
+		Expect(err).To(BeNil())
+		Expect(depths).To(Equal([]int{0, 1, 2, 3}))
+	})
+	It("Should sort flattened folders by path", func() {
+		tree := Folder{
+			Path: "/",
+			Subfolders: []Folder{
+				{Name: "b", Path: "/b", Subfolders: []Folder{{Name: "z", Path: "/b/z"}, {Name: "c", Path: "/b/c"}}},
+				{Name: "a", Path: "/a", Subfolders: []Folder{{Name: "d", Path: "/a/d", Subfolders: []Folder{{Name: "e", Path: "/a/d/e"}}}}},
+			},
+		}
+
+		var flattened, sorted []string
+
+		for _, folder := range tree.Flatten() {
+			flattened = append(flattened, folder.Path)
+		}
+
+		for _, folder := range tree.FlattenSorted() {
+			sorted = append(sorted, folder.Path)
+		}
+
+		Expect(flattened).To(Equal([]string{"/", "/b", "/b/z", "/b/c", "/a", "/a/d", "/a/d/e"}))
+		Expect(sorted).To(Equal([]string{"/", "/a", "/a/d", "/a/d/e", "/b", "/b/c", "/b/z"}))
+	})
+})
@@ -0,0 +1,153 @@
+package fs
+
+import (
+	"context"
+	"path"
+	"time"
+
+	"github.com/namecrane/hoist"
+	log "github.com/sirupsen/logrus"
+)
+
+// defaultCommitTimeout bounds each auto-commit upload unless changed with WithAutoCommitTimeout
+const defaultCommitTimeout = 10 * time.Minute
+
+// WithAutoCommit periodically uploads files which are still being written, so long-lived writers don't lose
+// everything buffered in the temporary file if the process dies before Close.
+// A commit happens every interval and whenever threshold bytes have been written since the last commit;
+// either can be zero to disable it.
+//
+// Each commit uploads the whole file written so far to the same path, as the backend has no append support.
+// Once a commit succeeds, the file uploaded by the previous commit is deleted if the backend stored it separately,
+// so at most one partial copy exists remotely. Readers may observe a partial file between commits, and the final
+// upload still happens on Close.
+//
+// Writes never fail because of a commit: the data is kept, and the failure is logged and returned by the next Sync.
+// Close uploads everything again, so its result supersedes earlier failures. Each commit holds the file's lock, so
+// it's bounded by WithAutoCommitTimeout to keep a stalled upload from blocking the handle indefinitely.
+func WithAutoCommit(interval time.Duration, threshold int64) Option {
+	return func(f *FileSystem) {
+		f.commitInterval = interval
+		f.commitThreshold = threshold
+	}
+}
+
+// WithAutoCommitTimeout sets how long each auto-commit upload may take, defaulting to 10 minutes
+func WithAutoCommitTimeout(timeout time.Duration) Option {
+	return func(f *FileSystem) {
+		f.commitTimeout = timeout
+	}
+}
+
+// startAutoCommit starts the commit timer if configured. It must be called with the file locked.
+func (c *CraneFile) startAutoCommit() {
+	if c.fs.commitInterval <= 0 || c.stopCommit != nil {
+		return
+	}
+
+	c.stopCommit = make(chan struct{})
+
+	go func(stop chan struct{}) {
+		ticker := time.NewTicker(c.fs.commitInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				c.mu.Lock()
+
+				if c.dirty {
+					if err := c.commit(); err != nil {
+						c.commitFailed(err)
+					}
+				}
+
+				c.mu.Unlock()
+			}
+		}
+	}(c.stopCommit)
+}
+
+// stopAutoCommit stops the commit timer, if running. It must be called with the file locked.
+func (c *CraneFile) stopAutoCommit() {
+	if c.stopCommit != nil {
+		close(c.stopCommit)
+		c.stopCommit = nil
+	}
+}
+
+// wrote records written bytes, committing if the threshold has been reached. The bytes have already been accepted,
+// so a failed commit is kept for Sync rather than returned. It must be called with the file locked.
+func (c *CraneFile) wrote(n int) error {
+	c.pending += int64(n)
+	c.dirty = true
+
+	if c.fs.commitThreshold > 0 && c.pending >= c.fs.commitThreshold {
+		if err := c.commit(); err != nil {
+			c.commitFailed(err)
+		}
+	}
+
+	return nil
+}
+
+// commitFailed logs a failed commit and keeps it for the next Sync. The pending count is reset so the next attempt
+// waits for another threshold of writes instead of retrying on every write. It must be called with the file locked.
+func (c *CraneFile) commitFailed(err error) {
+	log.WithError(err).WithField("file", path.Join(c.path, c.name)).Warning("Failed to auto-commit file")
+
+	c.commitErr = err
+	c.pending = 0
+}
+
+// commit uploads the temporary file's current contents, replacing the previous commit.
+// It must be called with the file locked.
+func (c *CraneFile) commit() error {
+	f, size, err := c.contents()
+
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	timeout := c.fs.commitTimeout
+
+	if timeout <= 0 {
+		timeout = defaultCommitTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	file, err := c.fs.client.ChunkedUpload(ctx, f, path.Join(c.path, c.name), size)
+
+	if err != nil {
+		return err
+	}
+
+	c.replaceCommitted(file)
+
+	c.pending = 0
+	c.dirty = false
+
+	return nil
+}
+
+// replaceCommitted sets the uploaded file, removing the previous commit if the backend kept it as a separate file
+func (c *CraneFile) replaceCommitted(file *hoist.File) {
+	previous := c.committed
+
+	c.file = file
+	c.committed = file
+
+	if previous == nil || previous.ID == file.ID {
+		return
+	}
+
+	if err := c.fs.client.DeleteFiles(context.Background(), previous.ID); err != nil {
+		log.WithError(err).WithField("id", previous.ID).Warning("Failed to remove previous auto-commit")
+	}
+}
@@ -3,6 +3,7 @@ package fs
 import (
 	"context"
 	"errors"
+	"fmt"
 	"github.com/google/uuid"
 	"github.com/namecrane/hoist"
 	log "github.com/sirupsen/logrus"
@@ -12,6 +13,7 @@ import (
 	"io/fs"
 	"os"
 	"path"
+	"sync"
 	"time"
 )
 
@@ -22,7 +24,11 @@ type ReaderAtSeeker interface {
 	io.Seeker
 }
 
+// CraneFile is an afero.File backed by a Hoist file or folder.
+// Reads, writes and closes are serialized, so a single CraneFile can be shared between goroutines,
+// though sequential Read/Write calls from multiple goroutines will interleave unpredictably.
 type CraneFile struct {
+	mu            sync.Mutex
 	fs            *FileSystem
 	mode          int
 	path          string
@@ -33,12 +39,32 @@ type CraneFile struct {
 	temporaryFile afero.File
 	readStream    io.ReadCloser
 	readAtStream  fscache.ReadAtCloser
+	buffer        *writeBuffer
+	writeOffset   int64
+	patches       []patch
+	patchBytes    int64
+
+	// Auto-commit state, see WithAutoCommit
+	pending    int64
+	dirty      bool
+	committed  *hoist.File
+	commitErr  error
+	stopCommit chan struct{}
 }
 
 func (c *CraneFile) Open(mode int) error {
-	// When creating a new file, explicitly open a temp file
+	// Writes to existing files are sent as patches on Close, see patching
+	if c.patching() {
+		if mode&os.O_APPEND != 0 {
+			c.writeOffset = c.file.Size
+		}
+
+		return nil
+	}
+
+	// When creating a new file, explicitly open it for writing
 	if mode&os.O_CREATE != 0 {
-		return c.openTempFile()
+		return c.openWriter()
 	}
 	return nil
 }
@@ -51,9 +77,19 @@ func (c *CraneFile) ID() string {
 	return ""
 }
 
+// ReadAt reads from the read cache (see WithReadCache). Without one, backends reporting hoist.Capabilities.Ranges
+// are read with a ranged download per call, while others fail with ErrNotSupported.
 func (c *CraneFile) ReadAt(p []byte, off int64) (n int, err error) {
+	if err := c.readable("readat"); err != nil {
+		return 0, err
+	}
+
+	if off >= c.file.Size {
+		return 0, io.EOF
+	}
+
 	if c.fs.readCache == nil {
-		return -1, ErrNotSupported
+		return c.readRange(p, off)
 	}
 
 	log.WithFields(log.Fields{
@@ -62,29 +98,92 @@ func (c *CraneFile) ReadAt(p []byte, off int64) (n int, err error) {
 		"offset": off,
 	}).Debug("Reading file bytes")
 
+	// Only the lazy stream initialization needs locking, the cached stream supports concurrent ReadAt calls
+	c.mu.Lock()
+
 	if c.readAtStream == nil {
 		log.WithField("path", c.path).Debug("Opening cache read file")
 		if err := c.openReadAtStream(); err != nil {
-			return -1, err
+			c.mu.Unlock()
+			return 0, err
 		}
 	}
 
-	if off >= c.file.Size {
-		return 0, io.EOF
+	stream := c.readAtStream
+
+	c.mu.Unlock()
+
+	return stream.ReadAt(p, off)
+}
+
+// readRange reads p at off with a ranged download, for backends which honour Range headers
+func (c *CraneFile) readRange(p []byte, off int64) (int, error) {
+	ctx := context.Background()
+
+	caps, err := c.fs.client.Capabilities(ctx)
+
+	if err != nil {
+		return 0, err
+	} else if !caps.Ranges {
+		return 0, ErrNotSupported
 	}
 
-	return c.readAtStream.ReadAt(p, off)
+	end := min(off+int64(len(p)), c.file.Size)
+
+	body, err := c.fs.client.DownloadFile(ctx, c.file.ID,
+		hoist.WithHeader("Range", fmt.Sprintf("bytes=%d-%d", off, end-1)))
+
+	if err != nil {
+		return 0, err
+	}
+
+	defer body.Close()
+
+	n, err := io.ReadFull(body, p[:end-off])
+
+	if err == nil && n < len(p) {
+		err = io.EOF
+	}
+
+	return n, err
 }
 
+// WriteAt writes p at off without moving the offset used by Write, matching os.File.
+// Writing beyond the current end leaves a gap which is zero filled, whether the data is buffered in memory or in the
+// temporary filesystem (sparse files read back as zeros), so the uploaded size is the end of the furthest write.
 func (c *CraneFile) WriteAt(p []byte, off int64) (n int, err error) {
-	if c.temporaryFile == nil {
-		// Create file to write to
-		if err := c.openTempFile(); err != nil {
-			return -1, err
+	if off < 0 {
+		return 0, &fs.PathError{Op: "writeat", Path: path.Join(c.path, c.name), Err: os.ErrInvalid}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Patches beyond the patch limit load the file, after which p is written like any other write
+	if c.patching() {
+		if patched, err := c.addPatch(p, off); err != nil {
+			return 0, err
+		} else if patched {
+			return len(p), nil
 		}
 	}
 
-	return c.temporaryFile.WriteAt(p, off)
+	// Create file to write to
+	if err := c.ensureWriter(); err != nil {
+		return 0, err
+	}
+
+	if err := c.reserve(off + int64(len(p))); err != nil {
+		return 0, err
+	}
+
+	n, err = c.writer().WriteAt(p, off)
+
+	if err != nil {
+		return n, err
+	}
+
+	return n, c.wrote(n)
 }
 
 func (c *CraneFile) Name() string {
@@ -113,13 +212,51 @@ func (c *CraneFile) Readdirnames(n int) ([]string, error) {
 	return names, nil
 }
 
+// Sync reports the last auto-commit failure since the previous Sync, if any (see WithAutoCommit)
 func (c *CraneFile) Sync() error {
-	return nil
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	err := c.commitErr
+
+	c.commitErr = nil
+
+	return err
 }
 
+// Truncate changes the size of the file being written, zero filling if it grows. Existing files opened for writing
+// are loaded in full first, so their content is kept up to size. Handles not opened for writing return
+// os.ErrPermission, leaving the remote file untouched.
 func (c *CraneFile) Truncate(size int64) error {
-	//TODO implement me
-	panic("implement me")
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.mode&(os.O_WRONLY|os.O_RDWR) == 0 {
+		return &fs.PathError{Op: "truncate", Path: path.Join(c.path, c.name), Err: os.ErrPermission}
+	}
+
+	// Patches can't shrink a file, so load it to be re-uploaded in full
+	if c.patching() {
+		if err := c.loadForWrite(); err != nil {
+			return err
+		}
+	}
+
+	if err := c.ensureWriter(); err != nil {
+		return err
+	}
+
+	if err := c.reserve(size); err != nil {
+		return err
+	}
+
+	if err := c.writer().Truncate(size); err != nil {
+		return err
+	}
+
+	c.dirty = true
+
+	return nil
 }
 
 func (c *CraneFile) WriteString(s string) (ret int, err error) {
@@ -127,8 +264,15 @@ func (c *CraneFile) WriteString(s string) (ret int, err error) {
 }
 
 func (c *CraneFile) Close() error {
-	if c.temporaryFile != nil {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.buffer != nil || c.temporaryFile != nil {
+		c.stopAutoCommit()
+
 		return c.uploadFile()
+	} else if len(c.patches) > 0 {
+		return c.applyPatches()
 	} else if c.readStream != nil {
 		return c.readStream.Close()
 	} else if c.readAtStream != nil {
@@ -139,54 +283,69 @@ func (c *CraneFile) Close() error {
 }
 
 func (c *CraneFile) uploadFile() error {
-	var err error
-
-	if err = c.temporaryFile.Close(); err != nil {
-		return err
-	}
-
-	var f afero.File
-
-	defer func() {
-		if f != nil {
-			_ = f.Close()
-		}
-
+	if c.temporaryFile != nil {
 		// Clean up the file when we're done
-		_ = c.tempFs.Remove(c.temporaryFile.Name())
-	}()
+		defer func() {
+			_ = c.temporaryFile.Close()
+			_ = c.tempFs.Remove(c.temporaryFile.Name())
+		}()
+	}
 
-	f, err = c.tempFs.Open(c.temporaryFile.Name())
+	f, size, err := c.contents()
 
 	if err != nil {
 		return err
 	}
 
-	stat, err := f.Stat()
+	defer f.Close()
 
-	if err != nil {
-		return err
+	// Everything written has already been uploaded by an auto-commit
+	if c.committed != nil && !c.dirty {
+		return nil
 	}
 
-	if stat.Size() == 0 {
+	if size == 0 && !c.fs.allowEmpty {
 		return ErrEmptyFile
 	}
 
-	file, err := c.fs.client.ChunkedUpload(context.Background(), f, path.Join(c.path, c.name), stat.Size())
+	var file *hoist.File
+
+	// Small files fit in a single request, skipping the chunked upload machinery
+	if size <= c.fs.client.ChunkSize() {
+		file, err = c.fs.client.Upload(context.Background(), f, path.Join(c.path, c.name), size)
+	} else {
+		file, err = c.fs.client.ChunkedUpload(context.Background(), f, path.Join(c.path, c.name), size)
+	}
 
 	if err != nil {
+		if size == 0 {
+			return fmt.Errorf("%w: %w", ErrEmptyFile, err)
+		}
+
 		return err
 	}
 
-	c.file = file
+	if c.committed != nil {
+		c.replaceCommitted(file)
+	} else {
+		c.file = file
+	}
 
-	return err
+	// Everything has been uploaded, superseding any failed auto-commit
+	c.commitErr = nil
+	c.pending = 0
+	c.dirty = false
+
+	return nil
 }
 
 func (c *CraneFile) Read(p []byte) (n int, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	// If something tries to read from a file that does not exist, make sure we catch it
-	if c.file == nil {
-		return -1, io.ErrUnexpectedEOF
+	if err := c.readable("read"); err != nil {
+		return 0, err
 	}
 
 	// Support cached reads
@@ -194,20 +353,44 @@ func (c *CraneFile) Read(p []byte) (n int, err error) {
 		return c.readAtStream.Read(p)
 	}
 
+	// Empty files have nothing to download
+	if c.file.Size == 0 && c.readStream == nil {
+		return 0, io.EOF
+	}
+
 	// Open direct read stream
 	if c.readStream == nil {
 		if err := c.openReadStream(); err != nil {
-			return -1, err
+			return 0, err
 		}
 	}
 
+	// The download stream returns io.EOF once the file has been read to completion
 	return c.readStream.Read(p)
 }
 
+// readable returns an error describing why the file can't be read, if it's a folder or doesn't exist remotely
+func (c *CraneFile) readable(op string) error {
+	if c.file != nil {
+		return nil
+	}
+
+	name := path.Join(c.path, c.name)
+
+	if c.folder != nil {
+		return &fs.PathError{Op: op, Path: name, Err: ErrIsDir}
+	}
+
+	return &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+}
+
 func (c *CraneFile) openReadStream() error {
 	stream, err := c.fs.client.DownloadFile(context.Background(), c.file.ID)
 
-	if err != nil {
+	if errors.Is(err, hoist.ErrNoFile) {
+		// The file was removed remotely since it was opened
+		return &fs.PathError{Op: "read", Path: path.Join(c.path, c.name), Err: fs.ErrNotExist}
+	} else if err != nil {
 		return err
 	}
 
@@ -299,25 +482,50 @@ func (c *CraneFile) openTempFile() error {
 		return err
 	}
 
-	tempFile, err := c.tempFs.Create(u.String())
+	tempFile, err := c.tempFs.Create(tempFilePrefix + u.String())
 
 	if err != nil {
 		return err
 	}
 
 	c.temporaryFile = tempFile
+
 	return nil
 }
 
 func (c *CraneFile) Write(p []byte) (n int, err error) {
-	if c.temporaryFile == nil {
-		// Create file to write to
-		if err := c.openTempFile(); err != nil {
-			return -1, err
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.patching() {
+		if patched, err := c.addPatch(p, c.writeOffset); err != nil {
+			return 0, err
+		} else if patched {
+			c.writeOffset += int64(len(p))
+
+			return len(p), nil
 		}
 	}
 
-	return c.temporaryFile.Write(p)
+	// Create file to write to
+	if err := c.ensureWriter(); err != nil {
+		return 0, err
+	}
+
+	if err := c.reserve(c.writeOffset + int64(len(p))); err != nil {
+		return 0, err
+	}
+
+	// Sequential writes track their own offset, as some afero.Fs implementations move the offset on WriteAt
+	n, err = c.writer().WriteAt(p, c.writeOffset)
+
+	c.writeOffset += int64(n)
+
+	if err != nil {
+		return n, err
+	}
+
+	return n, c.wrote(n)
 }
 
 // NewFileInfo creates a new CraneFileInfo struct, used for reading directories/etc
@@ -370,6 +578,15 @@ func (c *CraneFileInfo) IsDir() bool {
 	return c.folder != nil
 }
 
+// Sys returns the underlying *hoist.File, or *hoist.Folder for folders, bridging back to the ID-based API
 func (c *CraneFileInfo) Sys() any {
+	if c.file != nil {
+		return c.file
+	}
+
+	if c.folder != nil {
+		return c.folder
+	}
+
 	return nil
 }
@@ -0,0 +1,530 @@
+package fs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	iofs "io/fs"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/namecrane/hoist/hoisttest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"gopkg.in/djherbis/fscache.v0"
+)
+
+var _ = Describe("CraneFile tests", func() {
+	var client *hoisttest.Client
+	var fs *FileSystem
+
+	BeforeEach(func() {
+		cache, err := fscache.NewCache(fscache.NewMemFs(), nil)
+
+		Expect(err).To(BeNil())
+
+		client = hoisttest.NewClient()
+		fs = New(client, WithReadCache(cache))
+	})
+
+	It("Should support concurrent ReadAt calls", func() {
+		data := bytes.Repeat([]byte("0123456789"), 1000)
+
+		client.AddFile("/file.bin", data)
+
+		f, err := fs.Open("/file.bin")
+
+		Expect(err).To(BeNil())
+
+		var wg sync.WaitGroup
+
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+
+			go func(off int64) {
+				defer GinkgoRecover()
+				defer wg.Done()
+
+				buf := make([]byte, 10)
+
+				n, err := f.ReadAt(buf, off)
+
+				Expect(err).To(BeNil())
+				Expect(buf[:n]).To(Equal(data[off : off+10]))
+			}(int64(i * 100))
+		}
+
+		wg.Wait()
+
+		Expect(f.Close()).To(Succeed())
+	})
+	It("Should read ranges without a read cache when the backend supports them", func() {
+		client.AddFile("/file.txt", []byte("hello world"))
+
+		fs = New(client)
+
+		f, err := fs.Open("/file.txt")
+
+		Expect(err).To(BeNil())
+
+		buf := make([]byte, 5)
+
+		n, err := f.ReadAt(buf, 6)
+
+		Expect(err).To(BeNil())
+		Expect(string(buf[:n])).To(Equal("world"))
+
+		n, err = f.ReadAt(buf, 8)
+
+		Expect(err).To(Equal(io.EOF))
+		Expect(string(buf[:n])).To(Equal("rld"))
+
+		client.Caps.Ranges = false
+
+		_, err = f.ReadAt(buf, 0)
+
+		Expect(err).To(MatchError(ErrNotSupported))
+		Expect(f.Close()).To(Succeed())
+	})
+	It("Should support concurrent writes", func() {
+		f, err := fs.Create("/file.bin")
+
+		Expect(err).To(BeNil())
+
+		var wg sync.WaitGroup
+
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+
+				_, err := f.Write([]byte("0123456789"))
+
+				Expect(err).To(BeNil())
+			}()
+		}
+
+		wg.Wait()
+
+		Expect(f.Close()).To(Succeed())
+
+		Expect(client.Folder("/").Files[0].Size).To(Equal(int64(80)))
+	})
+	It("Should read a file to completion, ending with io.EOF", func() {
+		client.AddFile("/file.txt", []byte("hello world"))
+
+		f, err := fs.Open("/file.txt")
+
+		Expect(err).To(BeNil())
+
+		data, err := io.ReadAll(f)
+
+		Expect(err).To(BeNil())
+		Expect(string(data)).To(Equal("hello world"))
+
+		n, err := f.Read(make([]byte, 10))
+
+		Expect(n).To(Equal(0))
+		Expect(err).To(Equal(io.EOF))
+		Expect(f.Close()).To(Succeed())
+	})
+	It("Should return io.EOF when reading an empty file", func() {
+		client.AddFile("/empty.txt", nil)
+
+		f, err := fs.Open("/empty.txt")
+
+		Expect(err).To(BeNil())
+
+		n, err := f.Read(make([]byte, 10))
+
+		Expect(n).To(Equal(0))
+		Expect(err).To(Equal(io.EOF))
+	})
+	It("Should return fs.ErrNotExist for missing files", func() {
+		_, err := fs.Open("/missing.txt")
+
+		Expect(errors.Is(err, iofs.ErrNotExist)).To(BeTrue())
+
+		file := client.AddFile("/removed.txt", []byte("data"))
+
+		f, err := fs.Open("/removed.txt")
+
+		Expect(err).To(BeNil())
+		Expect(client.DeleteFiles(context.Background(), file.ID)).To(Succeed())
+
+		n, err := f.Read(make([]byte, 10))
+
+		Expect(n).To(Equal(0))
+		Expect(errors.Is(err, iofs.ErrNotExist)).To(BeTrue())
+	})
+	It("Should return ErrIsDir when reading a folder", func() {
+		client.AddFolder("/dir")
+
+		f, err := fs.Open("/dir")
+
+		Expect(err).To(BeNil())
+
+		_, err = f.Read(make([]byte, 10))
+
+		Expect(errors.Is(err, ErrIsDir)).To(BeTrue())
+	})
+	Describe("Auto-commit", func() {
+		remoteData := func(name string) string {
+			folder := client.Folder("/")
+
+			for _, file := range folder.Files {
+				if file.Name == name {
+					data, _ := client.Data(file.ID)
+					return string(data)
+				}
+			}
+
+			return ""
+		}
+
+		It("Should commit once the byte threshold is reached", func() {
+			fs = New(client, WithAutoCommit(0, 10))
+
+			f, err := fs.Create("/stream.log")
+
+			Expect(err).To(BeNil())
+
+			_, err = f.Write([]byte("0123456789"))
+
+			Expect(err).To(BeNil())
+			Expect(remoteData("stream.log")).To(Equal("0123456789"))
+
+			_, err = f.Write([]byte("abcde"))
+
+			Expect(err).To(BeNil())
+			Expect(remoteData("stream.log")).To(Equal("0123456789"))
+			Expect(f.Close()).To(Succeed())
+			Expect(client.Folder("/").Files).To(HaveLen(1))
+			Expect(remoteData("stream.log")).To(Equal("0123456789abcde"))
+		})
+		It("Should commit periodically", func() {
+			fs = New(client, WithAutoCommit(10*time.Millisecond, 0))
+
+			f, err := fs.Create("/stream.log")
+
+			Expect(err).To(BeNil())
+
+			_, err = f.Write([]byte("first"))
+
+			Expect(err).To(BeNil())
+			Eventually(func() string { return remoteData("stream.log") }).Should(Equal("first"))
+
+			_, err = f.Write([]byte(" second"))
+
+			Expect(err).To(BeNil())
+			Eventually(func() string { return remoteData("stream.log") }).Should(Equal("first second"))
+			Expect(f.Close()).To(Succeed())
+			Expect(client.Folder("/").Files).To(HaveLen(1))
+		})
+		It("Should keep writing when a commit fails and report it on Sync", func() {
+			stalling := &stallingUploadClient{Client: client, stall: true}
+
+			fs = New(stalling, WithAutoCommit(0, 10), WithAutoCommitTimeout(20*time.Millisecond))
+
+			f, err := fs.Create("/stream.log")
+
+			Expect(err).To(BeNil())
+
+			n, err := f.Write([]byte("0123456789"))
+
+			Expect(err).To(BeNil())
+			Expect(n).To(Equal(10))
+			Expect(f.Sync()).To(MatchError(context.DeadlineExceeded))
+			Expect(f.Sync()).To(Succeed())
+
+			stalling.stall = false
+
+			_, err = f.Write([]byte("abcde"))
+
+			Expect(err).To(BeNil())
+			Expect(f.Close()).To(Succeed())
+			Expect(client.Folder("/").Files).To(HaveLen(1))
+			Expect(remoteData("stream.log")).To(Equal("0123456789abcde"))
+		})
+	})
+	Describe("Write buffer", func() {
+		BeforeEach(func() {
+			fs = New(client, WithWriteBuffer(100))
+		})
+
+		remoteData := func() []byte {
+			data, _ := client.Data(client.Folder("/").Files[0].ID)
+			return data
+		}
+
+		It("Should upload small files from memory", func() {
+			f, err := fs.Create("/small.txt")
+
+			Expect(err).To(BeNil())
+
+			_, err = f.Write([]byte("hello"))
+
+			Expect(err).To(BeNil())
+
+			_, err = f.WriteAt([]byte("J"), 0)
+
+			Expect(err).To(BeNil())
+			Expect(f.(*CraneFile).temporaryFile).To(BeNil())
+			Expect(f.Close()).To(Succeed())
+			Expect(string(remoteData())).To(Equal("Jello"))
+		})
+		It("Should spill to the temporary filesystem beyond the threshold", func() {
+			f, err := fs.Create("/large.bin")
+
+			Expect(err).To(BeNil())
+
+			expected := make([]byte, 120)
+
+			_, err = f.Write(bytes.Repeat([]byte("a"), 60))
+
+			Expect(err).To(BeNil())
+
+			copy(expected, bytes.Repeat([]byte("a"), 60))
+
+			_, err = f.WriteAt(bytes.Repeat([]byte("b"), 50), 70)
+
+			Expect(err).To(BeNil())
+			Expect(f.(*CraneFile).temporaryFile).ToNot(BeNil())
+			Expect(f.(*CraneFile).buffer).To(BeNil())
+
+			copy(expected[70:], bytes.Repeat([]byte("b"), 50))
+
+			// Sequential writes continue from where they left off before the spill
+			_, err = f.Write([]byte("cc"))
+
+			Expect(err).To(BeNil())
+
+			copy(expected[60:], "cc")
+
+			Expect(f.Truncate(110)).To(Succeed())
+			Expect(f.Close()).To(Succeed())
+			Expect(remoteData()).To(Equal(expected[:110]))
+		})
+	})
+	It("Should not truncate a file opened for reading", func() {
+		file := client.AddFile("/file.txt", []byte("hello world"))
+
+		f, err := fs.Open("/file.txt")
+
+		Expect(err).To(BeNil())
+
+		err = f.Truncate(5)
+
+		Expect(errors.Is(err, os.ErrPermission)).To(BeTrue())
+		Expect(f.Close()).To(Succeed())
+
+		data, ok := client.Data(file.ID)
+
+		Expect(ok).To(BeTrue())
+		Expect(string(data)).To(Equal("hello world"))
+	})
+	It("Should keep existing content when truncating a file opened for writing", func() {
+		client.AddFile("/file.txt", []byte("hello world"))
+
+		f, err := fs.OpenFile("/file.txt", os.O_RDWR, 0)
+
+		Expect(err).To(BeNil())
+		Expect(f.Truncate(5)).To(Succeed())
+		Expect(f.Close()).To(Succeed())
+
+		files := client.Folder("/").Files
+
+		Expect(files).To(HaveLen(1))
+
+		data, _ := client.Data(files[0].ID)
+
+		Expect(string(data)).To(Equal("hello"))
+	})
+	Describe("Patching", func() {
+		It("Should patch the middle of an existing file in place", func() {
+			file := client.AddFile("/file.txt", []byte("hello world"))
+
+			f, err := fs.OpenFile("/file.txt", os.O_RDWR, 0)
+
+			Expect(err).To(BeNil())
+
+			_, err = f.WriteAt([]byte("W"), 6)
+
+			Expect(err).To(BeNil())
+			Expect(f.Close()).To(Succeed())
+
+			data, ok := client.Data(file.ID)
+
+			Expect(ok).To(BeTrue())
+			Expect(string(data)).To(Equal("hello World"))
+		})
+		It("Should re-upload the whole file when patching is unsupported", func() {
+			client.AddFile("/file.txt", []byte("hello world"))
+			client.PatchUnsupported = true
+
+			f, err := fs.OpenFile("/file.txt", os.O_RDWR, 0)
+
+			Expect(err).To(BeNil())
+
+			_, err = f.WriteAt([]byte("W"), 6)
+
+			Expect(err).To(BeNil())
+			Expect(f.Close()).To(Succeed())
+
+			files := client.Folder("/").Files
+
+			Expect(files).To(HaveLen(1))
+
+			data, _ := client.Data(files[0].ID)
+
+			Expect(string(data)).To(Equal("hello World"))
+		})
+		It("Should stop auto-commit after re-uploading an unpatchable file", func() {
+			client.AddFile("/file.txt", []byte("hello world"))
+			client.PatchUnsupported = true
+
+			fs = New(client, WithAutoCommit(10*time.Millisecond, 0))
+
+			f, err := fs.OpenFile("/file.txt", os.O_RDWR, 0)
+
+			Expect(err).To(BeNil())
+
+			_, err = f.WriteAt([]byte("W"), 6)
+
+			Expect(err).To(BeNil())
+			Expect(f.Close()).To(Succeed())
+
+			crane := f.(*CraneFile)
+
+			crane.mu.Lock()
+			defer crane.mu.Unlock()
+
+			Expect(crane.stopCommit).To(BeNil())
+			Expect(crane.dirty).To(BeFalse())
+		})
+		It("Should merge adjacent and overlapping writes into one patch", func() {
+			file := client.AddFile("/file.txt", []byte("hello world"))
+			counting := &countingPatchClient{Client: client}
+
+			fs = New(counting)
+
+			f, err := fs.OpenFile("/file.txt", os.O_RDWR, 0)
+
+			Expect(err).To(BeNil())
+
+			for _, s := range []string{"H", "E", "L"} {
+				_, err = f.Write([]byte(s))
+				Expect(err).To(BeNil())
+			}
+
+			_, err = f.WriteAt([]byte("LLO"), 2)
+			Expect(err).To(BeNil())
+
+			_, err = f.WriteAt([]byte("D"), 10)
+			Expect(err).To(BeNil())
+
+			Expect(f.Close()).To(Succeed())
+			Expect(counting.patches).To(Equal(2))
+
+			data, _ := client.Data(file.ID)
+
+			Expect(string(data)).To(Equal("HELLO worlD"))
+		})
+		It("Should re-upload the whole file once patches exceed the limit", func() {
+			client.AddFile("/file.txt", []byte("hello world"))
+			counting := &countingPatchClient{Client: client}
+
+			fs = New(counting, WithPatchLimit(4))
+
+			f, err := fs.OpenFile("/file.txt", os.O_RDWR, 0)
+
+			Expect(err).To(BeNil())
+
+			_, err = f.WriteAt([]byte("HE"), 0)
+			Expect(err).To(BeNil())
+
+			_, err = f.WriteAt([]byte("WOR"), 6)
+			Expect(err).To(BeNil())
+
+			_, err = f.Write([]byte("--"))
+			Expect(err).To(BeNil())
+
+			Expect(f.Close()).To(Succeed())
+			Expect(counting.patches).To(BeZero())
+
+			files := client.Folder("/").Files
+
+			Expect(files).To(HaveLen(1))
+
+			data, _ := client.Data(files[0].ID)
+
+			Expect(string(data)).To(Equal("--llo WORld"))
+		})
+	})
+	Describe("Non-contiguous writes", func() {
+		writeSparse := func(fs *FileSystem) []byte {
+			f, err := fs.Create("/sparse.bin")
+
+			Expect(err).To(BeNil())
+
+			// Written out of order, leaving gaps at 3-4 and 8-9
+			_, err = f.WriteAt([]byte("ccc"), 10)
+			Expect(err).To(BeNil())
+
+			_, err = f.WriteAt([]byte("bbb"), 5)
+			Expect(err).To(BeNil())
+
+			_, err = f.Write([]byte("aaa"))
+			Expect(err).To(BeNil())
+
+			_, err = f.WriteAt([]byte("x"), -1)
+			Expect(errors.Is(err, os.ErrInvalid)).To(BeTrue())
+
+			Expect(f.Close()).To(Succeed())
+
+			return []byte("aaa\x00\x00bbb\x00\x00ccc")
+		}
+
+		readBack := func(fs *FileSystem) []byte {
+			f, err := fs.Open("/sparse.bin")
+
+			Expect(err).To(BeNil())
+
+			stat, err := f.Stat()
+
+			Expect(err).To(BeNil())
+
+			buf := make([]byte, stat.Size())
+
+			n, err := f.ReadAt(buf, 0)
+
+			Expect(err).To(Or(BeNil(), Equal(io.EOF)))
+			Expect(f.Close()).To(Succeed())
+
+			return buf[:n]
+		}
+
+		It("Should zero fill gaps when writing to the temporary filesystem", func() {
+			expected := writeSparse(fs)
+
+			Expect(client.Folder("/").Files[0].Size).To(Equal(int64(len(expected))))
+			Expect(readBack(fs)).To(Equal(expected))
+		})
+		It("Should zero fill gaps when buffering in memory", func() {
+			cache, err := fscache.NewCache(fscache.NewMemFs(), nil)
+
+			Expect(err).To(BeNil())
+
+			fs = New(client, WithReadCache(cache), WithWriteBuffer(1024))
+
+			expected := writeSparse(fs)
+
+			Expect(client.Folder("/").Files[0].Size).To(Equal(int64(len(expected))))
+			Expect(readBack(fs)).To(Equal(expected))
+		})
+	})
+})
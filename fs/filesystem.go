@@ -3,6 +3,7 @@ package fs
 import (
 	"context"
 	"errors"
+	"fmt"
 	"github.com/namecrane/hoist"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/afero"
@@ -14,7 +15,11 @@ import (
 	"time"
 )
 
-var ErrNotSupported = errors.New("not supported")
+var (
+	ErrNotSupported = hoist.ErrNotSupported
+	ErrNotDir       = hoist.ErrNotDir
+	ErrIsDir        = errors.New("is a directory")
+)
 
 var _ afero.Fs = (*FileSystem)(nil)
 
@@ -34,6 +39,15 @@ func WithReadCache(cache fscache.Cache) Option {
 	}
 }
 
+// WithEmptyFiles allows closing files with nothing written, uploading them as empty files instead of failing with
+// ErrEmptyFile. Some backends can't store empty files and reject the upload, in which case Close returns the
+// backend's error wrapped with ErrEmptyFile so the rejection can still be detected.
+func WithEmptyFiles() Option {
+	return func(f *FileSystem) {
+		f.allowEmpty = true
+	}
+}
+
 func New(c hoist.Client, opts ...Option) *FileSystem {
 	f := &FileSystem{
 		client: c,
@@ -58,13 +72,36 @@ type FileSystem struct {
 
 	// Used for reading files when they request "ReadAt"
 	readCache fscache.Cache
+
+	// Writes are buffered in memory up to this size before using tempFs, see WithWriteBuffer
+	writeBuffer int64
+
+	// Auto-commit settings for files being written, see WithAutoCommit
+	commitInterval  time.Duration
+	commitThreshold int64
+	commitTimeout   time.Duration
+
+	// Pending patches to existing files are kept in memory up to this size, see WithPatchLimit
+	patchLimit int64
+
+	// Rename returns os.ErrExist instead of replacing an existing destination, see WithRenameNoReplace
+	renameNoReplace bool
+
+	// Empty files are uploaded rather than rejected with ErrEmptyFile, see WithEmptyFiles
+	allowEmpty bool
 }
 
-// Create will create a new file (an empty CraneFile)
+// Create will create a new file (an empty CraneFile). Names ending in a slash refer to folders and fail with
+// ErrIsDir, use Mkdir to create those.
 func (c *FileSystem) Create(name string) (afero.File, error) {
+	if err := checkFileName("create", name); err != nil {
+		return nil, err
+	}
+
 	path, sub := c.client.ParsePath(name)
 
 	f := &CraneFile{
+		mode:   os.O_RDWR | os.O_CREATE | os.O_TRUNC,
 		fs:     c,
 		path:   path,
 		name:   sub,
@@ -102,7 +139,17 @@ func (c *FileSystem) Remove(name string) error {
 	return nil
 }
 
+// endpointer is implemented by clients which can report the endpoint they're configured with
+type endpointer interface {
+	Endpoint() string
+}
+
+// Name returns the filesystem name, including the client's endpoint when available to tell multiple mounts apart
 func (c *FileSystem) Name() string {
+	if e, ok := c.client.(endpointer); ok && e.Endpoint() != "" {
+		return "NameCrane Hoist (" + e.Endpoint() + ")"
+	}
+
 	return "NameCrane Hoist"
 }
 
@@ -145,6 +192,10 @@ func (c *FileSystem) Mkdir(name string, perm os.FileMode) error {
 		return err
 	}
 
+	if parentFolder == nil {
+		return &fs.PathError{Op: "mkdir", Path: name, Err: ErrNotDir}
+	}
+
 	log.WithField("parent", parent).WithField("sub", sub).Debug("Create folders")
 
 	subfolder := parentFolder.Subfolder(sub)
@@ -154,6 +205,12 @@ func (c *FileSystem) Mkdir(name string, perm os.FileMode) error {
 		return nil
 	}
 
+	for _, file := range parentFolder.Files {
+		if file.Name == sub {
+			return &fs.PathError{Op: "mkdir", Path: name, Err: fs.ErrExist}
+		}
+	}
+
 	log.WithField("folder", path.Join(parentFolder.Path, sub)).Debug("Creating folder")
 
 	subfolder, err = c.client.CreateFolder(ctx, path.Join(parentFolder.Path, sub))
@@ -167,33 +224,32 @@ func (c *FileSystem) Mkdir(name string, perm os.FileMode) error {
 	return nil
 }
 
+// MkdirAll creates the folder and any missing parents, like os.MkdirAll.
+// Existing folders are left as-is, so it succeeds when the full path already exists.
+// If any segment of the path is a file, a *fs.PathError wrapping ErrNotDir is returned.
 func (c *FileSystem) MkdirAll(path string, perm os.FileMode) error {
 	ctx := context.Background()
+
 	log.WithField("name", path).Debug("MkdirAll")
 
-	folder, _, err := c.client.Find(ctx, path)
+	folder, file, err := c.client.Find(ctx, path)
 
-	if errors.Is(err, hoist.ErrNoFile) {
-		// OK
-	} else if err != nil {
+	switch {
+	case err != nil && !errors.Is(err, hoist.ErrNoFile) && !errors.Is(err, hoist.ErrNoFolder):
 		log.WithError(err).Warning("Failed to call find")
 		return err
-	} else if folder != nil {
+	case folder != nil:
 		return nil
+	case file != nil:
+		return &fs.PathError{Op: "mkdir", Path: path, Err: ErrNotDir}
 	}
 
-	folders, err := c.client.GetFolders(ctx)
-
-	if err != nil {
-		log.WithError(err).Warning("Failed to get root folder")
-		return err
-	}
-
-	parts := strings.Split(path, "/")
-
-	log.WithField("parts", parts).Debug("Create folders")
+	// Only the missing segments are created, walking the tree from the root
+	if _, err := c.client.MkdirAll(ctx, path); err != nil {
+		if errors.Is(err, ErrNotDir) {
+			return &fs.PathError{Op: "mkdir", Path: path, Err: ErrNotDir}
+		}
 
-	if err := c.recursiveMkdir(ctx, parts, folders[0]); err != nil {
 		return err
 	}
 
@@ -202,29 +258,20 @@ func (c *FileSystem) MkdirAll(path string, perm os.FileMode) error {
 	return nil
 }
 
-func (c *FileSystem) recursiveMkdir(ctx context.Context, parts []string, currentFolder hoist.Folder) error {
-	log.Info("Create directory", currentFolder.Name, parts)
-	subfolder := currentFolder.Subfolder(parts[0])
-
-	if subfolder == nil {
-		var err error
-		subfolder, err = c.client.CreateFolder(ctx, path.Join(currentFolder.Path, parts[0]))
-
-		if err != nil {
-			return err
+func (c *FileSystem) OpenFile(name string, flag int, perm os.FileMode) (afero.File, error) {
+	if flag&os.O_CREATE != 0 {
+		if err := checkFileName("open", name); err != nil {
+			return nil, err
 		}
 	}
 
-	if len(parts) > 1 {
-		return c.recursiveMkdir(ctx, parts[1:], *subfolder)
-	}
-
-	return nil
-}
-
-func (c *FileSystem) OpenFile(name string, flag int, perm os.FileMode) (afero.File, error) {
 	folder, file, err := c.client.Find(context.Background(), name)
 
+	// A missing parent folder means the path doesn't exist either, like os.Open
+	if errors.Is(err, hoist.ErrNoFolder) && flag&os.O_CREATE == 0 {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+
 	if err != nil && !errors.Is(err, hoist.ErrNoFile) {
 		return nil, err
 	}
@@ -237,6 +284,8 @@ func (c *FileSystem) OpenFile(name string, flag int, perm os.FileMode) (afero.Fi
 		log.WithFields(fields).Debug("Opening folder")
 	} else if file != nil {
 		log.WithFields(fields).Debug("Opening file")
+	} else if flag&os.O_CREATE == 0 {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
 	}
 
 	p, sub := c.client.ParsePath(name)
@@ -258,43 +307,39 @@ func (c *FileSystem) OpenFile(name string, flag int, perm os.FileMode) (afero.Fi
 	return f, nil
 }
 
+// checkFileName rejects names which can't be created as files: empty names, and names ending in a slash which
+// ParsePath would otherwise silently trim
+func checkFileName(op, name string) error {
+	if strings.HasSuffix(name, "/") || strings.HasSuffix(name, "\\") {
+		return &fs.PathError{Op: op, Path: name, Err: ErrIsDir}
+	}
+
+	if _, sub := hoist.ParsePath(name); sub == "" {
+		return &fs.PathError{Op: op, Path: name, Err: os.ErrInvalid}
+	}
+
+	return nil
+}
+
 func (c *FileSystem) RemoveAll(name string) error {
 	return c.Remove(name)
 }
 
-func (c *FileSystem) Rename(oldName, newName string) error {
-	folder, file, err := c.client.Find(context.Background(), oldName)
+// FileID resolves a path to the underlying Hoist file ID, for use with ID-based calls such as GetLink
+func (c *FileSystem) FileID(name string) (string, error) {
+	folder, file, err := c.client.Find(context.Background(), name)
 
-	if err != nil {
-		return err
+	if errors.Is(err, hoist.ErrNoFile) || errors.Is(err, hoist.ErrNoFolder) {
+		return "", fs.ErrNotExist
+	} else if err != nil {
+		return "", err
 	}
 
-	oldBase, oldFileName := c.client.ParsePath(oldName)
-	base, name := c.client.ParsePath(newName)
-
 	if folder != nil {
-		var newParent string
-
-		if base != oldBase {
-			newParent = base
-		}
-
-		return c.client.MoveFolder(context.Background(), folder.Path, newParent, name)
-	} else if file != nil {
-		if base != oldBase {
-			err = c.client.MoveFiles(context.Background(), base, file.ID)
-
-			if err != nil {
-				return err
-			}
-		}
-
-		if name != oldFileName {
-			return c.client.RenameFile(context.Background(), file.ID, name)
-		}
+		return "", fmt.Errorf("%w: %s", ErrIsDir, name)
 	}
 
-	return nil
+	return file.ID, nil
 }
 
 func (c *FileSystem) Stat(name string) (os.FileInfo, error) {
@@ -0,0 +1,475 @@
+package fs
+
+import (
+	"context"
+	"errors"
+	"io"
+	iofs "io/fs"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/namecrane/hoist"
+	"github.com/namecrane/hoist/hoisttest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/spf13/afero"
+)
+
+// failingDeleteClient is a fake client whose DeleteFiles always fails
+type failingDeleteClient struct {
+	*hoisttest.Client
+}
+
+func (c *failingDeleteClient) DeleteFiles(ctx context.Context, ids ...string) error {
+	return errors.New("delete failed")
+}
+
+// countingUploadClient is a fake client recording which upload method was used
+type countingUploadClient struct {
+	*hoisttest.Client
+	single, chunked int
+}
+
+func (c *countingUploadClient) Upload(ctx context.Context, in io.Reader, filePath string, fileSize int64) (*hoist.File, error) {
+	c.single++
+
+	return c.Client.Upload(ctx, in, filePath, fileSize)
+}
+
+func (c *countingUploadClient) ChunkedUpload(ctx context.Context, in io.Reader, filePath string, fileSize int64, opts ...hoist.UploadOpt) (*hoist.File, error) {
+	c.chunked++
+
+	return c.Client.ChunkedUpload(ctx, in, filePath, fileSize, opts...)
+}
+
+// countingPatchClient is a fake client counting PatchFile requests
+type countingPatchClient struct {
+	*hoisttest.Client
+	patches int
+}
+
+func (c *countingPatchClient) PatchFile(ctx context.Context, fileID string, r io.Reader, offset, length int64) error {
+	c.patches++
+
+	return c.Client.PatchFile(ctx, fileID, r, offset, length)
+}
+
+// stallingUploadClient is a fake client whose chunked uploads hang until their context ends while stall is set
+type stallingUploadClient struct {
+	*hoisttest.Client
+	stall bool
+}
+
+func (c *stallingUploadClient) ChunkedUpload(ctx context.Context, in io.Reader, filePath string, fileSize int64, opts ...hoist.UploadOpt) (*hoist.File, error) {
+	if c.stall {
+		<-ctx.Done()
+
+		return nil, ctx.Err()
+	}
+
+	return c.Client.ChunkedUpload(ctx, in, filePath, fileSize, opts...)
+}
+
+// rejectingUploadClient is a fake client rejecting every upload, like backends which can't store empty files
+type rejectingUploadClient struct {
+	*hoisttest.Client
+}
+
+func (c *rejectingUploadClient) Upload(ctx context.Context, in io.Reader, filePath string, fileSize int64) (*hoist.File, error) {
+	return nil, errors.New("upload rejected")
+}
+
+var _ = Describe("FileSystem tests", func() {
+	var client *hoisttest.Client
+	var fs *FileSystem
+
+	BeforeEach(func() {
+		client = hoisttest.NewClient()
+		fs = New(client)
+	})
+
+	Describe("MkdirAll", func() {
+		// The fake rejects creating existing folders, so success means only missing segments were created
+		DescribeTable("Should create only the missing segments",
+			func(existing []string, name string) {
+				for _, folder := range existing {
+					client.AddFolder(folder)
+				}
+
+				Expect(fs.MkdirAll(name, 0755)).To(Succeed())
+				Expect(client.Folder("/a/b/c")).ToNot(BeNil())
+
+				// Calling it again is a no-op
+				Expect(fs.MkdirAll(name, 0755)).To(Succeed())
+			},
+			Entry("fully existing", []string{"/a/b/c"}, "/a/b/c"),
+			Entry("partially existing", []string{"/a"}, "/a/b/c"),
+			Entry("partially existing with a sibling", []string{"/a/b/other"}, "/a/b/c"),
+			Entry("non-existing", nil, "/a/b/c"),
+			Entry("with a trailing slash", []string{"/a"}, "/a/b/c/"),
+		)
+		It("Should succeed for the root", func() {
+			Expect(fs.MkdirAll("/", 0755)).To(Succeed())
+		})
+		It("Should return ErrNotDir when an intermediate segment is a file", func() {
+			client.AddFile("/a/file", []byte("data"))
+
+			err := fs.MkdirAll("/a/file/b", 0755)
+
+			var pathErr *iofs.PathError
+
+			Expect(errors.As(err, &pathErr)).To(BeTrue())
+			Expect(pathErr.Op).To(Equal("mkdir"))
+			Expect(errors.Is(err, ErrNotDir)).To(BeTrue())
+			Expect(client.Folder("/a/file")).To(BeNil())
+		})
+		It("Should return ErrNotDir when the path itself is a file", func() {
+			client.AddFile("/file", []byte("data"))
+
+			Expect(errors.Is(fs.MkdirAll("/file", 0755), ErrNotDir)).To(BeTrue())
+		})
+	})
+
+	Describe("Mkdir", func() {
+		It("Should create a folder", func() {
+			client.AddFolder("/a")
+
+			Expect(fs.Mkdir("/a/b", 0755)).To(Succeed())
+			Expect(client.Folder("/a/b")).ToNot(BeNil())
+
+			// Existing folders are left as-is
+			Expect(fs.Mkdir("/a/b", 0755)).To(Succeed())
+		})
+		It("Should return fs.ErrExist when a file has the same name", func() {
+			client.AddFile("/a/name", []byte("data"))
+
+			err := fs.Mkdir("/a/name", 0755)
+
+			Expect(errors.Is(err, iofs.ErrExist)).To(BeTrue())
+			Expect(client.Folder("/a/name")).To(BeNil())
+		})
+		It("Should return ErrNotDir when the parent is a file", func() {
+			client.AddFile("/a/file", []byte("data"))
+
+			Expect(errors.Is(fs.Mkdir("/a/file/b", 0755), ErrNotDir)).To(BeTrue())
+		})
+	})
+
+	Describe("FileID", func() {
+		It("Should resolve a path to its file ID", func() {
+			file := client.AddFile("/a/file.txt", []byte("data"))
+
+			id, err := fs.FileID("/a/file.txt")
+
+			Expect(err).To(BeNil())
+			Expect(id).To(Equal(file.ID))
+		})
+		It("Should expose the file through Stat's Sys", func() {
+			file := client.AddFile("/a/file.txt", []byte("data"))
+
+			info, err := fs.Stat("/a/file.txt")
+
+			Expect(err).To(BeNil())
+			Expect(info.Sys()).To(BeAssignableToTypeOf(&hoist.File{}))
+			Expect(info.Sys().(*hoist.File).ID).To(Equal(file.ID))
+
+			info, err = fs.Stat("/a")
+
+			Expect(err).To(BeNil())
+			Expect(info.Sys().(*hoist.Folder).Path).To(Equal("/a"))
+		})
+		It("Should return fs.ErrNotExist for missing paths", func() {
+			_, err := fs.FileID("/a/missing.txt")
+
+			Expect(errors.Is(err, iofs.ErrNotExist)).To(BeTrue())
+		})
+		It("Should return ErrIsDir for folders", func() {
+			client.AddFolder("/a")
+
+			_, err := fs.FileID("/a")
+
+			Expect(errors.Is(err, ErrIsDir)).To(BeTrue())
+		})
+	})
+
+	Describe("Create", func() {
+		It("Should create a file for a normal name", func() {
+			client.AddFolder("/a")
+
+			f, err := fs.Create("/a/file.txt")
+
+			Expect(err).To(BeNil())
+
+			_, err = f.Write([]byte("data"))
+
+			Expect(err).To(BeNil())
+			Expect(f.Close()).To(Succeed())
+
+			_, err = client.GetFileByPath(context.Background(), "/a/file.txt")
+
+			Expect(err).To(BeNil())
+		})
+		It("Should reject names with a trailing slash", func() {
+			client.AddFolder("/a")
+
+			for _, name := range []string{"/a/dir/", "/a/dir\\", "/"} {
+				f, err := fs.Create(name)
+
+				Expect(f).To(BeNil())
+				Expect(errors.Is(err, ErrIsDir)).To(BeTrue(), name)
+			}
+
+			_, err := fs.OpenFile("/a/dir/", os.O_CREATE|os.O_WRONLY, 0644)
+
+			Expect(errors.Is(err, ErrIsDir)).To(BeTrue())
+			Expect(client.Folder("/a").Files).To(BeEmpty())
+		})
+		It("Should upload files up to the chunk size in a single request", func() {
+			counting := &countingUploadClient{Client: client}
+			counting.Caps.MaxChunkSize = 8
+			fs = New(counting)
+
+			for name, data := range map[string]string{"/small.txt": "small", "/large.txt": "larger than a chunk"} {
+				f, err := fs.Create(name)
+
+				Expect(err).To(BeNil())
+
+				_, err = f.Write([]byte(data))
+
+				Expect(err).To(BeNil())
+				Expect(f.Close()).To(Succeed())
+			}
+
+			Expect(counting.single).To(Equal(1))
+			Expect(counting.chunked).To(Equal(1))
+
+			file, err := client.GetFileByPath(context.Background(), "/small.txt")
+
+			Expect(err).To(BeNil())
+			Expect(file.Size).To(Equal(int64(5)))
+		})
+		It("Should reject closing an empty file by default", func() {
+			f, err := fs.Create("/empty.txt")
+
+			Expect(err).To(BeNil())
+			Expect(f.Close()).To(MatchError(ErrEmptyFile))
+
+			_, err = client.GetFileByPath(context.Background(), "/empty.txt")
+
+			Expect(err).To(MatchError(hoist.ErrNoFile))
+		})
+		It("Should upload an empty file with WithEmptyFiles", func() {
+			fs = New(client, WithEmptyFiles())
+
+			f, err := fs.Create("/empty.txt")
+
+			Expect(err).To(BeNil())
+			Expect(f.Close()).To(Succeed())
+
+			file, err := client.GetFileByPath(context.Background(), "/empty.txt")
+
+			Expect(err).To(BeNil())
+			Expect(file.Size).To(BeZero())
+		})
+		It("Should wrap a backend rejection of an empty file with ErrEmptyFile", func() {
+			fs = New(&rejectingUploadClient{Client: client}, WithEmptyFiles())
+
+			f, err := fs.Create("/empty.txt")
+
+			Expect(err).To(BeNil())
+
+			err = f.Close()
+
+			Expect(err).To(MatchError(ErrEmptyFile))
+			Expect(err).To(MatchError(ContainSubstring("upload rejected")))
+		})
+		It("Should reject an empty name", func() {
+			_, err := fs.Create("")
+
+			Expect(errors.Is(err, os.ErrInvalid)).To(BeTrue())
+		})
+	})
+
+	Describe("OpenFile", func() {
+		It("Should return fs.ErrNotExist for a missing file opened read-only", func() {
+			client.AddFolder("/a")
+
+			f, err := fs.Open("/a/missing.txt")
+
+			Expect(f).To(BeNil())
+			Expect(errors.Is(err, iofs.ErrNotExist)).To(BeTrue())
+		})
+		It("Should return a *fs.PathError for missing targets", func() {
+			client.AddFolder("/a")
+
+			for _, name := range []string{"/a/missing.txt", "/missing/file.txt"} {
+				_, err := fs.OpenFile(name, os.O_RDONLY, 0)
+
+				var pathErr *iofs.PathError
+
+				Expect(errors.As(err, &pathErr)).To(BeTrue(), name)
+				Expect(pathErr.Op).To(Equal("open"))
+				Expect(pathErr.Path).To(Equal(name))
+				Expect(pathErr.Err).To(Equal(iofs.ErrNotExist))
+			}
+		})
+		It("Should return fs.ErrNotExist when the parent folder is missing", func() {
+			_, err := fs.OpenFile("/missing/file.txt", os.O_RDWR, 0)
+
+			Expect(errors.Is(err, iofs.ErrNotExist)).To(BeTrue())
+		})
+		It("Should create a missing file with O_CREATE", func() {
+			client.AddFolder("/a")
+
+			f, err := fs.OpenFile("/a/new.txt", os.O_CREATE|os.O_WRONLY, 0644)
+
+			Expect(err).To(BeNil())
+
+			_, err = f.Write([]byte("data"))
+
+			Expect(err).To(BeNil())
+			Expect(f.Close()).To(Succeed())
+
+			file, err := client.GetFileByPath(context.Background(), "/a/new.txt")
+
+			Expect(err).To(BeNil())
+			Expect(file.Size).To(Equal(int64(4)))
+		})
+	})
+
+	Describe("Name", func() {
+		It("Should include the client endpoint", func() {
+			Expect(fs.Name()).To(Equal("NameCrane Hoist (memory)"))
+
+			remote := New(hoist.NewClient("https://one.example.com", nil))
+
+			Expect(remote.Name()).To(Equal("NameCrane Hoist (https://one.example.com)"))
+		})
+	})
+
+	Describe("Rename", func() {
+		It("Should replace an existing destination file", func() {
+			source := client.AddFile("/a/source.txt", []byte("new"))
+			dest := client.AddFile("/b/dest.txt", []byte("old"))
+
+			Expect(fs.Rename("/a/source.txt", "/b/dest.txt")).To(Succeed())
+
+			file, err := client.GetFileByPath(context.Background(), "/b/dest.txt")
+
+			Expect(err).To(BeNil())
+			Expect(file.ID).To(Equal(source.ID))
+			Expect(client.Folder("/b").Files).To(HaveLen(1))
+			Expect(client.Folder("/a").Files).To(BeEmpty())
+
+			_, ok := client.Data(dest.ID)
+
+			Expect(ok).To(BeFalse())
+		})
+		It("Should replace an empty destination folder", func() {
+			client.AddFile("/a/file.txt", []byte("data"))
+			client.AddFolder("/b")
+
+			Expect(fs.Rename("/a", "/b")).To(Succeed())
+
+			Expect(client.Folder("/a")).To(BeNil())
+			Expect(client.Folder("/b").Files).To(HaveLen(1))
+		})
+		It("Should not replace a folder which isn't empty", func() {
+			client.AddFolder("/a")
+			client.AddFile("/b/file.txt", []byte("data"))
+
+			err := fs.Rename("/a", "/b")
+
+			Expect(errors.Is(err, iofs.ErrExist)).To(BeTrue())
+			Expect(client.Folder("/a")).ToNot(BeNil())
+		})
+		It("Should return ErrExist with WithRenameNoReplace", func() {
+			fs = New(client, WithRenameNoReplace())
+
+			source := client.AddFile("/a/source.txt", []byte("new"))
+			dest := client.AddFile("/b/dest.txt", []byte("old"))
+
+			err := fs.Rename("/a/source.txt", "/b/dest.txt")
+
+			Expect(errors.Is(err, iofs.ErrExist)).To(BeTrue())
+
+			file, err := client.GetFileByPath(context.Background(), "/b/dest.txt")
+
+			Expect(err).To(BeNil())
+			Expect(file.ID).To(Equal(dest.ID))
+
+			file, err = client.GetFileByPath(context.Background(), "/a/source.txt")
+
+			Expect(err).To(BeNil())
+			Expect(file.ID).To(Equal(source.ID))
+		})
+		It("Should restore the source when replacing fails", func() {
+			fs = New(&failingDeleteClient{Client: client})
+
+			source := client.AddFile("/a/source.txt", []byte("new"))
+			dest := client.AddFile("/b/dest.txt", []byte("old"))
+
+			Expect(fs.Rename("/a/source.txt", "/b/dest.txt")).ToNot(Succeed())
+
+			file, err := client.GetFileByPath(context.Background(), "/a/source.txt")
+
+			Expect(err).To(BeNil())
+			Expect(file.ID).To(Equal(source.ID))
+
+			file, err = client.GetFileByPath(context.Background(), "/b/dest.txt")
+
+			Expect(err).To(BeNil())
+			Expect(file.ID).To(Equal(dest.ID))
+			Expect(client.Folder("/b").Files).To(HaveLen(1))
+		})
+	})
+
+	Describe("CleanupTemp", func() {
+		It("Should only remove old temp files created by the package", func() {
+			tempFs := afero.NewMemMapFs()
+			fs = New(client, WithWriteFs(tempFs))
+
+			old := time.Now().Add(-2 * time.Hour)
+
+			for _, name := range []string{tempFilePrefix + "old", tempFilePrefix + "new", "other-old"} {
+				Expect(afero.WriteFile(tempFs, name, []byte("data"), 0644)).To(Succeed())
+			}
+
+			Expect(tempFs.Chtimes(tempFilePrefix+"old", old, old)).To(Succeed())
+			Expect(tempFs.Chtimes("other-old", old, old)).To(Succeed())
+
+			removed, err := fs.CleanupTemp(time.Hour)
+
+			Expect(err).To(BeNil())
+			Expect(removed).To(Equal(1))
+
+			for name, exists := range map[string]bool{tempFilePrefix + "old": false, tempFilePrefix + "new": true, "other-old": true} {
+				ok, err := afero.Exists(tempFs, name)
+
+				Expect(err).To(BeNil())
+				Expect(ok).To(Equal(exists), name)
+			}
+		})
+		It("Should create temp files with the package prefix", func() {
+			tempFs := afero.NewMemMapFs()
+			fs = New(client, WithWriteFs(tempFs))
+
+			f, err := fs.Create("/file.txt")
+
+			Expect(err).To(BeNil())
+
+			_, err = f.Write([]byte("data"))
+
+			Expect(err).To(BeNil())
+
+			entries, err := afero.ReadDir(tempFs, ".")
+
+			Expect(err).To(BeNil())
+			Expect(entries).To(HaveLen(1))
+			Expect(strings.HasPrefix(entries[0].Name(), tempFilePrefix)).To(BeTrue())
+
+			Expect(f.Close()).To(Succeed())
+		})
+	})
+})
@@ -0,0 +1,196 @@
+package fs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"slices"
+	"sort"
+
+	"github.com/namecrane/hoist"
+	log "github.com/sirupsen/logrus"
+)
+
+// defaultPatchLimit is the pending patch size above which an existing file is loaded and re-uploaded in full, unless
+// changed with WithPatchLimit
+const defaultPatchLimit = 8 << 20
+
+// WithPatchLimit caps how many bytes of writes to an existing file are kept in memory as patches. Beyond the limit,
+// the file is loaded into the write buffer or temporary filesystem with the patches applied, and re-uploaded in full
+// on Close. Defaults to 8MiB.
+func WithPatchLimit(maxBytes int64) Option {
+	return func(f *FileSystem) {
+		f.patchLimit = maxBytes
+	}
+}
+
+// patch is a pending write to an existing file
+type patch struct {
+	off  int64
+	data []byte
+}
+
+func (p patch) end() int64 {
+	return p.off + int64(len(p.data))
+}
+
+// patching returns true if writes to the file should be sent as ranged patches on Close.
+// This applies to existing files opened for writing without truncation, until the file is fully loaded for writing.
+func (c *CraneFile) patching() bool {
+	return c.file != nil &&
+		c.mode&(os.O_WRONLY|os.O_RDWR) != 0 &&
+		c.mode&os.O_TRUNC == 0 &&
+		c.buffer == nil && c.temporaryFile == nil
+}
+
+// addPatch records a write to an existing file, merging it with any pending patches it overlaps or touches so
+// sequential writes are sent as a single request. Patches are kept sorted by offset and never overlap.
+// If the patches would grow beyond the patch limit, the file is loaded for writing instead and false is returned,
+// leaving the caller to write p through the writer. It must be called with the file locked.
+func (c *CraneFile) addPatch(p []byte, off int64) (bool, error) {
+	end := off + int64(len(p))
+
+	// Patches from i up to j overlap or touch the new one
+	i := sort.Search(len(c.patches), func(k int) bool { return c.patches[k].end() >= off })
+	j := sort.Search(len(c.patches), func(k int) bool { return c.patches[k].off > end })
+
+	merged := patch{off: off, data: p}
+
+	if i < j {
+		merged.off = min(off, c.patches[i].off)
+		merged.data = make([]byte, max(end, c.patches[j-1].end())-merged.off)
+
+		for _, existing := range c.patches[i:j] {
+			copy(merged.data[existing.off-merged.off:], existing.data)
+		}
+
+		copy(merged.data[off-merged.off:], p)
+	} else {
+		merged.data = bytes.Clone(p)
+	}
+
+	size := c.patchBytes + int64(len(merged.data))
+
+	for _, existing := range c.patches[i:j] {
+		size -= int64(len(existing.data))
+	}
+
+	limit := c.fs.patchLimit
+
+	if limit <= 0 {
+		limit = defaultPatchLimit
+	}
+
+	if size > limit {
+		log.WithField("file", c.file.ID).Debug("Pending patches exceed the patch limit, loading file for writing")
+
+		return false, c.loadForWrite()
+	}
+
+	c.patches = slices.Replace(c.patches, i, j, merged)
+	c.patchBytes = size
+
+	return true, nil
+}
+
+// applyPatches sends each pending write as a ranged patch. If the backend doesn't support patching, the file is
+// downloaded, patched locally and re-uploaded instead. It must be called with the file locked.
+func (c *CraneFile) applyPatches() error {
+	for i, p := range c.patches {
+		err := c.fs.client.PatchFile(context.Background(), c.file.ID, bytes.NewReader(p.data), p.off, int64(len(p.data)))
+
+		if errors.Is(err, hoist.ErrNotSupported) {
+			log.WithField("file", c.file.ID).Debug("Patching not supported, re-uploading file")
+
+			// Earlier patches were applied remotely, but the re-upload includes them anyway
+			err := c.loadForWrite()
+
+			// Loading opened the writer, starting any auto-commit, which must not outlive Close
+			c.stopAutoCommit()
+
+			if err != nil {
+				return err
+			}
+
+			return c.uploadFile()
+		} else if err != nil {
+			for _, sent := range c.patches[:i] {
+				c.patchBytes -= int64(len(sent.data))
+			}
+
+			c.patches = c.patches[i:]
+
+			return err
+		}
+
+		if end := p.off + int64(len(p.data)); end > c.file.Size {
+			c.file.Size = end
+		}
+	}
+
+	c.patches = nil
+	c.patchBytes = 0
+
+	return nil
+}
+
+// loadForWrite downloads the existing file into the writer and applies any pending patches to it, so the whole file
+// can be modified and re-uploaded. It must be called with the file locked.
+func (c *CraneFile) loadForWrite() error {
+	patches := c.patches
+
+	c.patches = nil
+	c.patchBytes = 0
+
+	if err := c.ensureWriter(); err != nil {
+		return err
+	}
+
+	if c.file.Size > 0 {
+		stream, err := c.fs.client.DownloadFile(context.Background(), c.file.ID)
+
+		if err != nil {
+			return err
+		}
+
+		defer stream.Close()
+
+		if _, err := io.Copy(&offsetWriter{file: c}, stream); err != nil {
+			return err
+		}
+	}
+
+	for _, p := range patches {
+		if err := c.reserve(p.off + int64(len(p.data))); err != nil {
+			return err
+		}
+
+		if _, err := c.writer().WriteAt(p.data, p.off); err != nil {
+			return err
+		}
+	}
+
+	c.dirty = true
+
+	return nil
+}
+
+// offsetWriter writes sequentially into a CraneFile's writer from offset 0, spilling the buffer as needed
+type offsetWriter struct {
+	file *CraneFile
+	off  int64
+}
+
+func (w *offsetWriter) Write(p []byte) (int, error) {
+	if err := w.file.reserve(w.off + int64(len(p))); err != nil {
+		return 0, err
+	}
+
+	n, err := w.file.writer().WriteAt(p, w.off)
+
+	w.off += int64(n)
+
+	return n, err
+}
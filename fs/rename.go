@@ -0,0 +1,175 @@
+package fs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/namecrane/hoist"
+	log "github.com/sirupsen/logrus"
+	"io/fs"
+	"os"
+)
+
+// WithRenameNoReplace makes Rename fail with os.ErrExist when the destination already exists,
+// instead of replacing it as POSIX rename does.
+func WithRenameNoReplace() Option {
+	return func(f *FileSystem) {
+		f.renameNoReplace = true
+	}
+}
+
+// Rename moves and/or renames a file or folder. An existing destination is replaced, matching POSIX: a file replaces
+// a file, and a folder replaces an empty folder. Replacing is refused with WithRenameNoReplace.
+// If a step fails part way through, the source is moved back so it isn't left orphaned. Replacing isn't atomic
+// though: the backend has no way to restore a deleted file or folder, so a destination deleted before a later step
+// fails is lost.
+func (c *FileSystem) Rename(oldName, newName string) error {
+	ctx := context.Background()
+
+	folder, file, err := c.client.Find(ctx, oldName)
+
+	if err != nil {
+		return err
+	}
+
+	oldBase, oldFileName := c.client.ParsePath(oldName)
+	base, name := c.client.ParsePath(newName)
+
+	if base == oldBase && name == oldFileName {
+		return nil
+	}
+
+	destFolder, destFile, err := c.client.Find(ctx, newName)
+
+	if err != nil && !errors.Is(err, hoist.ErrNoFile) && !errors.Is(err, hoist.ErrNoFolder) {
+		return err
+	}
+
+	if (destFolder != nil || destFile != nil) && c.renameNoReplace {
+		return &os.LinkError{Op: "rename", Old: oldName, New: newName, Err: fs.ErrExist}
+	}
+
+	if folder != nil {
+		return c.renameFolder(ctx, folder, destFolder, destFile, oldName, newName)
+	} else if file != nil {
+		return c.renameFile(ctx, file, destFolder, destFile, oldName, newName)
+	}
+
+	return nil
+}
+
+// renameFolder moves a folder, replacing an existing empty destination folder. If the move fails after the
+// destination was deleted, an empty folder is created at its path again, with a new ID.
+func (c *FileSystem) renameFolder(ctx context.Context, folder, destFolder *hoist.Folder, destFile *hoist.File, oldName, newName string) error {
+	oldBase, _ := c.client.ParsePath(oldName)
+	base, name := c.client.ParsePath(newName)
+
+	if destFile != nil {
+		return &os.LinkError{Op: "rename", Old: oldName, New: newName, Err: ErrNotDir}
+	}
+
+	if destFolder != nil {
+		children, err := c.client.HasChildren(ctx, destFolder.Path)
+
+		if err != nil {
+			return err
+		} else if children {
+			return &os.LinkError{Op: "rename", Old: oldName, New: newName, Err: fmt.Errorf("%w: directory not empty", fs.ErrExist)}
+		}
+
+		if err := c.client.DeleteFolder(ctx, destFolder.Path); err != nil {
+			return err
+		}
+	}
+
+	var newParent string
+
+	if base != oldBase {
+		newParent = base
+	}
+
+	err := c.client.MoveFolder(ctx, folder.Path, newParent, name)
+
+	// The move is a single call, so only the deleted destination's path needs recreating
+	if err != nil && destFolder != nil {
+		if _, createErr := c.client.CreateFolder(ctx, destFolder.Path); createErr != nil {
+			log.WithError(createErr).WithField("folder", destFolder.Path).Warning("Failed to recreate replaced folder")
+		}
+	}
+
+	return err
+}
+
+// renameFile moves and renames a file. When replacing a destination, the source is first given a temporary name and
+// moved alongside it, so the destination is only deleted once the source is in place. Only the final rename can fail
+// after the deletion, in which case the source is moved back but the destination is gone.
+func (c *FileSystem) renameFile(ctx context.Context, file *hoist.File, destFolder *hoist.Folder, destFile *hoist.File, oldName, newName string) error {
+	oldBase, oldFileName := c.client.ParsePath(oldName)
+	base, name := c.client.ParsePath(newName)
+
+	if destFolder != nil {
+		return &os.LinkError{Op: "rename", Old: oldName, New: newName, Err: ErrIsDir}
+	}
+
+	// Steps completed so far, undone in reverse if a later step fails
+	var undo []func() error
+
+	rollback := func(err error) error {
+		for i := len(undo) - 1; i >= 0; i-- {
+			if undoErr := undo[i](); undoErr != nil {
+				log.WithError(undoErr).WithField("file", oldName).Warning("Failed to roll back rename")
+			}
+		}
+
+		return err
+	}
+
+	currentName := oldFileName
+
+	if destFile != nil {
+		u, err := uuid.NewV7()
+
+		if err != nil {
+			return err
+		}
+
+		currentName = "." + name + ".rename-" + u.String()
+
+		if err := c.client.RenameFile(ctx, file.ID, currentName); err != nil {
+			return err
+		}
+
+		undo = append(undo, func() error {
+			return c.client.RenameFile(ctx, file.ID, oldFileName)
+		})
+	}
+
+	if base != oldBase {
+		if err := c.client.MoveFiles(ctx, base, file.ID); err != nil {
+			return rollback(err)
+		}
+
+		undo = append(undo, func() error {
+			return c.client.MoveFiles(ctx, oldBase, file.ID)
+		})
+	}
+
+	if destFile != nil {
+		if err := c.client.DeleteFiles(ctx, destFile.ID); err != nil {
+			return rollback(err)
+		}
+	}
+
+	if name != currentName {
+		if err := c.client.RenameFile(ctx, file.ID, name); err != nil {
+			if destFile != nil {
+				log.WithError(err).WithField("file", newName).Warning("Rename failed after deleting the replaced file")
+			}
+
+			return rollback(err)
+		}
+	}
+
+	return nil
+}
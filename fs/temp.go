@@ -0,0 +1,37 @@
+package fs
+
+import (
+	"github.com/spf13/afero"
+	"strings"
+	"time"
+)
+
+// tempFilePrefix marks temporary files created for writes, so CleanupTemp only removes files this package created
+const tempFilePrefix = "hoist-write-"
+
+// CleanupTemp removes temporary write files left in the temp filesystem (see WithWriteFs) by uploads which never
+// completed, such as after a crash. Only files created by this package and last modified over olderThan ago are
+// removed, so choose a threshold well above how long a write can sit idle to avoid removing files still in use.
+func (c *FileSystem) CleanupTemp(olderThan time.Duration) (removed int, err error) {
+	entries, err := afero.ReadDir(c.tempFs, ".")
+
+	if err != nil {
+		return 0, err
+	}
+
+	cutoff := time.Now().Add(-olderThan)
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasPrefix(entry.Name(), tempFilePrefix) || !entry.ModTime().Before(cutoff) {
+			continue
+		}
+
+		if err := c.tempFs.Remove(entry.Name()); err != nil {
+			return removed, err
+		}
+
+		removed++
+	}
+
+	return removed, nil
+}
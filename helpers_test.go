@@ -0,0 +1,183 @@
+package hoist
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// testAuthManager is a static AuthManager which always returns the same token
+type testAuthManager struct {
+	token string
+}
+
+func (t *testAuthManager) Authenticate(ctx context.Context, username, password, twoFactorCode string) error {
+	return nil
+}
+
+func (t *testAuthManager) RefreshToken(ctx context.Context) error {
+	return nil
+}
+
+func (t *testAuthManager) ForceRefresh(ctx context.Context) error {
+	return nil
+}
+
+func (t *testAuthManager) GetToken(ctx context.Context) (string, error) {
+	return t.token, nil
+}
+
+func (t *testAuthManager) AccessToken(ctx context.Context) (string, time.Time, error) {
+	return t.token, time.Now().Add(time.Hour), nil
+}
+
+func (t *testAuthManager) TokenStatus(ctx context.Context) (bool, time.Duration, error) {
+	return true, time.Hour, nil
+}
+
+func (t *testAuthManager) ClientID() string {
+	return "HOIST-TEST"
+}
+
+// failingAuthManager is an AuthManager which can never retrieve a token, counting each attempt
+type failingAuthManager struct {
+	testAuthManager
+	mu    sync.Mutex
+	calls int
+}
+
+func (f *failingAuthManager) GetToken(ctx context.Context) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls++
+
+	return "", errors.New("token unavailable")
+}
+
+func (f *failingAuthManager) Calls() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.calls
+}
+
+// newTestClient starts a test server with the handler and returns a client pointed at it
+func newTestClient(handler http.Handler, opts ...ClientOption) (*client, *httptest.Server) {
+	server := httptest.NewServer(handler)
+
+	c := NewClient(server.URL, &testAuthManager{token: "test-token"}, opts...).(*client)
+
+	return c, server
+}
+
+// testPath returns the server path of an endpoint using the default API version
+func testPath(endpoint string) string {
+	return "/" + strings.Replace(endpoint, "{version}", defaultAPIVersion, 1)
+}
+
+// testTree returns a small nested folder tree, /a/b, with one file per folder
+func testTree() Folder {
+	return Folder{
+		Name:  "",
+		Path:  "/",
+		Files: []File{{ID: "1", Name: "root.txt"}},
+		Subfolders: []Folder{
+			{
+				Name:  "a",
+				Path:  "/a",
+				Files: []File{{ID: "2", Name: "a.txt"}},
+				Subfolders: []Folder{
+					{
+						Name:  "b",
+						Path:  "/a/b",
+						Files: []File{{ID: "3", Name: "b.txt"}},
+					},
+				},
+			},
+		},
+	}
+}
+
+// uploadedChunk is a single chunk received by testUploadServer
+type uploadedChunk struct {
+	Fields map[string]string
+	Data   []byte
+}
+
+// testUploadServer records chunks sent to the upload endpoint, responding with a File on the final chunk
+type testUploadServer struct {
+	mu     sync.Mutex
+	Chunks []uploadedChunk
+}
+
+func (t *testUploadServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	chunk := uploadedChunk{Fields: make(map[string]string)}
+
+	for key, values := range r.MultipartForm.Value {
+		chunk.Fields[key] = values[0]
+	}
+
+	file, _, err := r.FormFile("file")
+
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	chunk.Data, _ = io.ReadAll(file)
+
+	t.mu.Lock()
+	t.Chunks = append(t.Chunks, chunk)
+	t.mu.Unlock()
+
+	if chunk.Fields["resumableChunkNumber"] != chunk.Fields["resumableTotalChunks"] {
+		_ = json.NewEncoder(w).Encode(defaultResponse{Success: true})
+		return
+	}
+
+	size, _ := strconv.ParseInt(chunk.Fields["resumableTotalSize"], 10, 64)
+
+	_ = json.NewEncoder(w).Encode(File{
+		ID:   chunk.Fields["resumableIdentifier"],
+		Name: chunk.Fields["resumableFilename"],
+		Size: size,
+	})
+}
+
+// Data returns all uploaded chunk data joined in chunk number order
+func (t *testUploadServer) Data() []byte {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// Concurrent chunks may arrive in any order
+	chunks := slices.Clone(t.Chunks)
+
+	slices.SortStableFunc(chunks, func(a, b uploadedChunk) int {
+		x, _ := strconv.Atoi(a.Fields["resumableChunkNumber"])
+		y, _ := strconv.Atoi(b.Fields["resumableChunkNumber"])
+
+		return x - y
+	})
+
+	var data []byte
+
+	for _, chunk := range chunks {
+		data = append(data, chunk.Data...)
+	}
+
+	return data
+}
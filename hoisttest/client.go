@@ -0,0 +1,1208 @@
+// Package hoisttest provides an in-memory hoist.Client for testing code built on top of hoist, without a server.
+package hoisttest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"net/http"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/namecrane/hoist"
+	"github.com/namecrane/hoist/internal/validate"
+)
+
+var ErrFolderExists = errors.New("folder already exists")
+
+var _ hoist.Client = (*Client)(nil)
+
+// Client is an in-memory implementation of hoist.Client.
+// Folders and files are stored in a tree, with file contents kept in memory. It is safe for concurrent use.
+type Client struct {
+	mu       sync.Mutex
+	root     *hoist.Folder
+	data     map[string][]byte
+	params   map[string]hoist.EditFileParams
+	chat     map[string][]hoist.File
+	lastID   int
+	allowed  int64
+	baseLink string
+	draining bool
+
+	// Caps are the capabilities returned by Capabilities, defaulting to hoist.BaselineCapabilities with Ranges set,
+	// as DownloadFile honours Range headers
+	Caps hoist.Capabilities
+
+	// PatchUnsupported makes PatchFile return hoist.ErrNotSupported, like backends without ranged updates
+	PatchUnsupported bool
+}
+
+// NewClient creates a new in-memory client containing only an empty root folder
+func NewClient() *Client {
+	return &Client{
+		root:     &hoist.Folder{Name: "", Path: "/"},
+		data:     make(map[string][]byte),
+		params:   make(map[string]hoist.EditFileParams),
+		chat:     make(map[string][]hoist.File),
+		allowed:  10 * 1024 * 1024 * 1024,
+		baseLink: "https://hoist.test",
+		Caps:     fakeCapabilities(),
+	}
+}
+
+// fakeCapabilities are the capabilities the in-memory client supports
+func fakeCapabilities() hoist.Capabilities {
+	caps := hoist.BaselineCapabilities()
+	caps.Ranges = true
+
+	return caps
+}
+
+// ClockSkew is always zero, as the in-memory client shares the local clock
+func (c *Client) ClockSkew() time.Duration {
+	return 0
+}
+
+// Drain rejects new uploads with hoist.ErrDraining. In-memory uploads hold the lock throughout, so there's nothing to
+// wait for.
+func (c *Client) Drain(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.draining = true
+
+	return nil
+}
+
+// AddFile stores a file with the specified contents, creating any missing parent folders
+func (c *Client) AddFile(filePath string, data []byte) hoist.File {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	dir, name := hoist.ParsePath(filePath)
+
+	return c.putFile(c.mkdirAll(dir), name, data)
+}
+
+// AddFolder creates a folder, along with any missing parent folders
+func (c *Client) AddFolder(folderPath string) hoist.Folder {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return copyFolder(*c.mkdirAll(folderPath))
+}
+
+// Folder returns a copy of the folder at the path, or nil if it doesn't exist
+func (c *Client) Folder(folderPath string) *hoist.Folder {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	folder := c.folder(folderPath)
+
+	if folder == nil {
+		return nil
+	}
+
+	f := copyFolder(*folder)
+
+	return &f
+}
+
+// Data returns the contents of the file with the specified id
+func (c *Client) Data(id string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data, ok := c.data[id]
+
+	return data, ok
+}
+
+// cleanPath normalizes paths to the form used by folders, e.g. /a/b
+func cleanPath(p string) string {
+	return path.Clean("/" + strings.ReplaceAll(p, "\\", "/"))
+}
+
+// folder finds a folder in the tree by its path. c.mu must be held.
+func (c *Client) folder(p string) *hoist.Folder {
+	p = cleanPath(p)
+
+	current := c.root
+
+	if p == "/" {
+		return current
+	}
+
+	for _, segment := range strings.Split(strings.Trim(p, "/"), "/") {
+		var next *hoist.Folder
+
+		for i := range current.Subfolders {
+			if current.Subfolders[i].Name == segment {
+				next = &current.Subfolders[i]
+				break
+			}
+		}
+
+		if next == nil {
+			return nil
+		}
+
+		current = next
+	}
+
+	return current
+}
+
+// mkdirAll returns the folder at the path, creating it and any parents as needed. c.mu must be held.
+func (c *Client) mkdirAll(p string) *hoist.Folder {
+	current := c.root
+
+	for _, segment := range strings.Split(strings.Trim(cleanPath(p), "/"), "/") {
+		if segment == "" {
+			continue
+		}
+
+		var next *hoist.Folder
+
+		for i := range current.Subfolders {
+			if current.Subfolders[i].Name == segment {
+				next = &current.Subfolders[i]
+				break
+			}
+		}
+
+		if next == nil {
+			current.Subfolders = append(current.Subfolders, hoist.Folder{
+				Name: segment,
+				Path: path.Join(current.Path, segment),
+			})
+
+			next = &current.Subfolders[len(current.Subfolders)-1]
+		}
+
+		current = next
+	}
+
+	return current
+}
+
+// putFile stores a file in folder, replacing any existing file with the same name. c.mu must be held.
+func (c *Client) putFile(folder *hoist.Folder, name string, data []byte) hoist.File {
+	c.lastID++
+
+	file := hoist.File{
+		ID:         strconv.Itoa(c.lastID),
+		Name:       name,
+		Type:       http.DetectContentType(data),
+		Size:       int64(len(data)),
+		DateAdded:  time.Now(),
+		FolderPath: folder.Path,
+		Version:    "1",
+	}
+
+	for i, existing := range folder.Files {
+		if existing.Name == name {
+			delete(c.data, existing.ID)
+
+			folder.Files = append(folder.Files[:i], folder.Files[i+1:]...)
+			break
+		}
+	}
+
+	folder.Files = append(folder.Files, file)
+	folder.Count = len(folder.Files)
+
+	c.data[file.ID] = data
+
+	return file
+}
+
+// findFile returns the folder containing the file and the file's index within it. c.mu must be held.
+func (c *Client) findFile(id string) (*hoist.Folder, int) {
+	var found *hoist.Folder
+	index := -1
+
+	var walk func(folder *hoist.Folder)
+
+	walk = func(folder *hoist.Folder) {
+		for i, file := range folder.Files {
+			if file.ID == id {
+				found, index = folder, i
+				return
+			}
+		}
+
+		for i := range folder.Subfolders {
+			if found == nil {
+				walk(&folder.Subfolders[i])
+			}
+		}
+	}
+
+	walk(c.root)
+
+	return found, index
+}
+
+// copyFolder deep copies a folder so callers can't modify the stored tree
+func copyFolder(f hoist.Folder) hoist.Folder {
+	f.Files = append([]hoist.File(nil), f.Files...)
+
+	subfolders := make([]hoist.Folder, len(f.Subfolders))
+
+	for i, sub := range f.Subfolders {
+		subfolders[i] = copyFolder(sub)
+	}
+
+	f.Subfolders = subfolders
+
+	return f
+}
+
+// setPath updates the path of a folder and everything below it
+func setPath(folder *hoist.Folder, p string) {
+	folder.Path = p
+
+	for i := range folder.Files {
+		folder.Files[i].FolderPath = p
+	}
+
+	for i := range folder.Subfolders {
+		setPath(&folder.Subfolders[i], path.Join(p, folder.Subfolders[i].Name))
+	}
+}
+
+// removeSubfolder removes the named subfolder from parent, returning it
+func removeSubfolder(parent *hoist.Folder, name string) (hoist.Folder, bool) {
+	for i, sub := range parent.Subfolders {
+		if sub.Name == name {
+			parent.Subfolders = append(parent.Subfolders[:i], parent.Subfolders[i+1:]...)
+			return sub, true
+		}
+	}
+
+	return hoist.Folder{}, false
+}
+
+func (c *Client) Capabilities(ctx context.Context) (*hoist.Capabilities, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	caps := c.Caps
+
+	return &caps, nil
+}
+
+func (c *Client) DiskUsageSummary(ctx context.Context) (*hoist.DiskUsage, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var used, chatUsed int64
+
+	for _, data := range c.data {
+		used += int64(len(data))
+	}
+
+	for _, files := range c.chat {
+		for _, file := range files {
+			chatUsed += file.Size
+		}
+	}
+
+	return &hoist.DiskUsage{
+		Allowed:     c.allowed,
+		Used:        used,
+		FileStorage: used - chatUsed,
+		ChatFiles:   chatUsed,
+	}, nil
+}
+
+// UploadChatFile stores the chat attachment in memory, see ChatFiles
+func (c *Client) UploadChatFile(ctx context.Context, in io.Reader, conversationID, fileName string, size int64) (*hoist.File, error) {
+	if err := validate.ConversationID(conversationID); err != nil {
+		return nil, err
+	}
+
+	data, err := io.ReadAll(io.LimitReader(in, size))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(len(data)) != size {
+		return nil, fmt.Errorf("expected %d bytes, read %d", size, len(data))
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.draining {
+		return nil, hoist.ErrDraining
+	}
+
+	c.lastID++
+
+	file := hoist.File{
+		ID:        strconv.Itoa(c.lastID),
+		Name:      fileName,
+		Type:      http.DetectContentType(data),
+		Size:      size,
+		DateAdded: time.Now(),
+	}
+
+	c.chat[conversationID] = append(c.chat[conversationID], file)
+	c.data[file.ID] = data
+
+	return &file, nil
+}
+
+// ChatFiles returns the files uploaded to the conversation with UploadChatFile
+func (c *Client) ChatFiles(conversationID string) []hoist.File {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return slices.Clone(c.chat[conversationID])
+}
+
+// ChunkSize returns the maximum chunk size from Caps, as the in-memory client doesn't split uploads
+func (c *Client) ChunkSize() int64 {
+	return c.Caps.MaxChunkSize
+}
+
+// Upload stores the file in memory like ChunkedUpload. Like the real client, files larger than ChunkSize fail with
+// hoist.ErrUploadTooLarge.
+func (c *Client) Upload(ctx context.Context, in io.Reader, filePath string, fileSize int64) (*hoist.File, error) {
+	if err := validate.Upload(fileSize, c.ChunkSize()); err != nil {
+		return nil, err
+	}
+
+	return c.ChunkedUpload(ctx, in, filePath, fileSize)
+}
+
+// ChunkedUpload stores the file in memory. Upload options are ignored.
+func (c *Client) ChunkedUpload(ctx context.Context, in io.Reader, filePath string, fileSize int64, opts ...hoist.UploadOpt) (*hoist.File, error) {
+	data, err := io.ReadAll(io.LimitReader(in, fileSize))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(len(data)) != fileSize {
+		return nil, fmt.Errorf("expected %d bytes, read %d", fileSize, len(data))
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.draining {
+		return nil, hoist.ErrDraining
+	}
+
+	dir, name := hoist.ParsePath(filePath)
+
+	folder := c.folder(dir)
+
+	if folder == nil {
+		return nil, hoist.ErrNoFolder
+	}
+
+	file := c.putFile(folder, name, data)
+
+	return &file, nil
+}
+
+// ChunkedUploadAt stores the file like ChunkedUpload, reading it from ra
+func (c *Client) ChunkedUploadAt(ctx context.Context, ra io.ReaderAt, filePath string, fileSize int64, opts ...hoist.UploadOpt) (*hoist.File, error) {
+	return c.ChunkedUpload(ctx, io.NewSectionReader(ra, 0, fileSize), filePath, fileSize, opts...)
+}
+
+// ResumeUpload stores the whole file like ChunkedUploadAt, as the in-memory client doesn't keep partial uploads.
+// The resume point is still validated against ChunkSize.
+func (c *Client) ResumeUpload(ctx context.Context, ra io.ReaderAt, filePath string, fileSize int64, identifier string, startChunk int, opts ...hoist.UploadOpt) (*hoist.File, error) {
+	if err := validate.Resume(identifier, startChunk, hoist.ChunkCount(fileSize, c.ChunkSize())); err != nil {
+		return nil, err
+	}
+
+	return c.ChunkedUploadAt(ctx, ra, filePath, fileSize, opts...)
+}
+
+func (c *Client) WriteFile(ctx context.Context, remotePath string, data []byte, opts ...hoist.UploadOpt) (*hoist.File, error) {
+	return hoist.WriteFile(ctx, c, remotePath, data, opts...)
+}
+
+func (c *Client) UploadReadSeeker(ctx context.Context, rs io.ReadSeeker, remotePath string, opts ...hoist.UploadOpt) (*hoist.File, error) {
+	start, err := rs.Seek(0, io.SeekCurrent)
+
+	if err != nil {
+		return nil, err
+	}
+
+	end, err := rs.Seek(0, io.SeekEnd)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := rs.Seek(start, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	return c.ChunkedUpload(ctx, rs, remotePath, end-start, opts...)
+}
+
+// Endpoint identifies the fake client, allowing it to be told apart from real endpoints
+func (c *Client) Endpoint() string {
+	return "memory"
+}
+
+func (c *Client) ParsePath(p string) (basePath, lastSegment string) {
+	return hoist.ParsePath(p)
+}
+
+func (c *Client) GetFolders(ctx context.Context) ([]hoist.Folder, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return copyFolder(*c.root).Flatten(), nil
+}
+
+func (c *Client) AllFiles(ctx context.Context, opts ...hoist.TraversalOpt) ([]hoist.File, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return copyFolder(*c.root).AllFiles(opts...), nil
+}
+
+func (c *Client) Manifest(ctx context.Context, root string) ([]hoist.FileEntry, error) {
+	folder := c.Folder(root)
+
+	if folder == nil {
+		return nil, hoist.ErrNoFolder
+	}
+
+	return hoist.BuildManifest(*folder), nil
+}
+
+func (c *Client) Diff(ctx context.Context, localDir, remoteRoot string, opts ...hoist.DiffOpt) ([]string, []string, []string, error) {
+	return hoist.Diff(ctx, c, localDir, remoteRoot, opts...)
+}
+
+func (c *Client) GetFolder(ctx context.Context, folder string, opts ...hoist.FolderOpt) (*hoist.Folder, error) {
+	if f := c.Folder(folder); f != nil {
+		return f, nil
+	}
+
+	return nil, hoist.ErrNoFolder
+}
+
+func (c *Client) StreamFolder(ctx context.Context, folder string, fn func(file hoist.File) error, opts ...hoist.FolderOpt) (*hoist.Folder, error) {
+	f, err := c.GetFolder(ctx, folder, opts...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range f.Files {
+		if file.FolderPath == "" {
+			file.FolderPath = folder
+		}
+
+		if err := fn(file); err != nil {
+			return nil, err
+		}
+	}
+
+	f.Files = nil
+
+	return f, nil
+}
+
+func (c *Client) GetFolderMeta(ctx context.Context, folderPath string) (*hoist.Folder, error) {
+	f, err := c.GetFolder(ctx, folderPath)
+
+	if err != nil {
+		return nil, err
+	}
+
+	f.Files = nil
+
+	return f, nil
+}
+
+func (c *Client) HasChildren(ctx context.Context, folderPath string) (bool, error) {
+	f, err := c.GetFolder(ctx, folderPath)
+
+	if err != nil {
+		return false, err
+	}
+
+	return len(f.Files) > 0 || len(f.Subfolders) > 0, nil
+}
+
+func (c *Client) GetFiles(ctx context.Context, ids ...string) ([]hoist.File, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var files []hoist.File
+
+	for _, id := range ids {
+		if folder, i := c.findFile(id); folder != nil {
+			files = append(files, folder.Files[i])
+		}
+	}
+
+	return files, nil
+}
+
+// GetFilesWithFields returns the files with any fields not requested zeroed, like a backend supporting projection
+func (c *Client) GetFilesWithFields(ctx context.Context, fields []string, ids ...string) ([]hoist.File, error) {
+	files, err := c.GetFiles(ctx, ids...)
+
+	if err != nil || len(fields) == 0 {
+		return files, err
+	}
+
+	for i, file := range files {
+		projected := hoist.File{
+			ID:   file.ID,
+			Name: file.Name,
+		}
+
+		for _, field := range fields {
+			switch field {
+			case hoist.FileFieldType:
+				projected.Type = file.Type
+			case hoist.FileFieldSize:
+				projected.Size = file.Size
+			case hoist.FileFieldDateAdded:
+				projected.DateAdded = file.DateAdded
+			case hoist.FileFieldFolderPath:
+				projected.FolderPath = file.FolderPath
+			}
+		}
+
+		files[i] = projected
+	}
+
+	return files, nil
+}
+
+func (c *Client) Files(ctx context.Context, folder string) iter.Seq2[hoist.File, error] {
+	return func(yield func(hoist.File, error) bool) {
+		f, err := c.GetFolder(ctx, folder)
+
+		if err != nil {
+			yield(hoist.File{}, err)
+			return
+		}
+
+		for _, file := range f.Files {
+			if !yield(file, nil) {
+				return
+			}
+		}
+	}
+}
+
+// EnsureRoot returns the root folder, which always exists in the in-memory client
+func (c *Client) EnsureRoot(ctx context.Context) (*hoist.Folder, error) {
+	return c.GetFolder(ctx, "/")
+}
+
+func (c *Client) Folders(ctx context.Context) iter.Seq2[hoist.Folder, error] {
+	return func(yield func(hoist.Folder, error) bool) {
+		folders, _ := c.GetFolders(ctx)
+
+		for _, folder := range folders {
+			if !yield(folder, nil) {
+				return
+			}
+		}
+	}
+}
+
+func (c *Client) Ancestry(ctx context.Context, fileID string) ([]hoist.Folder, *hoist.File, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	folder, i := c.findFile(fileID)
+
+	if folder == nil {
+		return nil, nil, hoist.ErrNoFile
+	}
+
+	file := folder.Files[i]
+
+	ancestry := []hoist.Folder{copyFolder(*c.root)}
+
+	current := "/"
+
+	for _, segment := range strings.Split(strings.Trim(folder.Path, "/"), "/") {
+		if segment == "" {
+			continue
+		}
+
+		current = path.Join(current, segment)
+
+		ancestry = append(ancestry, copyFolder(*c.folder(current)))
+	}
+
+	return ancestry, &file, nil
+}
+
+func (c *Client) DeleteFiles(ctx context.Context, ids ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, id := range ids {
+		folder, i := c.findFile(id)
+
+		if folder == nil {
+			return hoist.ErrNoFile
+		}
+
+		folder.Files = append(folder.Files[:i], folder.Files[i+1:]...)
+		folder.Count = len(folder.Files)
+
+		delete(c.data, id)
+		delete(c.params, id)
+	}
+
+	return nil
+}
+
+// DownloadFile returns the file contents, supporting a single `bytes=start-end` Range header in opts
+func (c *Client) DownloadFile(ctx context.Context, id string, opts ...hoist.RequestOpt) (io.ReadCloser, error) {
+	data, ok := c.Data(id)
+
+	if !ok {
+		return nil, hoist.ErrNoFile
+	}
+
+	req, err := http.NewRequest(http.MethodGet, "http://hoist.test", nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, opt := range opts {
+		opt(req)
+	}
+
+	if r := req.Header.Get("Range"); r != "" {
+		var start, end int64 = 0, int64(len(data)) - 1
+
+		if _, err := fmt.Sscanf(r, "bytes=%d-%d", &start, &end); err != nil && !strings.HasSuffix(r, "-") {
+			return nil, err
+		}
+
+		if start > int64(len(data)) {
+			start = int64(len(data))
+		}
+
+		if end >= int64(len(data)) {
+			end = int64(len(data)) - 1
+		}
+
+		data = data[start : end+1]
+	}
+
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+// OpenDownload returns the file contents like DownloadFile, with the file's Type as the content type
+func (c *Client) OpenDownload(ctx context.Context, id string, opts ...hoist.DownloadOpt) (*hoist.Download, error) {
+	body, err := c.DownloadFile(ctx, id, hoist.DownloadRequestOpts(opts...)...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := io.ReadAll(body)
+
+	if err != nil {
+		return nil, err
+	}
+
+	contentType := "application/octet-stream"
+
+	c.mu.Lock()
+
+	if folder, i := c.findFile(id); folder != nil && folder.Files[i].Type != "" {
+		contentType = folder.Files[i].Type
+	}
+
+	c.mu.Unlock()
+
+	return hoist.NewDownload(io.NopCloser(bytes.NewReader(data)), contentType, int64(len(data)), opts...), nil
+}
+
+func (c *Client) ReadFile(ctx context.Context, fileID string, opts ...hoist.DownloadOpt) ([]byte, error) {
+	download, err := c.OpenDownload(ctx, fileID, opts...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return hoist.ReadDownload(download, opts...)
+}
+
+func (c *Client) DownloadURL(ctx context.Context, fileID string) (string, error) {
+	if _, ok := c.Data(fileID); !ok {
+		return "", hoist.ErrNoFile
+	}
+
+	return c.baseLink + "/download/" + fileID, nil
+}
+
+// PatchFile overwrites the range in place, extending the file if the range ends beyond it
+func (c *Client) PatchFile(ctx context.Context, fileID string, r io.Reader, offset, length int64) error {
+	if c.PatchUnsupported {
+		return hoist.ErrNotSupported
+	}
+
+	patch, err := io.ReadAll(io.LimitReader(r, length))
+
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	folder, i := c.findFile(fileID)
+
+	if folder == nil {
+		return hoist.ErrNoFile
+	}
+
+	data := c.data[fileID]
+
+	if end := offset + int64(len(patch)); end > int64(len(data)) {
+		data = append(data, make([]byte, end-int64(len(data)))...)
+	}
+
+	copy(data[offset:], patch)
+
+	c.data[fileID] = data
+	folder.Files[i].Size = int64(len(data))
+
+	return nil
+}
+
+func (c *Client) GetFileID(ctx context.Context, dir, fileName string) (string, error) {
+	folder := c.Folder(dir)
+
+	if folder == nil {
+		return "", hoist.ErrNoFolder
+	}
+
+	for _, file := range folder.Files {
+		if file.Name == fileName {
+			return file.ID, nil
+		}
+	}
+
+	return "", hoist.ErrNoFile
+}
+
+func (c *Client) Find(ctx context.Context, file string) (*hoist.Folder, *hoist.File, error) {
+	base, name := c.ParsePath(file)
+
+	folder := c.Folder(base)
+
+	if folder == nil {
+		return nil, nil, hoist.ErrNoFolder
+	}
+
+	if name == "" {
+		return folder, nil, nil
+	}
+
+	for _, f := range folder.Files {
+		if f.Name == name {
+			return nil, &f, nil
+		}
+	}
+
+	if sub := folder.Subfolder(name); sub != nil {
+		return sub, nil, nil
+	}
+
+	return nil, nil, hoist.ErrNoFile
+}
+
+func (c *Client) GetFileByPath(ctx context.Context, filePath string) (*hoist.File, error) {
+	_, file, err := c.Find(ctx, filePath)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if file == nil {
+		return nil, hoist.ErrNoFile
+	}
+
+	return file, nil
+}
+
+func (c *Client) CreateFolder(ctx context.Context, folder string) (*hoist.Folder, error) {
+	base, name := c.ParsePath(folder)
+
+	return c.createFolder(base, name)
+}
+
+func (c *Client) CreateSubfolder(ctx context.Context, parentPath, name string) (*hoist.Folder, error) {
+	if err := validate.FolderName(name); err != nil {
+		return nil, err
+	}
+
+	return c.createFolder(path.Clean("/"+parentPath), name)
+}
+
+// createFolder creates name inside the folder at base
+func (c *Client) createFolder(base, name string) (*hoist.Folder, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	parent := c.folder(base)
+
+	if parent == nil {
+		return nil, hoist.ErrNoFolder
+	}
+
+	if parent.Subfolder(name) != nil {
+		return nil, ErrFolderExists
+	}
+
+	parent.Subfolders = append(parent.Subfolders, hoist.Folder{
+		Name: name,
+		Path: path.Join(parent.Path, name),
+	})
+
+	created := copyFolder(parent.Subfolders[len(parent.Subfolders)-1])
+
+	return &created, nil
+}
+
+func (c *Client) MkdirAll(ctx context.Context, folder string, opts ...hoist.MkdirOpt) (*hoist.Folder, error) {
+	return hoist.MkdirAll(ctx, c, folder, opts...)
+}
+
+func (c *Client) UploadDir(ctx context.Context, localDir, remoteDir string, opts ...hoist.UploadDirOpt) (map[string]*hoist.File, map[string]error) {
+	return hoist.UploadDir(ctx, c, localDir, remoteDir, opts...)
+}
+
+func (c *Client) DownloadDir(ctx context.Context, remoteDir, localDir string, opts ...hoist.DownloadDirOpt) (map[string]*hoist.File, map[string]error) {
+	return hoist.DownloadDir(ctx, c, remoteDir, localDir, opts...)
+}
+
+func (c *Client) DeleteFolder(ctx context.Context, folder string) error {
+	if hoist.IsRoot(folder) {
+		return hoist.ErrRootOperation
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	base, name := c.ParsePath(folder)
+
+	parent := c.folder(base)
+
+	if parent == nil {
+		return hoist.ErrNoFolder
+	}
+
+	removed, ok := removeSubfolder(parent, name)
+
+	if !ok {
+		return hoist.ErrNoFolder
+	}
+
+	for _, file := range removed.AllFiles() {
+		delete(c.data, file.ID)
+		delete(c.params, file.ID)
+	}
+
+	return nil
+}
+
+func (c *Client) MoveFiles(ctx context.Context, folder string, fileIDs ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	target := c.folder(folder)
+
+	if target == nil {
+		return hoist.ErrNoFolder
+	}
+
+	for _, id := range fileIDs {
+		source, i := c.findFile(id)
+
+		if source == nil {
+			return hoist.ErrNoFile
+		}
+
+		file := source.Files[i]
+
+		source.Files = append(source.Files[:i], source.Files[i+1:]...)
+		source.Count = len(source.Files)
+
+		file.FolderPath = target.Path
+
+		target.Files = append(target.Files, file)
+		target.Count = len(target.Files)
+	}
+
+	return nil
+}
+
+func (c *Client) MoveFilesResult(ctx context.Context, folder string, fileIDs ...string) ([]hoist.File, error) {
+	if err := c.MoveFiles(ctx, folder, fileIDs...); err != nil {
+		return nil, err
+	}
+
+	return c.GetFiles(ctx, fileIDs...)
+}
+
+func (c *Client) RenameFile(ctx context.Context, fileID string, name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	folder, i := c.findFile(fileID)
+
+	if folder == nil {
+		return hoist.ErrNoFile
+	}
+
+	folder.Files[i].Name = name
+
+	return nil
+}
+
+// EditFile stores the params, which can be retrieved with Params
+func (c *Client) EditFile(ctx context.Context, fileID string, params hoist.EditFileParams) error {
+	if err := params.Validate(); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.editFile(fileID, "", params)
+}
+
+// EditFileIfMatch edits the file like EditFile if its version matches, returning hoist.ErrConflict otherwise.
+// Files start at version "1", which is incremented by every edit.
+func (c *Client) EditFileIfMatch(ctx context.Context, fileID, version string, params hoist.EditFileParams) error {
+	if err := params.Validate(); err != nil {
+		return err
+	}
+
+	if version == "" {
+		return fmt.Errorf("%w: file has no version", hoist.ErrNotSupported)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.editFile(fileID, version, params)
+}
+
+// editFile stores params for the file, bumping its version, if version is empty or matches. c.mu must be held.
+func (c *Client) editFile(fileID, version string, params hoist.EditFileParams) error {
+	folder, i := c.findFile(fileID)
+
+	if folder == nil {
+		return hoist.ErrNoFile
+	}
+
+	file := &folder.Files[i]
+
+	if version != "" && file.Version != version {
+		return fmt.Errorf("%w: file is at version %s", hoist.ErrConflict, file.Version)
+	}
+
+	n, _ := strconv.Atoi(file.Version)
+	file.Version = strconv.Itoa(n + 1)
+
+	c.params[fileID] = params
+
+	return nil
+}
+
+// EditFiles applies the patch's set fields to the params of each file, keeping the rest
+func (c *Client) EditFiles(ctx context.Context, ids []string, patch hoist.EditFilePatch) (map[string]error, error) {
+	if err := patch.Validate(); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	failed := make(map[string]error)
+
+	for _, id := range ids {
+		if err := c.editFile(id, "", patch.Apply(c.params[id])); err != nil {
+			failed[id] = err
+		}
+	}
+
+	if len(failed) > 0 {
+		return failed, fmt.Errorf("%w: %d of %d files failed to update", hoist.ErrPartialFailure, len(failed), len(ids))
+	}
+
+	return failed, nil
+}
+
+// Params returns the params last set on a file with EditFile or EditFiles
+func (c *Client) Params(fileID string) (hoist.EditFileParams, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	params, ok := c.params[fileID]
+
+	return params, ok
+}
+
+func (c *Client) GetLink(ctx context.Context, fileID string) (string, string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if folder, _ := c.findFile(fileID); folder == nil {
+		return "", "", hoist.ErrNoFile
+	}
+
+	return c.baseLink + "/s/" + fileID, c.baseLink + "/public/" + fileID, nil
+}
+
+// GetLinkStatus reports the links along with the published state and MaxDownloads last set with EditFile
+func (c *Client) GetLinkStatus(ctx context.Context, fileID string) (*hoist.LinkStatus, error) {
+	shortLink, publicLink, err := c.GetLink(ctx, fileID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	params := c.params[fileID]
+
+	status := &hoist.LinkStatus{
+		ShortLink:      shortLink,
+		PublicLink:     publicLink,
+		IsPublic:       params.Published,
+		PublishedUntil: params.PublishedUntil,
+	}
+
+	if params.MaxDownloads > 0 {
+		remaining := params.MaxDownloads
+		status.RemainingDownloads = &remaining
+	}
+
+	return status, nil
+}
+
+// FilesModifiedSince returns files with a DateAdded at or after since
+func (c *Client) FilesModifiedSince(ctx context.Context, since time.Time) ([]hoist.File, error) {
+	files, err := c.AllFiles(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return hoist.FilterModifiedSince(files, since), nil
+}
+
+// ListShared returns files published with EditFile whose PublishedUntil is unset or in the future
+func (c *Client) ListShared(ctx context.Context) ([]hoist.SharedFile, error) {
+	files, err := c.AllFiles(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	shared := make([]hoist.SharedFile, 0)
+
+	for _, file := range files {
+		status, err := c.GetLinkStatus(ctx, file.ID)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if !status.IsPublic || (!status.PublishedUntil.IsZero() && status.PublishedUntil.Before(time.Now())) {
+			continue
+		}
+
+		shared = append(shared, hoist.SharedFile{
+			File:               file,
+			ShortLink:          status.ShortLink,
+			PublicLink:         status.PublicLink,
+			PublishedUntil:     status.PublishedUntil,
+			RemainingDownloads: status.RemainingDownloads,
+		})
+	}
+
+	return shared, nil
+}
+
+func (c *Client) MoveFolder(ctx context.Context, folder, newParentFolder, newName string) error {
+	if hoist.IsRoot(folder) {
+		return hoist.ErrRootOperation
+	}
+
+	if err := validate.Move(folder, newParentFolder); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	base, name := c.ParsePath(folder)
+
+	if newName == "" {
+		_, newName = c.ParsePath(newParentFolder)
+	}
+
+	if newParentFolder == "" {
+		newParentFolder = base
+	}
+
+	parent := c.folder(base)
+	target := c.folder(newParentFolder)
+
+	if parent == nil || target == nil {
+		return hoist.ErrNoFolder
+	}
+
+	if target.Subfolder(newName) != nil {
+		return ErrFolderExists
+	}
+
+	moved, ok := removeSubfolder(parent, name)
+
+	if !ok {
+		return hoist.ErrNoFolder
+	}
+
+	// The parent may have been reallocated when removing the folder
+	target = c.folder(newParentFolder)
+
+	moved.Name = newName
+	setPath(&moved, path.Join(target.Path, newName))
+
+	target.Subfolders = append(target.Subfolders, moved)
+
+	return nil
+}
@@ -0,0 +1,425 @@
+package hoisttest_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/namecrane/hoist"
+	"github.com/namecrane/hoist/hoisttest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// failingCreateClient fails to create the folder at path, simulating an interrupted MkdirAll. With cancel set, the
+// failure is the context being cancelled, which also fails any later DeleteFolder using it.
+type failingCreateClient struct {
+	*hoisttest.Client
+	path   string
+	cancel context.CancelFunc
+}
+
+func (c *failingCreateClient) CreateFolder(ctx context.Context, folder string) (*hoist.Folder, error) {
+	if folder == c.path {
+		if c.cancel != nil {
+			c.cancel()
+
+			return nil, ctx.Err()
+		}
+
+		return nil, errors.New("create failed")
+	}
+
+	return c.Client.CreateFolder(ctx, folder)
+}
+
+func (c *failingCreateClient) DeleteFolder(ctx context.Context, folder string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return c.Client.DeleteFolder(ctx, folder)
+}
+
+// renamingClient renames files and folders in folder listings, simulating a server returning malicious names
+type renamingClient struct {
+	*hoisttest.Client
+	names map[string]string
+}
+
+func (c *renamingClient) GetFolders(ctx context.Context) ([]hoist.Folder, error) {
+	folders, err := c.Client.GetFolders(ctx)
+
+	for i := range folders {
+		if name, ok := c.names[folders[i].Path]; ok {
+			folders[i].Path = name
+		}
+
+		for j := range folders[i].Files {
+			if name, ok := c.names[folders[i].Files[j].Name]; ok {
+				folders[i].Files[j].Name = name
+			}
+		}
+	}
+
+	return folders, err
+}
+
+var _ = Describe("Fake client tests", func() {
+	var client *hoisttest.Client
+	var ctx context.Context
+
+	BeforeEach(func() {
+		client = hoisttest.NewClient()
+		ctx = context.Background()
+	})
+
+	It("Should upload and download files", func() {
+		_, err := client.CreateFolder(ctx, "/docs")
+
+		Expect(err).To(BeNil())
+
+		file, err := client.ChunkedUpload(ctx, bytes.NewReader([]byte("hello world")), "/docs/hello.txt", 11)
+
+		Expect(err).To(BeNil())
+		Expect(file.FolderPath).To(Equal("/docs"))
+
+		r, err := client.DownloadFile(ctx, file.ID)
+
+		Expect(err).To(BeNil())
+
+		data, err := io.ReadAll(r)
+
+		Expect(err).To(BeNil())
+		Expect(string(data)).To(Equal("hello world"))
+
+		r, err = client.DownloadFile(ctx, file.ID, hoist.WithHeader("Range", "bytes=6-10"))
+
+		Expect(err).To(BeNil())
+
+		data, err = io.ReadAll(r)
+
+		Expect(err).To(BeNil())
+		Expect(string(data)).To(Equal("world"))
+	})
+	It("Should reject single request uploads larger than the chunk size", func() {
+		client.Caps.MaxChunkSize = 4
+
+		_, err := client.Upload(ctx, bytes.NewReader([]byte("hello world")), "/hello.txt", 11)
+
+		Expect(err).To(MatchError(hoist.ErrUploadTooLarge))
+		Expect(client.Folder("/").Files).To(BeEmpty())
+
+		_, err = client.Upload(ctx, bytes.NewReader([]byte("hey")), "/hey.txt", 3)
+
+		Expect(err).To(BeNil())
+	})
+	It("Should find files and folders", func() {
+		client.AddFile("/a/b/file.txt", []byte("data"))
+
+		folder, file, err := client.Find(ctx, "/a/b")
+
+		Expect(err).To(BeNil())
+		Expect(file).To(BeNil())
+		Expect(folder.Path).To(Equal("/a/b"))
+
+		folder, file, err = client.Find(ctx, "/a/b/file.txt")
+
+		Expect(err).To(BeNil())
+		Expect(folder).To(BeNil())
+		Expect(file.Name).To(Equal("file.txt"))
+
+		_, _, err = client.Find(ctx, "/a/missing")
+
+		Expect(err).To(MatchError(hoist.ErrNoFile))
+	})
+	It("Should move and rename folders, updating paths", func() {
+		file := client.AddFile("/a/b/file.txt", []byte("data"))
+		client.AddFolder("/c")
+
+		Expect(client.MoveFolder(ctx, "/a/b", "/c", "d")).To(Succeed())
+
+		files, err := client.GetFiles(ctx, file.ID)
+
+		Expect(err).To(BeNil())
+		Expect(files[0].FolderPath).To(Equal("/c/d"))
+		Expect(client.Folder("/a/b")).To(BeNil())
+		Expect(client.MoveFolder(ctx, "/c", "/c/d", "e")).ToNot(Succeed())
+	})
+	It("Should return moved files with their new folder", func() {
+		file := client.AddFile("/a/file.txt", []byte("data"))
+		client.AddFolder("/c")
+
+		files, err := client.MoveFilesResult(ctx, "/c", file.ID)
+
+		Expect(err).To(BeNil())
+		Expect(files).To(HaveLen(1))
+		Expect(files[0].FolderPath).To(Equal("/c"))
+	})
+	It("Should keep other params when editing multiple files", func() {
+		first := client.AddFile("/a/first.txt", []byte("data"))
+		second := client.AddFile("/a/second.txt", []byte("data"))
+
+		Expect(client.EditFile(ctx, first.ID, hoist.EditFileParams{Password: "one", ShortLink: "s1"})).To(Succeed())
+		Expect(client.EditFile(ctx, second.ID, hoist.EditFileParams{Password: "two", MaxDownloads: 3})).To(Succeed())
+
+		published := true
+
+		_, err := client.EditFiles(ctx, []string{first.ID, second.ID}, hoist.EditFilePatch{Published: &published})
+
+		Expect(err).To(BeNil())
+
+		params, _ := client.Params(first.ID)
+
+		Expect(params).To(Equal(hoist.EditFileParams{Password: "one", ShortLink: "s1", Published: true}))
+
+		params, _ = client.Params(second.ID)
+
+		Expect(params).To(Equal(hoist.EditFileParams{Password: "two", MaxDownloads: 3, Published: true}))
+	})
+	It("Should only edit files at the expected version", func() {
+		file := client.AddFile("/a/file.txt", []byte("data"))
+
+		Expect(file.Version).To(Equal("1"))
+		Expect(client.EditFileIfMatch(ctx, file.ID, "1", hoist.EditFileParams{Published: true})).To(Succeed())
+
+		err := client.EditFileIfMatch(ctx, file.ID, "1", hoist.EditFileParams{Published: false})
+
+		Expect(errors.Is(err, hoist.ErrConflict)).To(BeTrue())
+
+		files, err := client.GetFiles(ctx, file.ID)
+
+		Expect(err).To(BeNil())
+		Expect(files[0].Version).To(Equal("2"))
+		Expect(client.EditFileIfMatch(ctx, file.ID, files[0].Version, hoist.EditFileParams{Published: false})).To(Succeed())
+	})
+	It("Should delete files and folders", func() {
+		file := client.AddFile("/a/file.txt", []byte("data"))
+
+		Expect(client.DeleteFiles(ctx, file.ID)).To(Succeed())
+
+		_, ok := client.Data(file.ID)
+
+		Expect(ok).To(BeFalse())
+		Expect(client.DeleteFolder(ctx, "/a")).To(Succeed())
+		Expect(client.Folder("/a")).To(BeNil())
+		Expect(client.DeleteFolder(ctx, "/")).To(MatchError(hoist.ErrRootOperation))
+		Expect(client.MoveFolder(ctx, "/", "/b", "")).To(MatchError(hoist.ErrRootOperation))
+
+		client.AddFolder("/c/d")
+
+		Expect(client.MoveFolder(ctx, "/c", "/c/d", "c")).To(MatchError(hoist.ErrInvalidMove))
+	})
+	It("Should refuse to download files with names escaping the local directory", func() {
+		client.AddFile("/docs/safe.txt", []byte("safe"))
+		client.AddFile("/docs/evil.txt", []byte("evil"))
+		client.AddFile("/docs/sub/nested.txt", []byte("nested"))
+
+		renaming := &renamingClient{Client: client, names: map[string]string{
+			"evil.txt":  "../../escaped.txt",
+			"/docs/sub": `/docs/..\escaped`,
+		}}
+
+		parent := GinkgoT().TempDir()
+		dir := filepath.Join(parent, "a", "b")
+
+		files, failed := hoist.DownloadDir(ctx, renaming, "/docs", dir)
+
+		Expect(files).To(HaveLen(1))
+		Expect(files).To(HaveKey("safe.txt"))
+		Expect(failed).To(HaveLen(2))
+
+		for _, err := range failed {
+			Expect(err).To(MatchError(hoist.ErrUnsafeName))
+		}
+
+		_, err := os.Stat(filepath.Join(parent, "escaped.txt"))
+
+		Expect(os.IsNotExist(err)).To(BeTrue())
+
+		entries, err := os.ReadDir(parent)
+
+		Expect(err).To(BeNil())
+		Expect(entries).To(HaveLen(1))
+	})
+	It("Should create missing folders, rejecting file collisions", func() {
+		client.AddFile("/a/file.txt", []byte("data"))
+
+		folder, err := client.MkdirAll(ctx, "/a/b/c")
+
+		Expect(err).To(BeNil())
+		Expect(folder.Path).To(Equal("/a/b/c"))
+		Expect(client.Folder("/a/b/c")).ToNot(BeNil())
+
+		_, err = client.MkdirAll(ctx, "/a/file.txt/d")
+
+		Expect(err).To(MatchError(hoist.ErrNotDir))
+	})
+	It("Should leave or roll back a partial tree when MkdirAll fails", func() {
+		failing := &failingCreateClient{Client: client, path: "/a/b/c/d"}
+
+		client.AddFolder("/a")
+
+		_, err := hoist.MkdirAll(ctx, failing, "/a/b/c/d/e")
+
+		Expect(err).To(MatchError("create failed"))
+		Expect(client.Folder("/a/b/c")).ToNot(BeNil())
+
+		Expect(client.DeleteFolder(ctx, "/a/b")).To(Succeed())
+
+		_, err = hoist.MkdirAll(ctx, failing, "/a/b/c/d/e", hoist.WithMkdirRollback())
+
+		Expect(err).To(MatchError("create failed"))
+		Expect(client.Folder("/a/b")).To(BeNil())
+
+		// Folders which existed before the call are kept
+		Expect(client.Folder("/a")).ToNot(BeNil())
+	})
+	It("Should roll back a partial tree when MkdirAll is cancelled", func() {
+		cancelCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		failing := &failingCreateClient{Client: client, path: "/a/b/c", cancel: cancel}
+
+		_, err := hoist.MkdirAll(cancelCtx, failing, "/a/b/c/d", hoist.WithMkdirRollback())
+
+		Expect(err).To(MatchError(context.Canceled))
+		Expect(client.Folder("/a")).To(BeNil())
+	})
+	It("Should upload a local directory", func() {
+		dir := GinkgoT().TempDir()
+
+		Expect(os.MkdirAll(filepath.Join(dir, "sub", "skip"), 0o755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "root.txt"), []byte("root"), 0o644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "sub", "nested.txt"), []byte("nested"), 0o644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "sub", "ignored.log"), []byte("log"), 0o644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "sub", "skip", "file.txt"), []byte("skip"), 0o644)).To(Succeed())
+
+		files, failed := client.UploadDir(ctx, dir, "/backup", hoist.WithExclude("*.log", "skip"))
+
+		Expect(failed).To(BeEmpty())
+		Expect(files).To(HaveLen(2))
+		Expect(files).To(HaveKey("root.txt"))
+		Expect(files["sub/nested.txt"].FolderPath).To(Equal("/backup/sub"))
+		Expect(client.Folder("/backup/sub/skip")).To(BeNil())
+
+		data, ok := client.Data(files["sub/nested.txt"].ID)
+
+		Expect(ok).To(BeTrue())
+		Expect(string(data)).To(Equal("nested"))
+
+		again, failed := client.UploadDir(ctx, dir, "/backup", hoist.WithInclude("*.txt"), hoist.WithExclude("skip"), hoist.WithSkipUnchanged())
+
+		Expect(failed).To(BeEmpty())
+		Expect(again["root.txt"].ID).To(Equal(files["root.txt"].ID))
+		Expect(again["sub/nested.txt"].ID).To(Equal(files["sub/nested.txt"].ID))
+	})
+	It("Should write a byte slice, creating parents when requested", func() {
+		_, err := client.WriteFile(ctx, "/a/b/config.json", []byte("{}"))
+
+		Expect(err).To(MatchError(hoist.ErrNoFolder))
+
+		file, err := client.WriteFile(ctx, "/a/b/config.json", []byte("{}"), hoist.WithCreateParents())
+
+		Expect(err).To(BeNil())
+		Expect(file.FolderPath).To(Equal("/a/b"))
+
+		data, ok := client.Data(file.ID)
+
+		Expect(ok).To(BeTrue())
+		Expect(string(data)).To(Equal("{}"))
+	})
+	It("Should store chat files separately from file storage", func() {
+		file, err := client.UploadChatFile(ctx, bytes.NewReader([]byte("hello")), "conv-1", "hello.txt", 5)
+
+		Expect(err).To(BeNil())
+		Expect(client.ChatFiles("conv-1")).To(ConsistOf(*file))
+		Expect(client.Folder("/").Files).To(BeEmpty())
+
+		usage, err := client.DiskUsageSummary(ctx)
+
+		Expect(err).To(BeNil())
+		Expect(usage.ChatFiles).To(Equal(int64(5)))
+		Expect(usage.FileStorage).To(BeZero())
+
+		_, err = client.UploadChatFile(ctx, bytes.NewReader([]byte("hello")), "", "hello.txt", 5)
+
+		Expect(err).To(MatchError(hoist.ErrInvalidConversationID))
+	})
+	It("Should classify local and remote differences for sync", func() {
+		dir := GinkgoT().TempDir()
+
+		old := time.Now().Add(-time.Hour)
+		newer := time.Now().Add(time.Hour)
+
+		local := map[string]string{
+			"same.txt":           "same",
+			"local-only.txt":     "local",
+			"sub/newer.txt":      "local changes",
+			"sub/older.txt":      "local changes",
+			"deleted-remote.txt": "synced",
+		}
+
+		for name, data := range local {
+			p := filepath.Join(dir, filepath.FromSlash(name))
+
+			Expect(os.MkdirAll(filepath.Dir(p), 0755)).To(Succeed())
+			Expect(os.WriteFile(p, []byte(data), 0644)).To(Succeed())
+			Expect(os.Chtimes(p, old, old)).To(Succeed())
+		}
+
+		Expect(os.Chtimes(filepath.Join(dir, "sub", "newer.txt"), newer, newer)).To(Succeed())
+
+		client.AddFile("/root/same.txt", []byte("same"))
+		client.AddFile("/root/remote-only.txt", []byte("remote"))
+		client.AddFile("/root/sub/newer.txt", []byte("remote"))
+		client.AddFile("/root/sub/older.txt", []byte("remote"))
+		client.AddFile("/root/deleted-local.txt", []byte("synced"))
+		client.AddFile("/other/ignored.txt", []byte("ignored"))
+
+		toUpload, toDownload, toDelete, err := client.Diff(ctx, dir, "/root")
+
+		Expect(err).To(BeNil())
+		Expect(toUpload).To(Equal([]string{"deleted-remote.txt", "local-only.txt", "sub/newer.txt"}))
+		Expect(toDownload).To(Equal([]string{"deleted-local.txt", "remote-only.txt", "sub/older.txt"}))
+		Expect(toDelete).To(BeEmpty())
+
+		// With a baseline, files synced before but now missing on one side were deleted there
+		deletedLocal, err := client.GetFileByPath(ctx, "/root/deleted-local.txt")
+
+		Expect(err).To(BeNil())
+
+		baseline := []hoist.FileEntry{
+			{Path: "/root/same.txt", Size: 4},
+			{Path: "/root/deleted-local.txt", Size: 6, DateAdded: deletedLocal.DateAdded},
+			{Path: "/root/deleted-remote.txt", Size: 6, DateAdded: time.Now()},
+		}
+
+		toUpload, toDownload, toDelete, err = client.Diff(ctx, dir, "/root", hoist.WithDiffBaseline(baseline))
+
+		Expect(err).To(BeNil())
+		Expect(toUpload).To(Equal([]string{"local-only.txt", "sub/newer.txt"}))
+		Expect(toDownload).To(Equal([]string{"remote-only.txt", "sub/older.txt"}))
+		Expect(toDelete).To(Equal([]string{"deleted-local.txt", "deleted-remote.txt"}))
+
+		// Files changed since the baseline are transferred rather than deleted
+		Expect(os.WriteFile(filepath.Join(dir, "deleted-remote.txt"), []byte("edited locally"), 0644)).To(Succeed())
+
+		client.AddFile("/root/deleted-local.txt", []byte("edited"))
+
+		toUpload, toDownload, toDelete, err = client.Diff(ctx, dir, "/root", hoist.WithDiffBaseline(baseline))
+
+		Expect(err).To(BeNil())
+		Expect(toUpload).To(Equal([]string{"deleted-remote.txt", "local-only.txt", "sub/newer.txt"}))
+		Expect(toDownload).To(Equal([]string{"deleted-local.txt", "remote-only.txt", "sub/older.txt"}))
+		Expect(toDelete).To(BeEmpty())
+
+		_, _, _, err = client.Diff(ctx, dir, "/missing")
+
+		Expect(err).To(MatchError(hoist.ErrNoFolder))
+	})
+})
@@ -0,0 +1,13 @@
+package hoisttest_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestHoisttest(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Hoisttest Suite")
+}
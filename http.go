@@ -2,8 +2,10 @@ package hoist
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -72,7 +74,101 @@ func WithURLParameter(key string, value any) RequestOpt {
 	}
 }
 
+// WithGzipBody compresses JSON request bodies larger than minSize bytes, setting Content-Encoding accordingly.
+// Only use this when the backend accepts gzip encoded requests.
+func WithGzipBody(minSize int) RequestOpt {
+	return func(r *http.Request) {
+		if r.Body == nil || r.GetBody == nil || r.ContentLength <= int64(minSize) {
+			return
+		}
+
+		if r.Header.Get("Content-Type") != "application/json" || r.Header.Get("Content-Encoding") != "" {
+			return
+		}
+
+		body, err := r.GetBody()
+
+		if err != nil {
+			return
+		}
+
+		var buf bytes.Buffer
+
+		gz := gzip.NewWriter(&buf)
+
+		if _, err := io.Copy(gz, body); err != nil {
+			return
+		}
+
+		if err := gz.Close(); err != nil {
+			return
+		}
+
+		compressed := buf.Bytes()
+
+		r.Body = io.NopCloser(bytes.NewReader(compressed))
+		r.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(compressed)), nil
+		}
+		r.ContentLength = int64(len(compressed))
+		r.Header.Set("Content-Encoding", "gzip")
+	}
+}
+
+// gzipBody wraps a gzip encoded response body, closing both the gzip reader and the underlying body
+type gzipBody struct {
+	*gzip.Reader
+	body io.ReadCloser
+}
+
+func (g *gzipBody) Close() error {
+	_ = g.Reader.Close()
+
+	return g.body.Close()
+}
+
+// redactURL hides credentials and query values in u so it's safe to include in errors and logs
+func redactURL(u string) string {
+	parsed, err := url.Parse(u)
+
+	if err != nil {
+		// Fall back to dropping anything which might contain secrets
+		u, _, _ = strings.Cut(u, "?")
+
+		if i := strings.LastIndex(u, "@"); i >= 0 {
+			scheme, _, _ := strings.Cut(u, "://")
+
+			return scheme + "://REDACTED" + u[i:]
+		}
+
+		return u
+	}
+
+	if parsed.RawQuery != "" {
+		query := parsed.Query()
+
+		for key := range query {
+			query.Set(key, "REDACTED")
+		}
+
+		parsed.RawQuery = query.Encode()
+	}
+
+	return parsed.Redacted()
+}
+
 func doHttpRequest(ctx context.Context, client *http.Client, method, u string, body any, opts ...RequestOpt) (*Response, error) {
+	req, err := newHttpRequest(ctx, method, u, body, opts...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return sendHttpRequest(client, req)
+}
+
+// newHttpRequest creates a request, encoding body based on its type and applying opts
+func newHttpRequest(ctx context.Context, method, u string, body any, opts ...RequestOpt) (*http.Request, error) {
 	var bodyReader io.Reader
 	var jsonBody bool
 
@@ -109,7 +205,14 @@ func doHttpRequest(ctx context.Context, client *http.Client, method, u string, b
 	req, err := http.NewRequestWithContext(ctx, method, u, bodyReader)
 
 	if err != nil {
-		return nil, fmt.Errorf("failed to create rmdir request: %w", err)
+		// The url.Error repeats the unredacted URL, so only keep the underlying cause
+		var urlErr *url.Error
+
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+
+		return nil, fmt.Errorf("failed to create %s %s request: %w", method, redactURL(u), err)
 	}
 
 	if jsonBody {
@@ -121,14 +224,52 @@ func doHttpRequest(ctx context.Context, client *http.Client, method, u string, b
 		opt(req)
 	}
 
+	return req, nil
+}
+
+// sendHttpRequest executes the request, transparently decompressing gzip responses
+func sendHttpRequest(client *http.Client, req *http.Request) (*Response, error) {
 	// Execute the HTTP request
 	resp, err := client.Do(req)
 
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute rmdir request: %w", err)
+		// The url.Error repeats the method and URL, so only keep the underlying cause
+		var urlErr *url.Error
+
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+
+		return nil, &NetworkError{
+			Method: req.Method,
+			URL:    redactURL(req.URL.String()),
+			Err:    err,
+		}
 	}
 
-	return &Response{
+	// The http.Transport only decompresses transparently when it set Accept-Encoding itself,
+	// so handle responses to our own Accept-Encoding header here.
+	if !resp.Uncompressed && strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		gz, err := gzip.NewReader(resp.Body)
+
+		if err != nil {
+			_ = resp.Body.Close()
+			return nil, fmt.Errorf("failed to read gzip response: %w", err)
+		}
+
+		resp.Body = &gzipBody{Reader: gz, body: resp.Body}
+		resp.Header.Del("Content-Encoding")
+		resp.Header.Del("Content-Length")
+		resp.ContentLength = -1
+	}
+
+	res := &Response{
 		Response: resp,
-	}, err
+	}
+
+	if err := captureRawResponse(req.Context(), res); err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	return res, nil
 }
@@ -0,0 +1,93 @@
+// Package timestamp parses the timestamp formats sent by the API, shared by the hoist and events packages
+// so every timestamp decodes the same way.
+package timestamp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// millisThreshold separates Unix seconds from Unix milliseconds. In seconds it's in the year 5138,
+// while in milliseconds it's early 1973, so any current timestamp is unambiguous.
+const millisThreshold = 1e11
+
+// layouts are tried in order for string timestamps. Timestamps without a zone are treated as UTC.
+var layouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999",
+}
+
+// Parse parses an RFC3339 timestamp (with or without a zone), or Unix seconds or milliseconds as a string.
+// An empty string is the zero time.
+func Parse(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+
+	if s == "" {
+		return time.Time{}, nil
+	}
+
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return unix(n), nil
+	}
+
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid timestamp: %q", s)
+}
+
+// UnmarshalJSON decodes a timestamp from a JSON string or number, with null as the zero time
+func UnmarshalJSON(data []byte) (time.Time, error) {
+	data = bytes.TrimSpace(data)
+
+	if len(data) == 0 || bytes.Equal(data, []byte("null")) {
+		return time.Time{}, nil
+	}
+
+	if data[0] == '"' {
+		var s string
+
+		if err := json.Unmarshal(data, &s); err != nil {
+			return time.Time{}, err
+		}
+
+		return Parse(s)
+	}
+
+	return Parse(string(data))
+}
+
+// Time is a time.Time which decodes from any format supported by UnmarshalJSON, for use as a JSON field
+type Time struct {
+	time.Time
+}
+
+// UnmarshalJSON decodes a timestamp from a JSON string or number, with null as the zero time
+func (t *Time) UnmarshalJSON(data []byte) error {
+	parsed, err := UnmarshalJSON(data)
+
+	if err != nil {
+		return err
+	}
+
+	t.Time = parsed
+
+	return nil
+}
+
+// unix converts Unix seconds or milliseconds to a UTC time
+func unix(n int64) time.Time {
+	if n >= millisThreshold || n <= -millisThreshold {
+		return time.UnixMilli(n).UTC()
+	}
+
+	return time.Unix(n, 0).UTC()
+}
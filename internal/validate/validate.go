@@ -0,0 +1,89 @@
+// Package validate checks request arguments before anything is sent, shared by the hoist client and the hoisttest
+// fake so both reject the same input. The errors are exported by hoist for callers to match with errors.Is.
+package validate
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"unicode"
+)
+
+// maxConversationID is the longest conversation ID accepted for chat files
+const maxConversationID = 128
+
+var (
+	ErrUploadTooLarge        = errors.New("file is larger than a single upload chunk")
+	ErrInvalidConversationID = errors.New("invalid conversation id")
+	ErrInvalidFolderName     = errors.New("invalid folder name")
+	ErrInvalidMove           = errors.New("cannot move a folder into itself")
+	ErrInvalidResume         = errors.New("invalid upload resume point")
+)
+
+// Upload checks that a file of fileSize bytes fits in the single chunk of a one-request upload, given the chunkSize
+func Upload(fileSize, chunkSize int64) error {
+	if fileSize > chunkSize {
+		return fmt.Errorf("%w: %d bytes, chunk size %d", ErrUploadTooLarge, fileSize, chunkSize)
+	}
+
+	return nil
+}
+
+// ConversationID checks the conversation ID is non-empty, at most 128 characters, and has no whitespace, control
+// characters or slashes, returning ErrInvalidConversationID otherwise
+func ConversationID(conversationID string) error {
+	if conversationID == "" || len(conversationID) > maxConversationID {
+		return fmt.Errorf("%w: %q", ErrInvalidConversationID, conversationID)
+	}
+
+	for _, r := range conversationID {
+		if unicode.IsSpace(r) || unicode.IsControl(r) || r == '/' || r == '\\' {
+			return fmt.Errorf("%w: %q", ErrInvalidConversationID, conversationID)
+		}
+	}
+
+	return nil
+}
+
+// FolderName checks name is usable as a single folder name, returning ErrInvalidFolderName if it's empty, "." or
+// "..", or contains a slash or NUL character
+func FolderName(name string) error {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, "/\x00") {
+		return fmt.Errorf("%w: %q", ErrInvalidFolderName, name)
+	}
+
+	return nil
+}
+
+// Move checks that newParentFolder isn't folder itself or one of its subfolders, which would create a cycle,
+// returning ErrInvalidMove if it is. An empty newParentFolder (a rename in place) is always valid.
+func Move(folder, newParentFolder string) error {
+	if newParentFolder == "" {
+		return nil
+	}
+
+	source := path.Clean("/" + strings.ReplaceAll(folder, "\\", "/"))
+	target := path.Clean("/" + strings.ReplaceAll(newParentFolder, "\\", "/"))
+
+	if target == source || strings.HasPrefix(target, strings.TrimSuffix(source, "/")+"/") {
+		return fmt.Errorf("%w: %s into %s", ErrInvalidMove, source, target)
+	}
+
+	return nil
+}
+
+// Resume checks that an upload of totalChunks chunks can be resumed at startChunk, the zero-based index of the first
+// chunk to send (the number of chunks already accepted). The final chunk is always sent, as it's what triggers the
+// server to combine the file.
+func Resume(identifier string, startChunk, totalChunks int) error {
+	if identifier == "" {
+		return fmt.Errorf("%w: missing identifier", ErrInvalidResume)
+	}
+
+	if startChunk < 0 || startChunk >= totalChunks {
+		return fmt.Errorf("%w: chunk %d of %d", ErrInvalidResume, startChunk, totalChunks)
+	}
+
+	return nil
+}
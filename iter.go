@@ -0,0 +1,58 @@
+package hoist
+
+import (
+	"context"
+	"iter"
+)
+
+// filesPageSize is how many files Files requests per page
+const filesPageSize = 500
+
+// Files iterates the files of a folder, fetching pages lazily as the loop advances.
+// Breaking out of the loop stops any further requests. If a page fails, the error is yielded once and iteration ends.
+func (c *client) Files(ctx context.Context, folder string) iter.Seq2[File, error] {
+	return func(yield func(File, error) bool) {
+		for start := 0; ; start += filesPageSize {
+			page, err := c.GetFolder(ctx, folder, WithStartIndex(start), WithCount(filesPageSize))
+
+			if err != nil {
+				yield(File{}, err)
+				return
+			}
+
+			for _, file := range page.Files {
+				if file.FolderPath == "" {
+					file.FolderPath = page.Path
+				}
+
+				if !yield(file, nil) {
+					return
+				}
+			}
+
+			// A short page is the last, as is reaching the folder's reported file count
+			if len(page.Files) < filesPageSize || (page.Count > 0 && start+len(page.Files) >= page.Count) {
+				return
+			}
+		}
+	}
+}
+
+// Folders iterates all folders depth first, as returned by GetFolders.
+// The tree is fetched once when iteration starts; if that fails, the error is yielded once.
+func (c *client) Folders(ctx context.Context) iter.Seq2[Folder, error] {
+	return func(yield func(Folder, error) bool) {
+		folders, err := c.GetFolders(ctx)
+
+		if err != nil {
+			yield(Folder{}, err)
+			return
+		}
+
+		for _, folder := range folders {
+			if !yield(folder, nil) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package hoist
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Iterator tests", func() {
+	const total = 1200
+
+	var requests []folderRequest
+
+	pagedServer := func() http.Handler {
+		requests = nil
+
+		mux := http.NewServeMux()
+
+		mux.HandleFunc(testPath(apiFolder), func(w http.ResponseWriter, r *http.Request) {
+			var req folderRequest
+
+			_ = json.NewDecoder(r.Body).Decode(&req)
+
+			requests = append(requests, req)
+
+			folder := Folder{Path: req.Folder, Count: total}
+
+			for i := *req.StartIndex; i < total && i < *req.StartIndex+*req.Count; i++ {
+				folder.Files = append(folder.Files, File{ID: strconv.Itoa(i)})
+			}
+
+			_ = json.NewEncoder(w).Encode(FolderResponse{
+				defaultResponse: defaultResponse{Success: true},
+				Folder:          folder,
+			})
+		})
+
+		mux.HandleFunc(testPath(apiFolders), func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewEncoder(w).Encode(FolderResponse{
+				defaultResponse: defaultResponse{Success: true},
+				Folder:          testTree(),
+			})
+		})
+
+		return mux
+	}
+
+	It("Should fetch every page of files", func() {
+		c, server := newTestClient(pagedServer())
+		defer server.Close()
+
+		var ids []string
+
+		for file, err := range c.Files(context.Background(), "/docs") {
+			Expect(err).To(BeNil())
+			Expect(file.FolderPath).To(Equal("/docs"))
+
+			ids = append(ids, file.ID)
+		}
+
+		Expect(ids).To(HaveLen(total))
+		Expect(ids[total-1]).To(Equal(strconv.Itoa(total - 1)))
+		Expect(requests).To(HaveLen(3))
+	})
+	It("Should stop fetching when the loop breaks", func() {
+		c, server := newTestClient(pagedServer())
+		defer server.Close()
+
+		count := 0
+
+		for range c.Files(context.Background(), "/docs") {
+			count++
+
+			if count == 10 {
+				break
+			}
+		}
+
+		Expect(count).To(Equal(10))
+		Expect(requests).To(HaveLen(1))
+	})
+	It("Should yield request errors", func() {
+		c, server := newTestClient(http.NotFoundHandler())
+		defer server.Close()
+
+		var errs []error
+
+		for _, err := range c.Files(context.Background(), "/docs") {
+			errs = append(errs, err)
+		}
+
+		Expect(errs).To(HaveLen(1))
+		Expect(errors.Is(errs[0], ErrUnexpectedStatus)).To(BeTrue())
+	})
+	It("Should iterate folders", func() {
+		c, server := newTestClient(pagedServer())
+		defer server.Close()
+
+		var paths []string
+
+		for folder, err := range c.Folders(context.Background()) {
+			Expect(err).To(BeNil())
+
+			paths = append(paths, folder.Path)
+		}
+
+		Expect(paths).To(Equal([]string{"/", "/a", "/a/b"}))
+	})
+})
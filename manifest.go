@@ -0,0 +1,70 @@
+package hoist
+
+import (
+	"context"
+	"path"
+	"sort"
+	"time"
+)
+
+// FileEntry is a single file in a Manifest
+type FileEntry struct {
+	Path      string    `json:"path"`
+	ID        string    `json:"id"`
+	Size      int64     `json:"size"`
+	DateAdded time.Time `json:"dateAdded"`
+}
+
+// Manifest returns every file below root as a flat list of entries with absolute paths, sorted by path
+func (c *client) Manifest(ctx context.Context, root string) ([]FileEntry, error) {
+	folder, err := c.folderTree(ctx, root)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return BuildManifest(*folder), nil
+}
+
+// BuildManifest creates a manifest from an already loaded folder tree
+func BuildManifest(root Folder) []FileEntry {
+	var entries []FileEntry
+
+	_ = root.WalkFolders(func(folder Folder, depth int) error {
+		for _, file := range folder.Files {
+			entries = append(entries, FileEntry{
+				Path:      path.Join("/", folder.Path, file.Name),
+				ID:        file.ID,
+				Size:      file.Size,
+				DateAdded: file.DateAdded,
+			})
+		}
+
+		return nil
+	})
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Path < entries[j].Path
+	})
+
+	return entries
+}
+
+// folderTree returns the folder at p, using the root folder from GetFolders for "" or "/"
+func (c *client) folderTree(ctx context.Context, p string) (*Folder, error) {
+	if p == "" || p == "/" {
+		folders, err := c.GetFolders(ctx)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if len(folders) == 0 {
+			return nil, ErrNoFolder
+		}
+
+		return &folders[0], nil
+	}
+
+	return c.GetFolder(ctx, p)
+}
@@ -0,0 +1,64 @@
+package hoist
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Manifest tests", func() {
+	It("Should build a complete manifest sorted by path", func() {
+		tree := testTree()
+		tree.Subfolders[0].Files = append(tree.Subfolders[0].Files, File{ID: "4", Name: "0.txt", Size: 10})
+
+		mux := http.NewServeMux()
+
+		mux.HandleFunc(testPath(apiFolders), func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewEncoder(w).Encode(FolderResponse{
+				defaultResponse: defaultResponse{Success: true},
+				Folder:          tree,
+			})
+		})
+
+		c, server := newTestClient(mux)
+		defer server.Close()
+
+		entries, err := c.Manifest(context.Background(), "/")
+
+		Expect(err).To(BeNil())
+
+		var paths []string
+
+		for _, entry := range entries {
+			paths = append(paths, entry.Path)
+		}
+
+		Expect(paths).To(Equal([]string{"/a/0.txt", "/a/a.txt", "/a/b/b.txt", "/root.txt"}))
+		Expect(entries[0].ID).To(Equal("4"))
+		Expect(entries[0].Size).To(Equal(int64(10)))
+	})
+	It("Should build a manifest of a subfolder", func() {
+		tree := testTree()
+
+		mux := http.NewServeMux()
+
+		mux.HandleFunc(testPath(apiFolder), func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewEncoder(w).Encode(FolderResponse{
+				defaultResponse: defaultResponse{Success: true},
+				Folder:          tree.Subfolders[0],
+			})
+		})
+
+		c, server := newTestClient(mux)
+		defer server.Close()
+
+		entries, err := c.Manifest(context.Background(), "/a")
+
+		Expect(err).To(BeNil())
+		Expect(entries).To(HaveLen(2))
+		Expect(entries[1].Path).To(Equal("/a/b/b.txt"))
+	})
+})
@@ -0,0 +1,133 @@
+package hoist
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// rollbackTimeout bounds the cleanup of WithMkdirRollback, which runs even when the MkdirAll context is done
+const rollbackTimeout = 30 * time.Second
+
+// mkdirOptions controls how MkdirAll handles failures
+type mkdirOptions struct {
+	rollback bool
+}
+
+// MkdirOpt allows defining options for MkdirAll
+type MkdirOpt func(o *mkdirOptions)
+
+// WithMkdirRollback deletes the folders created by the call when a later segment fails, instead of leaving the
+// partial tree in place like os.MkdirAll. Folders which already existed are never removed. The rollback is best
+// effort: folders which fail to delete are logged, and the original error is returned either way. It also runs when
+// the failure is a cancelled or expired context, with its own short timeout.
+func WithMkdirRollback() MkdirOpt {
+	return func(o *mkdirOptions) {
+		o.rollback = true
+	}
+}
+
+// MkdirAll creates the folder and any missing parents, returning the folder.
+// If any segment of the path is an existing file, ErrNotDir is returned.
+func (c *client) MkdirAll(ctx context.Context, folder string, opts ...MkdirOpt) (*Folder, error) {
+	return MkdirAll(ctx, c, folder, opts...)
+}
+
+// MkdirAll creates the folder and any missing parents using c, returning the folder.
+// If any segment of the path is an existing file, ErrNotDir is returned.
+// By default the folders created before a failure are left in place, see WithMkdirRollback.
+func MkdirAll(ctx context.Context, c FileClient, folder string, opts ...MkdirOpt) (*Folder, error) {
+	var o mkdirOptions
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	folders, err := c.GetFolders(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(folders) == 0 {
+		return nil, ErrNoFolder
+	}
+
+	root := folders[0]
+
+	created, createdPaths, err := mkdirAllTree(ctx, c, &root, folder)
+
+	if err != nil && o.rollback {
+		rollbackFolders(ctx, c, createdPaths)
+	}
+
+	return created, err
+}
+
+// mkdirAllTree creates any segments of folder missing from the loaded tree under root, adding created folders to
+// the tree so it can be reused for subsequent calls. The paths of the folders it created are returned, parents first,
+// including when it fails.
+func mkdirAllTree(ctx context.Context, c FileClient, root *Folder, folder string) (*Folder, []string, error) {
+	current := root
+
+	var createdPaths []string
+
+	for _, segment := range strings.Split(strings.Trim(path.Clean("/"+folder), "/"), "/") {
+		if segment == "" {
+			continue
+		}
+
+		var next *Folder
+
+		for i := range current.Subfolders {
+			if current.Subfolders[i].Name == segment {
+				next = &current.Subfolders[i]
+				break
+			}
+		}
+
+		if next != nil {
+			current = next
+			continue
+		}
+
+		segmentPath := path.Join("/", current.Path, segment)
+
+		for _, file := range current.Files {
+			if file.Name == segment {
+				return nil, createdPaths, fmt.Errorf("%w: %s", ErrNotDir, segmentPath)
+			}
+		}
+
+		created, err := c.CreateFolder(ctx, segmentPath)
+
+		if err != nil {
+			return nil, createdPaths, err
+		}
+
+		createdPaths = append(createdPaths, segmentPath)
+
+		current.Subfolders = append(current.Subfolders, *created)
+
+		current = &current.Subfolders[len(current.Subfolders)-1]
+	}
+
+	return current, createdPaths, nil
+}
+
+// rollbackFolders deletes the created folders, deepest first. It ignores the cancellation of ctx, as the failure being
+// rolled back is often ctx ending, and uses rollbackTimeout instead.
+func rollbackFolders(ctx context.Context, c FileClient, created []string) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), rollbackTimeout)
+	defer cancel()
+
+	for i := len(created) - 1; i >= 0; i-- {
+		if err := c.DeleteFolder(ctx, created[i]); err != nil {
+			log.WithError(err).WithField("folder", created[i]).Warning("Failed to roll back created folder")
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package hoist
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+type modifiedFilesResponse struct {
+	defaultResponse
+	Files []File `json:"files"`
+}
+
+// FilesModifiedSince returns every file added or modified at or after since, with FolderPath populated, for
+// incremental syncs. The boundary is inclusive so a file stamped exactly at the previous run's time is repeated
+// rather than missed.
+//
+// Backends with a modified files endpoint filter server-side. Others are handled by walking all files and filtering
+// on DateAdded, which costs a full tree fetch. With WithServerTime, since is shifted onto the server's clock first.
+func (c *client) FilesModifiedSince(ctx context.Context, since time.Time) ([]File, error) {
+	since = c.toServerTime(since)
+
+	res, err := c.doFeatureRequest(ctx, http.MethodGet, apiModifiedFiles, &url.Values{
+		"since": {since.UTC().Format(time.RFC3339Nano)},
+	})
+
+	if errors.Is(err, ErrNotSupported) {
+		return c.walkModified(ctx, since)
+	} else if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return nil, statusError(res)
+	}
+
+	var response modifiedFilesResponse
+
+	if err := res.Decode(&response); err != nil {
+		return nil, err
+	}
+
+	if !response.Success {
+		return nil, failureError("list modified files", res, response.Message)
+	}
+
+	if err := c.fillFolderPaths(ctx, response.Files); err != nil {
+		return nil, err
+	}
+
+	return response.Files, nil
+}
+
+// fillFolderPaths sets FolderPath on files returned without it, looking up their folders with GetFilesWithFields
+func (c *client) fillFolderPaths(ctx context.Context, files []File) error {
+	var missing []string
+
+	for _, file := range files {
+		if file.FolderPath == "" {
+			missing = append(missing, file.ID)
+		}
+	}
+
+	if len(missing) == 0 {
+		return nil
+	}
+
+	found, err := c.GetFilesWithFields(ctx, []string{FileFieldFolderPath}, missing...)
+
+	if err != nil {
+		return fmt.Errorf("failed to find folders of modified files: %w", err)
+	}
+
+	folders := make(map[string]string, len(found))
+
+	for _, file := range found {
+		folders[file.ID] = file.FolderPath
+	}
+
+	for i := range files {
+		if files[i].FolderPath == "" {
+			files[i].FolderPath = folders[files[i].ID]
+		}
+	}
+
+	return nil
+}
+
+// walkModified finds files with a DateAdded at or after since by walking every file
+func (c *client) walkModified(ctx context.Context, since time.Time) ([]File, error) {
+	files, err := c.AllFiles(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return FilterModifiedSince(files, since), nil
+}
+
+// FilterModifiedSince returns the files with a DateAdded at or after since
+func FilterModifiedSince(files []File, since time.Time) []File {
+	modified := make([]File, 0)
+
+	for _, file := range files {
+		if !file.DateAdded.Before(since) {
+			modified = append(modified, file)
+		}
+	}
+
+	return modified
+}
@@ -0,0 +1,102 @@
+package hoist
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Modified files tests", func() {
+	since := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	It("Should filter server-side when supported", func() {
+		var query string
+
+		mux := http.NewServeMux()
+
+		mux.HandleFunc(testPath(apiModifiedFiles), func(w http.ResponseWriter, r *http.Request) {
+			query = r.URL.Query().Get("since")
+
+			_, _ = w.Write([]byte(`{"success":true,"files":[{"id":"1","fileName":"a.txt","folderPath":"/a"}]}`))
+		})
+
+		c, server := newTestClient(mux)
+		defer server.Close()
+
+		files, err := c.FilesModifiedSince(context.Background(), since)
+
+		Expect(err).To(BeNil())
+		Expect(query).To(Equal("2025-06-01T12:00:00Z"))
+		Expect(files).To(HaveLen(1))
+		Expect(files[0].FolderPath).To(Equal("/a"))
+	})
+	It("Should fill in folder paths missing from server-side results", func() {
+		mux := http.NewServeMux()
+
+		mux.HandleFunc(testPath(apiModifiedFiles), func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"success":true,"files":[{"id":"3","fileName":"b.txt"},{"id":"1","fileName":"root.txt","folderPath":"/"}]}`))
+		})
+
+		mux.HandleFunc(testPath(apiFolders), func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewEncoder(w).Encode(FolderResponse{
+				defaultResponse: defaultResponse{Success: true},
+				Folder:          testTree(),
+			})
+		})
+
+		c, server := newTestClient(mux)
+		defer server.Close()
+
+		files, err := c.FilesModifiedSince(context.Background(), since)
+
+		Expect(err).To(BeNil())
+		Expect(files).To(HaveLen(2))
+		Expect(files[0].FolderPath).To(Equal("/a/b"))
+		Expect(files[1].FolderPath).To(Equal("/"))
+	})
+	It("Should walk and filter on DateAdded when unsupported", func() {
+		tree := testTree()
+
+		tree.Files[0].DateAdded = since.Add(-time.Nanosecond)
+		tree.Subfolders[0].Files[0].DateAdded = since
+		tree.Subfolders[0].Subfolders[0].Files[0].DateAdded = since.Add(time.Hour)
+
+		mux := http.NewServeMux()
+
+		mux.HandleFunc(testPath(apiFolders), func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewEncoder(w).Encode(FolderResponse{
+				defaultResponse: defaultResponse{Success: true},
+				Folder:          tree,
+			})
+		})
+
+		c, server := newTestClient(mux)
+		defer server.Close()
+
+		files, err := c.FilesModifiedSince(context.Background(), since)
+
+		Expect(err).To(BeNil())
+		Expect(files).To(HaveLen(2))
+		Expect(files[0].ID).To(Equal("2"))
+		Expect(files[0].FolderPath).To(Equal("/a"))
+		Expect(files[1].ID).To(Equal("3"))
+		Expect(files[1].FolderPath).To(Equal("/a/b"))
+	})
+	It("Should include files exactly at the boundary", func() {
+		files := []File{
+			{ID: "before", DateAdded: since.Add(-time.Nanosecond)},
+			{ID: "at", DateAdded: since},
+			{ID: "unknown"},
+		}
+
+		modified := FilterModifiedSince(files, since)
+
+		Expect(modified).To(HaveLen(1))
+		Expect(modified[0].ID).To(Equal("at"))
+		Expect(FilterModifiedSince(nil, since)).To(BeEmpty())
+	})
+})
@@ -0,0 +1,155 @@
+package hoist
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+)
+
+// File fields which can be requested with WithFields and GetFilesWithFields, matching the API's JSON names.
+// FileFieldID and FileFieldName are always returned, even if not requested.
+const (
+	FileFieldID         = "id"
+	FileFieldName       = "fileName"
+	FileFieldType       = "type"
+	FileFieldSize       = "size"
+	FileFieldDateAdded  = "dateAdded"
+	FileFieldFolderPath = "folderPath"
+)
+
+// projectFields returns fields including the ones which are always returned, or nil if no projection was requested
+func projectFields(fields []string) []string {
+	if len(fields) == 0 {
+		return nil
+	}
+
+	projected := []string{FileFieldID, FileFieldName}
+
+	for _, field := range fields {
+		if !slices.Contains(projected, field) {
+			projected = append(projected, field)
+		}
+	}
+
+	return projected
+}
+
+// WithFields requests only the specified file fields in a folder listing, reducing the payload for large folders.
+// Fields which aren't returned are left zeroed. This is only honored by backends which support field selection,
+// others return full files.
+func WithFields(fields ...string) FolderOpt {
+	return func(f *folderRequest) {
+		f.Fields = projectFields(fields)
+	}
+}
+
+// GetFilesWithFields returns file data of the specified files, requesting only the specified fields (see WithFields).
+// Backends confirmed to lack the files endpoint are handled by findFiles instead.
+func (c *client) GetFilesWithFields(ctx context.Context, fields []string, ids ...string) ([]File, error) {
+	res, err := c.doFeatureRequest(ctx, http.MethodPost, apiFiles, filesRequest{
+		FileIDs: ids,
+		Fields:  projectFields(fields),
+	})
+
+	if errors.Is(err, ErrNotSupported) {
+		return c.findFiles(ctx, projectFields(fields), ids)
+	} else if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return nil, statusError(res)
+	}
+
+	var response ListResponse
+
+	if err := res.Decode(&response); err != nil {
+		return nil, err
+	}
+
+	return response.Files, nil
+}
+
+// findFiles resolves file IDs for backends without the files endpoint. The folder each file was last seen in is
+// remembered, so later lookups only fetch those folders; the folder tree is only walked for IDs which haven't been
+// seen before or have since moved. Files are returned in the order requested, skipping IDs which weren't found,
+// with only the requested fields set.
+func (c *client) findFiles(ctx context.Context, fields []string, ids []string) ([]File, error) {
+	found := make(map[string]File, len(ids))
+	byFolder := make(map[string][]string)
+
+	for _, id := range ids {
+		if folderPath, ok := c.fileFolders.Load(id); ok {
+			byFolder[folderPath.(string)] = append(byFolder[folderPath.(string)], id)
+		}
+	}
+
+	for folderPath, folderIDs := range byFolder {
+		folder, err := c.GetFolder(ctx, folderPath)
+
+		if errors.Is(err, ErrNoFolder) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		for _, file := range folder.Files {
+			if slices.Contains(folderIDs, file.ID) {
+				file.FolderPath = folder.Path
+				found[file.ID] = file
+			}
+		}
+	}
+
+	if slices.ContainsFunc(ids, func(id string) bool { _, ok := found[id]; return !ok }) {
+		all, err := c.AllFiles(ctx)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, file := range all {
+			c.fileFolders.Store(file.ID, file.FolderPath)
+
+			if slices.Contains(ids, file.ID) {
+				found[file.ID] = file
+			}
+		}
+	}
+
+	files := make([]File, 0, len(ids))
+
+	for _, id := range ids {
+		if file, ok := found[id]; ok {
+			files = append(files, projectFile(file, fields))
+		}
+	}
+
+	return files, nil
+}
+
+// projectFile zeroes the fields of file which weren't requested, matching a response projected by the backend.
+// No fields means all of them.
+func projectFile(file File, fields []string) File {
+	if len(fields) == 0 {
+		return file
+	}
+
+	projected := File{ID: file.ID, Name: file.Name}
+
+	for _, field := range fields {
+		switch field {
+		case FileFieldType:
+			projected.Type = file.Type
+		case FileFieldSize:
+			projected.Size = file.Size
+		case FileFieldDateAdded:
+			projected.DateAdded = file.DateAdded
+		case FileFieldFolderPath:
+			projected.FolderPath = file.FolderPath
+		}
+	}
+
+	return projected
+}
@@ -0,0 +1,88 @@
+package hoist
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// WithEnsureRoot creates the root folder with EnsureRoot when GetFolders finds none, such as on brand-new accounts
+func WithEnsureRoot() ClientOption {
+	return func(c *client) {
+		c.ensureRoot = true
+	}
+}
+
+// EnsureRoot returns the root folder, creating it if the account doesn't have one yet
+func (c *client) EnsureRoot(ctx context.Context) (*Folder, error) {
+	root, err := c.getRoot(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if root != nil {
+		return root, nil
+	}
+
+	res, err := c.doRequest(ctx, http.MethodPost, apiPutFolder, folderRequest{
+		Folder: "/",
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return nil, statusError(res)
+	}
+
+	var response FolderResponse
+
+	if err := res.Decode(&response); err != nil {
+		return nil, err
+	}
+
+	if !response.Success {
+		return nil, failureError("create root folder", res, response.Message)
+	}
+
+	// Fetch the root rather than trusting the create response, which may not include the full folder
+	root, err = c.getRoot(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if root == nil {
+		return nil, fmt.Errorf("%w: root folder missing after creation", ErrNoFolder)
+	}
+
+	return root, nil
+}
+
+// getRoot fetches the folder tree, returning a nil root if the account doesn't have one
+func (c *client) getRoot(ctx context.Context) (*Folder, error) {
+	res, err := c.doRequest(ctx, http.MethodGet, apiFolders, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return nil, statusError(res)
+	}
+
+	var response FolderResponse
+
+	if err := res.Decode(&response); err != nil {
+		return nil, err
+	}
+
+	// Accounts without a root folder return an empty folder
+	if response.Folder.Path == "" {
+		return nil, nil
+	}
+
+	return &response.Folder, nil
+}
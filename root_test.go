@@ -0,0 +1,80 @@
+package hoist
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Root folder tests", func() {
+	var created bool
+	var createReq folderRequest
+
+	emptyAccount := func() http.Handler {
+		created = false
+		createReq = folderRequest{}
+
+		mux := http.NewServeMux()
+
+		mux.HandleFunc(testPath(apiFolders), func(w http.ResponseWriter, r *http.Request) {
+			if !created {
+				_ = json.NewEncoder(w).Encode(FolderResponse{defaultResponse: defaultResponse{Success: true}})
+				return
+			}
+
+			_ = json.NewEncoder(w).Encode(FolderResponse{
+				defaultResponse: defaultResponse{Success: true},
+				Folder:          Folder{Path: "/"},
+			})
+		})
+
+		mux.HandleFunc(testPath(apiPutFolder), func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewDecoder(r.Body).Decode(&createReq)
+			created = true
+
+			_ = json.NewEncoder(w).Encode(FolderResponse{defaultResponse: defaultResponse{Success: true}})
+		})
+
+		return mux
+	}
+
+	It("Should return no folders when the account has no root", func() {
+		c, server := newTestClient(emptyAccount())
+		defer server.Close()
+
+		folders, err := c.GetFolders(context.Background())
+
+		Expect(err).To(BeNil())
+		Expect(folders).To(BeEmpty())
+		Expect(created).To(BeFalse())
+
+		_, err = c.GetFileID(context.Background(), "/", "file.txt")
+
+		Expect(err).To(MatchError(ErrNoFolder))
+	})
+	It("Should create the root with EnsureRoot", func() {
+		c, server := newTestClient(emptyAccount())
+		defer server.Close()
+
+		root, err := c.EnsureRoot(context.Background())
+
+		Expect(err).To(BeNil())
+		Expect(root.Path).To(Equal("/"))
+		Expect(created).To(BeTrue())
+		Expect(createReq.Folder).To(Equal("/"))
+	})
+	It("Should create the root lazily with WithEnsureRoot", func() {
+		c, server := newTestClient(emptyAccount(), WithEnsureRoot())
+		defer server.Close()
+
+		folders, err := c.GetFolders(context.Background())
+
+		Expect(err).To(BeNil())
+		Expect(created).To(BeTrue())
+		Expect(folders).To(HaveLen(1))
+		Expect(folders[0].Path).To(Equal("/"))
+	})
+})
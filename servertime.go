@@ -0,0 +1,67 @@
+package hoist
+
+import (
+	"net/http"
+	"time"
+)
+
+// minClockSkew is the smallest skew recorded, as the Date header only has second precision
+const minClockSkew = time.Second
+
+// WithServerTime tracks the server's clock from the Date header of API responses, so expiries such as
+// EditFileParams.PublishedUntil are shifted by the detected skew and land at the intended moment on the server.
+// The skew is available from ClockSkew either way.
+func WithServerTime() ClientOption {
+	return func(c *client) {
+		c.serverTime = true
+	}
+}
+
+// ClockSkew returns how far the server's clock is ahead of the local clock (negative if behind), as last seen in a
+// response Date header. It's zero until a response has been received, or when the clocks are within a second.
+func (c *client) ClockSkew() time.Duration {
+	return time.Duration(c.clockSkew.Load())
+}
+
+// ServerNow returns the current time according to the server's clock, using the detected ClockSkew
+func (c *client) ServerNow() time.Time {
+	return time.Now().Add(c.ClockSkew())
+}
+
+// recordClockSkew updates the clock skew from a response's Date header, received at the local time now
+func (c *client) recordClockSkew(res *http.Response, now time.Time) {
+	date := res.Header.Get("Date")
+
+	if date == "" {
+		return
+	}
+
+	serverTime, err := http.ParseTime(date)
+
+	if err != nil {
+		return
+	}
+
+	skew := serverTime.Sub(now.Truncate(time.Second))
+
+	if skew > -minClockSkew && skew < minClockSkew {
+		skew = 0
+	}
+
+	c.clockSkew.Store(int64(skew))
+}
+
+// serverExpiry shifts a local expiry time onto the server's clock when WithServerTime is enabled
+func (c *client) serverExpiry(t time.Time) time.Time {
+	return c.toServerTime(t)
+}
+
+// toServerTime shifts a local time onto the server's clock by the measured ClockSkew when WithServerTime is enabled.
+// The zero time is returned unchanged.
+func (c *client) toServerTime(t time.Time) time.Time {
+	if !c.serverTime || t.IsZero() {
+		return t
+	}
+
+	return t.Add(c.ClockSkew())
+}
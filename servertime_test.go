@@ -0,0 +1,73 @@
+package hoist
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Server time tests", func() {
+	var sent EditFileParams
+
+	skewedServer := func(skew time.Duration) http.Handler {
+		mux := http.NewServeMux()
+
+		mux.HandleFunc(strings.Replace(testPath(apiEditFile), "{fileId}", "1", 1), func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewDecoder(r.Body).Decode(&sent)
+
+			w.Header().Set("Date", time.Now().Add(skew).UTC().Format(http.TimeFormat))
+			_ = json.NewEncoder(w).Encode(defaultResponse{Success: true})
+		})
+
+		return mux
+	}
+
+	BeforeEach(func() {
+		sent = EditFileParams{}
+	})
+
+	It("Should detect the skew from the Date header", func() {
+		c, server := newTestClient(skewedServer(time.Hour))
+		defer server.Close()
+
+		Expect(c.ClockSkew()).To(BeZero())
+		Expect(c.EditFile(context.Background(), "1", EditFileParams{})).To(Succeed())
+		Expect(c.ClockSkew()).To(BeNumerically("~", time.Hour, 2*time.Second))
+		Expect(c.ServerNow()).To(BeTemporally("~", time.Now().Add(time.Hour), 2*time.Second))
+	})
+	It("Should ignore differences within the Date header precision", func() {
+		c, server := newTestClient(skewedServer(0))
+		defer server.Close()
+
+		Expect(c.EditFile(context.Background(), "1", EditFileParams{})).To(Succeed())
+		Expect(c.ClockSkew()).To(BeZero())
+	})
+	It("Should shift expiries onto the server clock", func() {
+		c, server := newTestClient(skewedServer(-time.Hour), WithServerTime())
+		defer server.Close()
+
+		// The first request detects the skew
+		Expect(c.EditFile(context.Background(), "1", EditFileParams{})).To(Succeed())
+
+		expiry := time.Now().Add(24 * time.Hour)
+
+		Expect(c.EditFile(context.Background(), "1", EditFileParams{Published: true, PublishedUntil: expiry})).To(Succeed())
+		Expect(sent.PublishedUntil).To(BeTemporally("~", expiry.Add(-time.Hour), 2*time.Second))
+	})
+	It("Should leave expiries unchanged without WithServerTime", func() {
+		c, server := newTestClient(skewedServer(-time.Hour))
+		defer server.Close()
+
+		Expect(c.EditFile(context.Background(), "1", EditFileParams{})).To(Succeed())
+
+		expiry := time.Now().Add(24 * time.Hour)
+
+		Expect(c.EditFile(context.Background(), "1", EditFileParams{Published: true, PublishedUntil: expiry})).To(Succeed())
+		Expect(sent.PublishedUntil).To(BeTemporally("==", expiry))
+	})
+})
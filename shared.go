@@ -0,0 +1,50 @@
+package hoist
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// SharedFile is a file with an active public link
+type SharedFile struct {
+	File
+	ShortLink  string `json:"shortLink"`
+	PublicLink string `json:"publicLink"`
+	// PublishedUntil is when the public link expires, or zero if it doesn't
+	PublishedUntil time.Time `json:"publishedUntil"`
+	// RemainingDownloads is the number of downloads left on a link published with MaxDownloads, or nil if unlimited
+	RemainingDownloads *int `json:"remainingDownloads"`
+}
+
+type sharedFilesResponse struct {
+	defaultResponse
+	Files []SharedFile `json:"files"`
+}
+
+// ListShared returns every file with an active public link, for auditing what's been shared.
+// Backends without a shared files endpoint fail with ErrNotSupported. There's no read-only way to check each file's
+// link instead, as the link status endpoint creates a link for any file which doesn't have one.
+func (c *client) ListShared(ctx context.Context) ([]SharedFile, error) {
+	res, err := c.doFeatureRequest(ctx, http.MethodGet, apiSharedFiles, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return nil, statusError(res)
+	}
+
+	var response sharedFilesResponse
+
+	if err := res.Decode(&response); err != nil {
+		return nil, err
+	}
+
+	if !response.Success {
+		return nil, failureError("list shared files", res, response.Message)
+	}
+
+	return response.Files, nil
+}
@@ -0,0 +1,60 @@
+package hoist
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Shared file tests", func() {
+	It("Should list shared files from the shared files endpoint", func() {
+		mux := http.NewServeMux()
+
+		mux.HandleFunc(testPath(apiSharedFiles), func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"success":true,"files":[{"id":"1","fileName":"a.txt","publicLink":"p","shortLink":"s","publishedUntil":"2030-01-02T00:00:00Z"}]}`))
+		})
+
+		c, server := newTestClient(mux)
+		defer server.Close()
+
+		shared, err := c.ListShared(context.Background())
+
+		Expect(err).To(BeNil())
+		Expect(shared).To(HaveLen(1))
+		Expect(shared[0].ID).To(Equal("1"))
+		Expect(shared[0].Name).To(Equal("a.txt"))
+		Expect(shared[0].PublicLink).To(Equal("p"))
+		Expect(shared[0].PublishedUntil).To(Equal(time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC)))
+	})
+	It("Should fail with ErrNotSupported without checking each file's link", func() {
+		var linkRequests int
+
+		mux := http.NewServeMux()
+
+		mux.HandleFunc(testPath(apiFolders), func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewEncoder(w).Encode(FolderResponse{
+				defaultResponse: defaultResponse{Success: true},
+				Folder:          testTree(),
+			})
+		})
+
+		mux.HandleFunc(strings.Replace(testPath(apiGetFileLink), "{fileId}", "1", 1), func(w http.ResponseWriter, r *http.Request) {
+			linkRequests++
+
+			_, _ = w.Write([]byte(`{"success":true,"publicLink":"p1","isPublic":true}`))
+		})
+
+		c, server := newTestClient(mux)
+		defer server.Close()
+
+		_, err := c.ListShared(context.Background())
+
+		Expect(err).To(MatchError(ErrNotSupported))
+		Expect(linkRequests).To(BeZero())
+	})
+})
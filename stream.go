@@ -0,0 +1,173 @@
+package hoist
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// DecodeStream decodes a JSON object from the body without buffering one of its arrays. The array is found by
+// following path through nested objects, and fn is called to decode each element from dec in turn. Every other field
+// is decoded into v once the body has been read, leaving the streamed array empty.
+// Like Decode, the body is closed afterwards.
+func (r *Response) DecodeStream(v any, path []string, fn func(dec *json.Decoder) error) error {
+	defer r.Close()
+
+	if len(path) == 0 {
+		return fmt.Errorf("stream path is empty")
+	}
+
+	dec := json.NewDecoder(r.Body)
+
+	fields, err := streamObject(dec, path, fn)
+
+	if err != nil {
+		return err
+	}
+
+	if v == nil {
+		return nil
+	}
+
+	b, err := json.Marshal(fields)
+
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(b, v)
+}
+
+// streamObject reads an object from dec, streaming the array at path and collecting the remaining fields
+func streamObject(dec *json.Decoder, path []string, fn func(dec *json.Decoder) error) (map[string]any, error) {
+	tok, err := dec.Token()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if tok == nil {
+		return nil, nil
+	}
+
+	if tok != json.Delim('{') {
+		return nil, fmt.Errorf("expected object, got %v", tok)
+	}
+
+	fields := make(map[string]any)
+
+	for dec.More() {
+		tok, err := dec.Token()
+
+		if err != nil {
+			return nil, err
+		}
+
+		key, ok := tok.(string)
+
+		if !ok {
+			return nil, fmt.Errorf("expected object key, got %v", tok)
+		}
+
+		switch {
+		case key == path[0] && len(path) == 1:
+			if err := streamArray(dec, fn); err != nil {
+				return nil, fmt.Errorf("failed to stream %s: %w", key, err)
+			}
+		case key == path[0]:
+			nested, err := streamObject(dec, path[1:], fn)
+
+			if err != nil {
+				return nil, err
+			}
+
+			fields[key] = nested
+		default:
+			var raw json.RawMessage
+
+			if err := dec.Decode(&raw); err != nil {
+				return nil, err
+			}
+
+			fields[key] = raw
+		}
+	}
+
+	// Closing '}'
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+
+	return fields, nil
+}
+
+// streamArray calls fn for each element of the array read from dec, treating null as empty
+func streamArray(dec *json.Decoder, fn func(dec *json.Decoder) error) error {
+	tok, err := dec.Token()
+
+	if err != nil {
+		return err
+	}
+
+	if tok == nil {
+		return nil
+	}
+
+	if tok != json.Delim('[') {
+		return fmt.Errorf("expected array, got %v", tok)
+	}
+
+	for dec.More() {
+		if err := fn(dec); err != nil {
+			return err
+		}
+	}
+
+	// Closing ']'
+	_, err = dec.Token()
+
+	return err
+}
+
+// StreamFolder fetches a folder like GetFolder, but decodes its files one at a time, calling fn for each instead of
+// holding the whole listing in memory. The returned folder has no files. Files without a FolderPath have it set to
+// the requested folder. Returning an error from fn stops decoding and is returned as-is.
+func (c *client) StreamFolder(ctx context.Context, folder string, fn func(file File) error, opts ...FolderOpt) (*Folder, error) {
+	res, err := c.requestFolder(ctx, folder, opts)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var folderResponse FolderResponse
+	var callbackErr error
+
+	err = res.DecodeStream(&folderResponse, []string{"folder", "files"}, func(dec *json.Decoder) error {
+		var file File
+
+		if err := dec.Decode(&file); err != nil {
+			return err
+		}
+
+		if file.FolderPath == "" {
+			file.FolderPath = folder
+		}
+
+		if err := fn(file); err != nil {
+			callbackErr = err
+			return err
+		}
+
+		return nil
+	})
+
+	if callbackErr != nil {
+		return nil, callbackErr
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return folderResponse.result()
+}
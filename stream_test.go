@@ -0,0 +1,154 @@
+package hoist
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"runtime"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Stream tests", func() {
+	// largeFolderServer writes a listing of count files without building it in memory
+	largeFolderServer := func(count int) http.Handler {
+		mux := http.NewServeMux()
+
+		mux.HandleFunc(testPath(apiFolder), func(w http.ResponseWriter, r *http.Request) {
+			bw := bufio.NewWriter(w)
+
+			_, _ = fmt.Fprintf(bw, `{"folder":{"name":"big","path":"/big","count":%d,"files":[`, count)
+
+			for i := 0; i < count; i++ {
+				if i > 0 {
+					_ = bw.WriteByte(',')
+				}
+
+				_, _ = fmt.Fprintf(bw, `{"id":"%d","fileName":"file-%d.txt","size":%d}`, i, i, i)
+			}
+
+			_, _ = bw.WriteString(`],"subfolders":[{"name":"sub","path":"/big/sub"}]},"success":true}`)
+			_ = bw.Flush()
+		})
+
+		return mux
+	}
+
+	It("Should stream a large listing with bounded memory", func() {
+		const count = 200000
+
+		c, server := newTestClient(largeFolderServer(count))
+		defer server.Close()
+
+		var stats runtime.MemStats
+
+		runtime.GC()
+		runtime.ReadMemStats(&stats)
+
+		baseline := stats.HeapAlloc
+
+		var seen int
+		var peak uint64
+
+		folder, err := c.StreamFolder(context.Background(), "/big", func(file File) error {
+			if file.Name != fmt.Sprintf("file-%d.txt", seen) || file.FolderPath != "/big" {
+				return fmt.Errorf("unexpected file %d: %+v", seen, file)
+			}
+
+			seen++
+
+			if seen%10000 == 0 {
+				runtime.ReadMemStats(&stats)
+				peak = max(peak, stats.HeapAlloc)
+			}
+
+			return nil
+		})
+
+		Expect(err).To(BeNil())
+		Expect(seen).To(Equal(count))
+		Expect(folder.Path).To(Equal("/big"))
+		Expect(folder.Count).To(Equal(count))
+		Expect(folder.Files).To(BeEmpty())
+		Expect(folder.Subfolders).To(HaveLen(1))
+
+		// Buffering 200k files would need well over 16MB
+		Expect(peak - min(peak, baseline)).To(BeNumerically("<", 16<<20))
+	})
+	It("Should stop when the callback fails", func() {
+		c, server := newTestClient(largeFolderServer(100))
+		defer server.Close()
+
+		stop := errors.New("stop")
+
+		var seen int
+
+		_, err := c.StreamFolder(context.Background(), "/big", func(file File) error {
+			seen++
+
+			if seen == 10 {
+				return stop
+			}
+
+			return nil
+		})
+
+		Expect(err).To(MatchError(stop))
+		Expect(seen).To(Equal(10))
+	})
+	It("Should report unsuccessful responses", func() {
+		mux := http.NewServeMux()
+
+		mux.HandleFunc(testPath(apiFolder), func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"success":false,"message":"Folder not found","folder":null}`))
+		})
+
+		c, server := newTestClient(mux)
+		defer server.Close()
+
+		_, err := c.StreamFolder(context.Background(), "/missing", func(file File) error {
+			return nil
+		})
+
+		Expect(err).To(MatchError(ErrNoFolder))
+	})
+	It("Should decode the remaining fields around the streamed array", func() {
+		res := &Response{Response: &http.Response{
+			Body: io.NopCloser(strings.NewReader(`{"a":{"items":[1,2,3],"name":"x"},"b":true,"c":{"items":null}}`)),
+		}}
+
+		var v struct {
+			A struct {
+				Items []int  `json:"items"`
+				Name  string `json:"name"`
+			} `json:"a"`
+			B bool `json:"b"`
+		}
+
+		var items []int
+
+		err := res.DecodeStream(&v, []string{"a", "items"}, func(dec *json.Decoder) error {
+			var item int
+
+			if err := dec.Decode(&item); err != nil {
+				return err
+			}
+
+			items = append(items, item)
+
+			return nil
+		})
+
+		Expect(err).To(BeNil())
+		Expect(items).To(Equal([]int{1, 2, 3}))
+		Expect(v.A.Items).To(BeEmpty())
+		Expect(v.A.Name).To(Equal("x"))
+		Expect(v.B).To(BeTrue())
+	})
+})
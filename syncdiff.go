@@ -0,0 +1,144 @@
+package hoist
+
+import (
+	"context"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"sort"
+)
+
+// diffOptions controls how Diff classifies files
+type diffOptions struct {
+	baseline []FileEntry
+}
+
+// DiffOpt allows defining options for Diff
+type DiffOpt func(o *diffOptions)
+
+// WithDiffBaseline sets the remote manifest from the last completed sync, as returned by Manifest for the same
+// remote root. Without a baseline a file missing from one side can't have been deleted, so it's always transferred.
+func WithDiffBaseline(entries []FileEntry) DiffOpt {
+	return func(o *diffOptions) {
+		o.baseline = entries
+	}
+}
+
+// Diff compares a local directory against the remote manifest below remoteRoot, for the client's account.
+// See the package level Diff for how files are classified.
+func (c *client) Diff(ctx context.Context, localDir, remoteRoot string, opts ...DiffOpt) ([]string, []string, []string, error) {
+	return Diff(ctx, c, localDir, remoteRoot, opts...)
+}
+
+// Diff compares a local directory against the remote manifest below remoteRoot using c, classifying each file by its
+// slash separated path relative to both roots. All three lists are sorted.
+//
+// Files only present locally are uploaded and files only present remotely are downloaded, unless the baseline from
+// WithDiffBaseline shows they were synced before, in which case they were deleted on the other side and are listed in
+// toDelete, to be removed from the side where they still exist. A file changed since the baseline is transferred
+// instead of deleted: a local file whose size differs or which was modified after the baseline DateAdded is uploaded,
+// and a remote file whose size or DateAdded differs from the baseline is downloaded.
+// Files present on both sides are compared by size, as the API doesn't expose checksums, with the newer copy winning
+// by comparing the local modification time against the remote DateAdded.
+func Diff(ctx context.Context, c FileClient, localDir, remoteRoot string, opts ...DiffOpt) (toUpload, toDownload, toDelete []string, err error) {
+	var o diffOptions
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	remoteRoot = path.Clean("/" + remoteRoot)
+
+	manifest, err := c.Manifest(ctx, remoteRoot)
+
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	remote := relativeEntries(remoteRoot, manifest)
+	baseline := relativeEntries(remoteRoot, o.baseline)
+
+	local := make(map[string]fs.FileInfo)
+
+	err = filepath.WalkDir(localDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(localDir, p)
+
+		if err != nil {
+			return err
+		}
+
+		info, err := d.Info()
+
+		if err != nil {
+			return err
+		}
+
+		local[filepath.ToSlash(rel)] = info
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	for rel, info := range local {
+		entry, ok := remote[rel]
+
+		switch {
+		case !ok:
+			// A local file changed since the sync wins over its remote deletion
+			if base, synced := baseline[rel]; synced && info.Size() == base.Size && !info.ModTime().After(base.DateAdded) {
+				toDelete = append(toDelete, rel)
+			} else {
+				toUpload = append(toUpload, rel)
+			}
+		case entry.Size != info.Size():
+			if info.ModTime().After(entry.DateAdded) {
+				toUpload = append(toUpload, rel)
+			} else {
+				toDownload = append(toDownload, rel)
+			}
+		}
+	}
+
+	for rel, entry := range remote {
+		if _, ok := local[rel]; ok {
+			continue
+		}
+
+		// A remote file replaced since the sync wins over its local deletion
+		if base, synced := baseline[rel]; synced && entry.Size == base.Size && entry.DateAdded.Equal(base.DateAdded) {
+			toDelete = append(toDelete, rel)
+		} else {
+			toDownload = append(toDownload, rel)
+		}
+	}
+
+	sort.Strings(toUpload)
+	sort.Strings(toDownload)
+	sort.Strings(toDelete)
+
+	return toUpload, toDownload, toDelete, nil
+}
+
+// relativeEntries maps manifest entries below root by their path relative to it
+func relativeEntries(root string, entries []FileEntry) map[string]FileEntry {
+	relative := make(map[string]FileEntry, len(entries))
+
+	for _, entry := range entries {
+		if rel, ok := relativePath(root, path.Clean("/"+entry.Path)); ok && rel != "." {
+			relative[rel] = entry
+		}
+	}
+
+	return relative
+}
@@ -0,0 +1,75 @@
+package hoist
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// requestTimeoutKey is the context key for the WithRequestTimeout duration
+type requestTimeoutKey struct{}
+
+// WithRequestTimeout returns a context which limits how long each request made with it waits for the response
+// headers, like TransportConfig.ResponseHeaderTimeout but per call, so a huge folder listing and a quick lookup can
+// use different limits. Requests which time out fail with an error matching context.DeadlineExceeded.
+// Once the headers arrive, the body is only bounded by ctx itself, so a download stream isn't cut off by a timeout
+// meant for the metadata request. Each request gets the full timeout, including retries. Use context.WithTimeout
+// instead to bound the whole call.
+func WithRequestTimeout(ctx context.Context, timeout time.Duration) context.Context {
+	return context.WithValue(ctx, requestTimeoutKey{}, timeout)
+}
+
+// sendHttpRequestTimeout sends the request like sendHttpRequest, applying the context's WithRequestTimeout until the
+// response headers are received
+func sendHttpRequestTimeout(client *http.Client, req *http.Request) (*Response, error) {
+	timeout, ok := req.Context().Value(requestTimeoutKey{}).(time.Duration)
+
+	if !ok || timeout <= 0 {
+		return sendHttpRequest(client, req)
+	}
+
+	ctx, cancel := context.WithCancelCause(req.Context())
+
+	timer := time.AfterFunc(timeout, func() {
+		cancel(context.DeadlineExceeded)
+	})
+
+	res, err := sendHttpRequest(client, req.WithContext(ctx))
+
+	timer.Stop()
+
+	if err != nil {
+		// Report the timeout rather than the cancellation it caused, unless the caller's context ended first
+		var netErr *NetworkError
+
+		if req.Context().Err() == nil && errors.Is(context.Cause(ctx), context.DeadlineExceeded) && errors.As(err, &netErr) {
+			netErr.Err = fmt.Errorf("no response within %s: %w", timeout, context.DeadlineExceeded)
+		}
+
+		cancel(nil)
+
+		return nil, err
+	}
+
+	// Keep the request context alive until the body has been read
+	res.Body = &cancelOnClose{ReadCloser: res.Body, cancel: cancel}
+
+	return res, nil
+}
+
+// cancelOnClose releases a request context once the response body is closed
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelCauseFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+
+	c.cancel(nil)
+
+	return err
+}
@@ -0,0 +1,93 @@
+package hoist
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Request timeout tests", func() {
+	// slowServer waits for delay before sending the folder listing headers
+	slowServer := func(delay time.Duration) http.Handler {
+		mux := http.NewServeMux()
+
+		mux.HandleFunc(testPath(apiFolders), func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(delay):
+			}
+
+			_, _ = w.Write([]byte(`{"success":true,"folders":[]}`))
+		})
+
+		return mux
+	}
+
+	It("Should fail requests which don't respond within the timeout", func() {
+		c, server := newTestClient(slowServer(time.Second))
+		defer server.Close()
+
+		_, err := c.GetFolders(WithRequestTimeout(context.Background(), 20*time.Millisecond))
+
+		var netErr *NetworkError
+
+		Expect(errors.Is(err, context.DeadlineExceeded)).To(BeTrue())
+		Expect(errors.As(err, &netErr)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("no response within 20ms"))
+	})
+	It("Should honour the caller's context deadline", func() {
+		c, server := newTestClient(slowServer(time.Second))
+		defer server.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		_, err := c.GetFolders(ctx)
+
+		Expect(errors.Is(err, context.DeadlineExceeded)).To(BeTrue())
+	})
+	It("Should succeed when the response arrives in time", func() {
+		c, server := newTestClient(slowServer(10 * time.Millisecond))
+		defer server.Close()
+
+		_, err := c.GetFolders(WithRequestTimeout(context.Background(), time.Second))
+
+		Expect(err).To(BeNil())
+	})
+	It("Should not cut off a download stream once the headers arrive", func() {
+		mux := http.NewServeMux()
+
+		mux.HandleFunc(strings.Replace(testPath(apiFileDownload), "{fileId}", "1", 1), func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("first "))
+			w.(http.Flusher).Flush()
+
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(100 * time.Millisecond):
+			}
+
+			_, _ = w.Write([]byte("second"))
+		})
+
+		c, server := newTestClient(mux)
+		defer server.Close()
+
+		download, err := c.OpenDownload(WithRequestTimeout(context.Background(), 20*time.Millisecond), "1")
+
+		Expect(err).To(BeNil())
+
+		data, err := io.ReadAll(download)
+
+		Expect(err).To(BeNil())
+		Expect(string(data)).To(Equal("first second"))
+		Expect(download.Close()).To(Succeed())
+	})
+})
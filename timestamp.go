@@ -0,0 +1,91 @@
+package hoist
+
+import (
+	"encoding/json"
+	"github.com/namecrane/hoist/internal/timestamp"
+)
+
+// Timestamps sent by the API are decoded with timestamp.Time, accepting RFC3339 strings and Unix seconds or
+// milliseconds. Exported fields stay time.Time, so each type with one decodes it through an alias.
+
+// UnmarshalJSON decodes a file, accepting any server timestamp format for DateAdded
+func (f *File) UnmarshalJSON(data []byte) error {
+	type Alias File
+
+	aux := struct {
+		*Alias
+		DateAdded timestamp.Time `json:"dateAdded"`
+	}{Alias: (*Alias)(f)}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	f.DateAdded = aux.DateAdded.Time
+
+	return nil
+}
+
+// UnmarshalJSON decodes a shared file. It's needed as the embedded File's UnmarshalJSON would otherwise
+// be promoted, skipping the link fields.
+func (s *SharedFile) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &s.File); err != nil {
+		return err
+	}
+
+	var link struct {
+		ShortLink          string         `json:"shortLink"`
+		PublicLink         string         `json:"publicLink"`
+		PublishedUntil     timestamp.Time `json:"publishedUntil"`
+		RemainingDownloads *int           `json:"remainingDownloads"`
+	}
+
+	if err := json.Unmarshal(data, &link); err != nil {
+		return err
+	}
+
+	s.ShortLink = link.ShortLink
+	s.PublicLink = link.PublicLink
+	s.PublishedUntil = link.PublishedUntil.Time
+	s.RemainingDownloads = link.RemainingDownloads
+
+	return nil
+}
+
+// UnmarshalJSON decodes edit params, accepting any server timestamp format for PublishedUntil
+func (p *EditFileParams) UnmarshalJSON(data []byte) error {
+	type Alias EditFileParams
+
+	aux := struct {
+		*Alias
+		PublishedUntil timestamp.Time `json:"publishedUntil"`
+	}{Alias: (*Alias)(p)}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	p.PublishedUntil = aux.PublishedUntil.Time
+
+	return nil
+}
+
+// UnmarshalJSON decodes an auth response, accepting any server timestamp format for the token expirations
+func (r *AuthResponse) UnmarshalJSON(data []byte) error {
+	type Alias AuthResponse
+
+	aux := struct {
+		*Alias
+		TokenExpiration        timestamp.Time `json:"accessTokenExpiration"`
+		RefreshTokenExpiration timestamp.Time `json:"refreshTokenExpiration"`
+	}{Alias: (*Alias)(r)}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	r.TokenExpiration = aux.TokenExpiration.Time
+	r.RefreshTokenExpiration = aux.RefreshTokenExpiration.Time
+
+	return nil
+}
@@ -0,0 +1,67 @@
+package hoist
+
+import (
+	"encoding/json"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Timestamp tests", func() {
+	expected := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+
+	DescribeTable("Should decode File.DateAdded in each timestamp format",
+		func(input string) {
+			var file File
+
+			Expect(json.Unmarshal([]byte(`{"id":"1","fileName":"a.txt","size":3,"dateAdded":`+input+`}`), &file)).To(Succeed())
+			Expect(file.DateAdded).To(BeTemporally("==", expected))
+			Expect(file.ID).To(Equal("1"))
+			Expect(file.Name).To(Equal("a.txt"))
+			Expect(file.Size).To(BeEquivalentTo(3))
+		},
+		Entry("RFC3339", `"2024-05-06T07:08:09Z"`),
+		Entry("RFC3339 with an offset", `"2024-05-06T09:08:09+02:00"`),
+		Entry("RFC3339 without a zone", `"2024-05-06T07:08:09"`),
+		Entry("Unix seconds", `1714979289`),
+		Entry("Unix milliseconds", `1714979289000`),
+	)
+
+	It("Should leave a missing DateAdded as the zero time", func() {
+		var file File
+
+		Expect(json.Unmarshal([]byte(`{"id":"1"}`), &file)).To(Succeed())
+		Expect(file.DateAdded.IsZero()).To(BeTrue())
+	})
+	It("Should decode shared files with their link fields", func() {
+		var shared SharedFile
+
+		Expect(json.Unmarshal([]byte(`{"id":"1","dateAdded":1714979289,"publicLink":"p","publishedUntil":"2024-05-06T07:08:09Z"}`), &shared)).To(Succeed())
+		Expect(shared.ID).To(Equal("1"))
+		Expect(shared.DateAdded).To(BeTemporally("==", expected))
+		Expect(shared.PublicLink).To(Equal("p"))
+		Expect(shared.PublishedUntil).To(BeTemporally("==", expected))
+	})
+	It("Should decode auth token expirations in any timestamp format", func() {
+		var auth AuthResponse
+
+		Expect(json.Unmarshal([]byte(`{"accessToken":"t","accessTokenExpiration":1714979289000,"refreshTokenExpiration":"2024-05-06T07:08:09"}`), &auth)).To(Succeed())
+		Expect(auth.Token).To(Equal("t"))
+		Expect(auth.TokenExpiration).To(BeTemporally("==", expected))
+		Expect(auth.RefreshTokenExpiration).To(BeTemporally("==", expected))
+	})
+	It("Should decode link and edit expiries in any timestamp format", func() {
+		var link linkResponse
+
+		Expect(json.Unmarshal([]byte(`{"success":true,"publicLink":"p","publishedUntil":1714979289}`), &link)).To(Succeed())
+		Expect(link.PublicLink).To(Equal("p"))
+		Expect(link.PublishedUntil.Time).To(BeTemporally("==", expected))
+
+		var params EditFileParams
+
+		Expect(json.Unmarshal([]byte(`{"published":true,"publishedUntil":"1714979289"}`), &params)).To(Succeed())
+		Expect(params.Published).To(BeTrue())
+		Expect(params.PublishedUntil).To(BeTemporally("==", expected))
+	})
+})
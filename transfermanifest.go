@@ -0,0 +1,130 @@
+package hoist
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// TransferEntry records a file completed by UploadDir or DownloadDir in a transfer manifest (see WithDirManifest and
+// WithDownloadManifest).
+//
+// The manifest is a JSON Lines file with one entry appended and synced per completed file, so an interrupted
+// transfer loses at most the files in progress. A file is skipped on the next run when an entry has the same path,
+// size and modification time: the local modification time for uploads, and DateAdded of the remote file for
+// downloads.
+type TransferEntry struct {
+	// Path is the slash-separated path relative to the transferred directory
+	Path     string    `json:"path"`
+	ID       string    `json:"id"`
+	Size     int64     `json:"size"`
+	Modified time.Time `json:"modified"`
+}
+
+// transferManifest is an open transfer manifest, holding the entries read when it was opened
+type transferManifest struct {
+	mu      sync.Mutex
+	f       *os.File
+	entries map[string]TransferEntry
+}
+
+// openTransferManifest opens or creates the manifest at name. A trailing partial entry, left by a crash mid-write,
+// is discarded so that new entries start on a fresh line.
+func openTransferManifest(name string) (*transferManifest, error) {
+	f, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE, 0644)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to open transfer manifest: %w", err)
+	}
+
+	data, err := io.ReadAll(f)
+
+	if err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("failed to read transfer manifest: %w", err)
+	}
+
+	m := &transferManifest{
+		f:       f,
+		entries: make(map[string]TransferEntry),
+	}
+
+	var valid int64
+
+	for len(data) > 0 {
+		line, rest, complete := bytes.Cut(data, []byte("\n"))
+
+		if !complete {
+			break
+		}
+
+		var entry TransferEntry
+
+		if err := json.Unmarshal(line, &entry); err != nil {
+			break
+		}
+
+		m.entries[entry.Path] = entry
+
+		valid += int64(len(line)) + 1
+		data = rest
+	}
+
+	if err := f.Truncate(valid); err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("failed to truncate transfer manifest: %w", err)
+	}
+
+	if _, err := f.Seek(valid, io.SeekStart); err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("failed to seek transfer manifest: %w", err)
+	}
+
+	return m, nil
+}
+
+// done returns the entry for rel if it was completed with the same size and modification time
+func (m *transferManifest) done(rel string, size int64, modified time.Time) (TransferEntry, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.entries[rel]
+
+	if !ok || entry.Size != size || !entry.Modified.Equal(modified) {
+		return TransferEntry{}, false
+	}
+
+	return entry, true
+}
+
+// record appends the entry and syncs it to disk
+func (m *transferManifest) record(entry TransferEntry) error {
+	b, err := json.Marshal(entry)
+
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, err := m.f.Write(append(b, '\n')); err != nil {
+		return fmt.Errorf("failed to write transfer manifest: %w", err)
+	}
+
+	if err := m.f.Sync(); err != nil {
+		return fmt.Errorf("failed to sync transfer manifest: %w", err)
+	}
+
+	m.entries[entry.Path] = entry
+
+	return nil
+}
+
+func (m *transferManifest) Close() error {
+	return m.f.Close()
+}
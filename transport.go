@@ -0,0 +1,74 @@
+package hoist
+
+import (
+	"net"
+	"net/http"
+	"time"
+)
+
+// TransportConfig tunes the connection pool and timeouts of an HTTP client built by NewHTTPClient
+type TransportConfig struct {
+	// MaxIdleConnsPerHost is how many idle connections are kept open to the API for reuse
+	MaxIdleConnsPerHost int
+	// IdleConnTimeout closes idle connections after this long
+	IdleConnTimeout time.Duration
+	// KeepAlive is the TCP keep-alive period for open connections
+	KeepAlive time.Duration
+	// DialTimeout limits how long establishing a connection can take
+	DialTimeout time.Duration
+	// TLSHandshakeTimeout limits how long the TLS handshake can take
+	TLSHandshakeTimeout time.Duration
+	// ResponseHeaderTimeout limits the wait for response headers once a request is sent, zero for no limit.
+	// It doesn't apply to reading the body, so long streaming downloads aren't cut off.
+	ResponseHeaderTimeout time.Duration
+}
+
+// DefaultTransportConfig returns settings suited to many small API calls alongside long streaming downloads
+func DefaultTransportConfig() TransportConfig {
+	return TransportConfig{
+		MaxIdleConnsPerHost: 16,
+		IdleConnTimeout:     90 * time.Second,
+		KeepAlive:           30 * time.Second,
+		DialTimeout:         10 * time.Second,
+		TLSHandshakeTimeout: 10 * time.Second,
+	}
+}
+
+// NewHTTPClient builds an *http.Client with its own transport using the given settings.
+// The client has no overall timeout, as that would include streaming the body of downloads; use request contexts
+// instead.
+func NewHTTPClient(config TransportConfig) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	transport.DialContext = (&net.Dialer{
+		Timeout:   config.DialTimeout,
+		KeepAlive: config.KeepAlive,
+	}).DialContext
+	transport.MaxIdleConnsPerHost = config.MaxIdleConnsPerHost
+	transport.IdleConnTimeout = config.IdleConnTimeout
+	transport.TLSHandshakeTimeout = config.TLSHandshakeTimeout
+	transport.ResponseHeaderTimeout = config.ResponseHeaderTimeout
+
+	if transport.MaxIdleConns > 0 && transport.MaxIdleConns < config.MaxIdleConnsPerHost {
+		transport.MaxIdleConns = config.MaxIdleConnsPerHost
+	}
+
+	return &http.Client{Transport: transport}
+}
+
+// WithTransportConfig uses an HTTP client built by NewHTTPClient for API requests. The auth manager shares the client
+// too when it was created by NewAuthManager without WithAuthClient, so both use a single connection pool.
+func WithTransportConfig(config TransportConfig) ClientOption {
+	return func(c *client) {
+		c.client = NewHTTPClient(config)
+
+		if am, ok := c.authManager.(*authManager); ok {
+			am.mu.Lock()
+			defer am.mu.Unlock()
+
+			if am.client == http.DefaultClient {
+				am.client = c.client
+			}
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package hoist
+
+import (
+	"net/http"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Transport tests", func() {
+	config := TransportConfig{
+		MaxIdleConnsPerHost:   32,
+		IdleConnTimeout:       time.Minute,
+		KeepAlive:             15 * time.Second,
+		DialTimeout:           5 * time.Second,
+		TLSHandshakeTimeout:   7 * time.Second,
+		ResponseHeaderTimeout: 20 * time.Second,
+	}
+
+	It("Should apply the transport settings", func() {
+		httpClient := NewHTTPClient(config)
+
+		transport, ok := httpClient.Transport.(*http.Transport)
+
+		Expect(ok).To(BeTrue())
+		Expect(transport.MaxIdleConnsPerHost).To(Equal(32))
+		Expect(transport.IdleConnTimeout).To(Equal(time.Minute))
+		Expect(transport.TLSHandshakeTimeout).To(Equal(7 * time.Second))
+		Expect(transport.ResponseHeaderTimeout).To(Equal(20 * time.Second))
+		Expect(transport.DialContext).ToNot(BeNil())
+		Expect(httpClient.Timeout).To(BeZero())
+	})
+	It("Should share the client with the auth manager", func() {
+		am := NewAuthManager("http://localhost").(*authManager)
+
+		c := NewClient("http://localhost", am, WithTransportConfig(config)).(*client)
+
+		Expect(c.client).ToNot(BeIdenticalTo(http.DefaultClient))
+		Expect(am.client).To(BeIdenticalTo(c.client))
+		Expect(c.client.Transport.(*http.Transport).MaxIdleConnsPerHost).To(Equal(32))
+	})
+	It("Should keep an explicitly configured auth client", func() {
+		authClient := &http.Client{}
+
+		am := NewAuthManager("http://localhost", WithAuthClient(authClient)).(*authManager)
+
+		NewClient("http://localhost", am, WithTransportConfig(config))
+
+		Expect(am.client).To(BeIdenticalTo(authClient))
+	})
+})
@@ -0,0 +1,647 @@
+package hoist
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/namecrane/hoist/internal/validate"
+	log "github.com/sirupsen/logrus"
+	"hash"
+	"io"
+	"maps"
+	"net/http"
+	"path"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// checksumField is the final chunk field carrying the client computed checksum, for backends which validate assembly
+const checksumField = "resumableChecksum"
+
+// uploadOptions are the options applied to a single upload
+type uploadOptions struct {
+	gzip           bool
+	checksum       hash.Hash
+	concurrency    int
+	finalChunkLast bool
+	createParents  bool
+	progress       func(uploaded, total int64)
+	identifier     func(identifier string)
+	retryAttempts  int
+	retryBase      time.Duration
+
+	// Continues an earlier upload, see ResumeUpload
+	resumeID   string
+	startChunk int
+
+	// Overrides the file storage context, see UploadChatFile
+	uploadContext string
+	contextData   any
+
+	// The chunk size used for every chunk of the upload, see client.newUploadOptions
+	chunkSize int64
+}
+
+// UploadOpt allows defining per-upload options for ChunkedUpload and related methods
+type UploadOpt func(o *uploadOptions)
+
+// WithUploadGzip compresses the upload with gzip, appending .gz to the file name and uploading it as application/gzip.
+// Compression is skipped for already-compressed file types, and when it doesn't shrink the data.
+// The whole file is compressed before uploading, since the compressed size must be known up front, through temporary
+// files in os.TempDir rather than memory, so that needs room for up to twice the file size. Use DecompressGzip to read
+// the file back.
+func WithUploadGzip() UploadOpt {
+	return func(o *uploadOptions) {
+		o.gzip = true
+	}
+}
+
+// WithUploadChecksum computes h across every chunk as it's uploaded, sending the hex digest in the final chunk's
+// resumableChecksum field so the backend can validate the assembled file. The digest is also set as File.Checksum
+// on the result. When combined with WithUploadGzip, the compressed data is hashed, since that's what is stored.
+func WithUploadChecksum(h hash.Hash) UploadOpt {
+	return func(o *uploadOptions) {
+		o.checksum = h
+	}
+}
+
+// WithChunkConcurrency uploads up to n chunks at once. Each in-flight chunk is buffered in memory, so memory use
+// grows to roughly n times the chunk size. By default the final chunk may be sent while earlier chunks are still in
+// flight; use WithFinalChunkLast for backends which assemble the file as soon as the final chunk arrives.
+func WithChunkConcurrency(n int) UploadOpt {
+	return func(o *uploadOptions) {
+		o.concurrency = n
+	}
+}
+
+// WithFinalChunkLast holds back the final chunk of a concurrent upload until every other chunk has been confirmed.
+// Resumable.js style backends, including NameCrane's, treat the final chunk as the trigger to combine the file and
+// would otherwise assemble it prematurely from the chunks received so far. It has no effect on sequential uploads,
+// which always send chunks in order.
+func WithFinalChunkLast() UploadOpt {
+	return func(o *uploadOptions) {
+		o.finalChunkLast = true
+	}
+}
+
+// WithUploadProgress calls fn once each time the server accepts a chunk, with the cumulative bytes uploaded and the
+// total size. The final call is made once the combined file is returned, reporting uploaded == total.
+// Calls are serialized with increasing counts, even for concurrent chunks, though those may complete out of order.
+// fn runs on the upload's goroutine, so a slow callback delays the upload; a panic is logged and otherwise ignored.
+// When combined with WithUploadGzip, the compressed size is reported, since that's what is sent.
+func WithUploadProgress(fn func(uploaded, total int64)) UploadOpt {
+	return func(o *uploadOptions) {
+		o.progress = fn
+	}
+}
+
+// WithUploadIdentifier calls fn with the upload's resumableIdentifier before any chunk is sent. Persisting it along
+// with the number of chunks accepted (see WithUploadProgress) allows an interrupted upload to be continued with
+// ResumeUpload instead of starting over.
+func WithUploadIdentifier(fn func(identifier string)) UploadOpt {
+	return func(o *uploadOptions) {
+		o.identifier = fn
+	}
+}
+
+// withResume continues the upload identified by identifier, skipping chunks before startChunk
+func withResume(identifier string, startChunk int) UploadOpt {
+	return func(o *uploadOptions) {
+		o.resumeID = identifier
+		o.startChunk = startChunk
+	}
+}
+
+// WithUploadRetry re-sends a chunk which fails with a NetworkError or 5xx response, making up to attempts attempts in
+// total and waiting base before the first retry, doubling the wait each time. Other failures, such as an AuthError,
+// a request signer or modifier error or a 4xx response, are returned at once. The chunk is buffered, so the same bytes
+// are re-sent. Retrying stops as soon as the context is cancelled. It overrides the client's WithChunkRetry policy;
+// without either, only ChunkedUploadAt and ResumeUpload retry chunks. The final chunk is always retried separately,
+// as the combine step is what usually times out.
+func WithUploadRetry(attempts int, base time.Duration) UploadOpt {
+	return func(o *uploadOptions) {
+		o.retryAttempts = attempts
+		o.retryBase = base
+	}
+}
+
+// WithCreateParents makes WriteFile create any missing parent folders before uploading
+func WithCreateParents() UploadOpt {
+	return func(o *uploadOptions) {
+		o.createParents = true
+	}
+}
+
+// withUploadContext uploads into a context other than file storage, such as chat files
+func withUploadContext(uploadContext string, contextData any) UploadOpt {
+	return func(o *uploadOptions) {
+		o.uploadContext = uploadContext
+		o.contextData = contextData
+	}
+}
+
+// applyContext replaces the file storage context fields set by uploadFields when another context is requested
+func (o uploadOptions) applyContext(fields map[string]string) error {
+	if o.uploadContext == "" {
+		return nil
+	}
+
+	contextBytes, err := json.Marshal(o.contextData)
+
+	if err != nil {
+		return err
+	}
+
+	fields["context"] = o.uploadContext
+	fields["contextData"] = string(contextBytes)
+
+	return nil
+}
+
+// applyIdentifier replaces the identifier generated by uploadFields when resuming, and reports it for
+// WithUploadIdentifier
+func (o uploadOptions) applyIdentifier(fields map[string]string) {
+	if o.resumeID != "" {
+		fields["resumableIdentifier"] = o.resumeID
+	}
+
+	if o.identifier != nil {
+		o.identifier(fields["resumableIdentifier"])
+	}
+}
+
+// chunkRetry is how a failed chunk is retried, see WithUploadRetry
+type chunkRetry struct {
+	attempts int
+	base     time.Duration
+}
+
+// delay returns how long to wait before attempt (starting at 2), doubling the base delay for each attempt
+func (r chunkRetry) delay(attempt int) time.Duration {
+	return r.base << min(attempt-2, 16)
+}
+
+// defaultChunkRetry returns the client's WithChunkRetry policy, or fallback without it
+func (c *client) defaultChunkRetry(fallback chunkRetry) chunkRetry {
+	if c.chunkRetry.attempts > 0 {
+		return c.chunkRetry
+	}
+
+	return fallback
+}
+
+// finalChunkRetry returns the retry policy for the final chunk of an upload with o. Without WithUploadRetry or
+// WithChunkRetry, it's re-sent up to maxCombineAttempts times, as the combine step occasionally fails transiently.
+func (c *client) finalChunkRetry(o uploadOptions) chunkRetry {
+	return o.chunkRetry(c.defaultChunkRetry(chunkRetry{attempts: maxCombineAttempts, base: c.combineRetryDelay}))
+}
+
+// chunkRetry returns the retry policy set by WithUploadRetry, or fallback without it
+func (o uploadOptions) chunkRetry(fallback chunkRetry) chunkRetry {
+	if o.retryAttempts > 0 {
+		return chunkRetry{attempts: o.retryAttempts, base: o.retryBase}
+	}
+
+	return fallback
+}
+
+// uploadProgress tracks the bytes accepted by the server for WithUploadProgress. A nil tracker ignores reports.
+type uploadProgress struct {
+	mu       sync.Mutex
+	fn       func(uploaded, total int64)
+	uploaded int64
+	total    int64
+}
+
+// newProgress returns a tracker for an upload of total bytes, or nil without WithUploadProgress
+func (o uploadOptions) newProgress(total int64) *uploadProgress {
+	if o.progress == nil {
+		return nil
+	}
+
+	return &uploadProgress{fn: o.progress, total: total}
+}
+
+// add reports n more bytes as uploaded. The count is updated before calling the callback, and a panicking callback is
+// logged rather than aborting the upload, so a misbehaving callback can't affect the upload itself.
+func (p *uploadProgress) add(n int64) {
+	if p == nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.uploaded += n
+
+	defer func() {
+		if r := recover(); r != nil {
+			log.WithFields(log.Fields{
+				"uploaded": p.uploaded,
+				"total":    p.total,
+				"panic":    r,
+			}).Warn("Upload progress callback panicked")
+		}
+	}()
+
+	p.fn(p.uploaded, p.total)
+}
+
+func newUploadOptions(opts []UploadOpt) uploadOptions {
+	var o uploadOptions
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
+
+// singleRequest returns true if the options can be honoured by Upload, which doesn't take any
+func (o uploadOptions) singleRequest() bool {
+	return !o.gzip && o.checksum == nil && o.progress == nil && o.identifier == nil && o.resumeID == "" &&
+		o.uploadContext == ""
+}
+
+// newUploadOptions applies opts for an upload, fixing the chunk size so every chunk of the upload uses the same one
+func (c *client) newUploadOptions(opts []UploadOpt) uploadOptions {
+	o := newUploadOptions(opts)
+	o.chunkSize = c.ChunkSize()
+
+	return o
+}
+
+// ChunkCount returns how many chunks ChunkedUpload splits a file of fileSize bytes into, using chunks of chunkSize.
+// Empty files are uploaded as a single empty chunk, as is everything when chunkSize isn't positive.
+func ChunkCount(fileSize, chunkSize int64) int {
+	if fileSize <= 0 || chunkSize <= 0 {
+		return 1
+	}
+
+	return int((fileSize + chunkSize - 1) / chunkSize)
+}
+
+// ChunkBounds returns the byte offset and length of the chunk at the zero-based index (the upload's
+// resumableChunkNumber is index+1), matching how ChunkedUpload splits the file. Indexes outside of
+// [0, ChunkCount) have a length of 0.
+func ChunkBounds(index int, fileSize, chunkSize int64) (offset, length int64) {
+	if index < 0 || index >= ChunkCount(fileSize, chunkSize) {
+		return 0, 0
+	}
+
+	if chunkSize <= 0 {
+		return 0, max(fileSize, 0)
+	}
+
+	offset = int64(index) * chunkSize
+
+	return offset, min(chunkSize, fileSize-offset)
+}
+
+// uploadChunksConcurrently uploads all but the final chunk using up to o.concurrency requests at once, then the final
+// chunk, which is sent only once the others have succeeded when o.finalChunkLast is set.
+// Chunks are read from in sequentially, so a checksum tee still sees the data in order.
+func (c *client) uploadChunksConcurrently(ctx context.Context, in io.Reader, filePath string, fileSize int64, totalChunks int, fields map[string]string, o uploadOptions, progress *uploadProgress) (*File, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	fileName := path.Base(filePath)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var firstErr error
+
+	fail := func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if firstErr == nil {
+			firstErr = err
+			cancel()
+		}
+	}
+
+	failed := func() error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return firstErr
+	}
+
+	sem := make(chan struct{}, o.concurrency)
+
+	retry := o.chunkRetry(c.defaultChunkRetry(chunkRetry{attempts: 1}))
+
+	for chunk := 1; chunk < totalChunks; chunk++ {
+		_, chunkSize := ChunkBounds(chunk-1, fileSize, o.chunkSize)
+
+		buf := make([]byte, chunkSize)
+
+		if _, err := io.ReadFull(in, buf); err != nil {
+			fail(fmt.Errorf("failed to read chunk data: %w", err))
+			break
+		}
+
+		chunkFields := maps.Clone(fields)
+		chunkFields["resumableChunkNumber"] = strconv.Itoa(chunk)
+		chunkFields["resumableCurrentChunkSize"] = strconv.FormatInt(chunkSize, 10)
+
+		select {
+		case <-ctx.Done():
+		case sem <- struct{}{}:
+		}
+
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			err := c.retryChunk(ctx, filePath, chunkFields, retry, func() (*Response, error) {
+				return c.uploadChunk(ctx, bytes.NewReader(buf), fileName, fileSize, chunkSize, chunkFields)
+			})
+
+			if err != nil {
+				fail(err)
+				return
+			}
+
+			progress.add(chunkSize)
+		}()
+	}
+
+	if o.finalChunkLast {
+		wg.Wait()
+	}
+
+	if err := failed(); err != nil {
+		wg.Wait()
+		return nil, err
+	}
+
+	_, finalSize := ChunkBounds(totalChunks-1, fileSize, o.chunkSize)
+
+	finalFields := maps.Clone(fields)
+	finalFields["resumableChunkNumber"] = strconv.Itoa(totalChunks)
+	finalFields["resumableCurrentChunkSize"] = strconv.FormatInt(finalSize, 10)
+
+	file, err := c.uploadFinalChunk(ctx, in, filePath, fileSize, finalSize, finalFields, o.checksum, c.finalChunkRetry(o))
+
+	wg.Wait()
+
+	if err := failed(); err != nil {
+		return nil, err
+	}
+
+	if err == nil {
+		progress.add(finalSize)
+	}
+
+	return file, err
+}
+
+// ResumeUpload continues an interrupted upload of fileSize bytes read from ra, reusing its resumableIdentifier (see
+// WithUploadIdentifier) so the server adds to the chunks it already has. Sending starts at startChunk, the zero-based
+// index of the first chunk which wasn't accepted, so reading starts at offset startChunk * ChunkSize(). The file
+// size and chunk size must match the original upload. Otherwise, it behaves like ChunkedUploadAt, except that
+// WithUploadGzip can't be combined with a non-zero startChunk, as the compressed chunks don't map to offsets in ra.
+// A missing identifier or a startChunk past the final chunk fails with ErrInvalidResume. The final chunk is always
+// sent, as it's what triggers the server to combine the file.
+func (c *client) ResumeUpload(ctx context.Context, ra io.ReaderAt, filePath string, fileSize int64, identifier string, startChunk int, opts ...UploadOpt) (*File, error) {
+	if err := validate.Resume(identifier, startChunk, ChunkCount(fileSize, c.ChunkSize())); err != nil {
+		return nil, err
+	}
+
+	done, err := c.beginUpload()
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer done()
+
+	return c.chunkedUploadAt(ctx, ra, filePath, fileSize, append(opts, withResume(identifier, startChunk))...)
+}
+
+// ChunkedUploadAt uploads fileSize bytes read from ra, like ChunkedUpload. Each chunk is read with ReadAt, so a chunk
+// the server fails to accept (a network error or 5xx) is re-read and re-sent, by default up to maxChunkAttempts with
+// exponential backoff (see WithUploadRetry), instead of failing the whole upload. Concurrent chunks (see
+// WithChunkConcurrency) are read directly from ra without buffering. WithUploadGzip compresses the whole file up
+// front, so it falls back to ChunkedUpload.
+func (c *client) ChunkedUploadAt(ctx context.Context, ra io.ReaderAt, filePath string, fileSize int64, opts ...UploadOpt) (*File, error) {
+	done, err := c.beginUpload()
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer done()
+
+	return c.chunkedUploadAt(ctx, ra, filePath, fileSize, opts...)
+}
+
+func (c *client) chunkedUploadAt(ctx context.Context, ra io.ReaderAt, filePath string, fileSize int64, opts ...UploadOpt) (*File, error) {
+	o := c.newUploadOptions(opts)
+
+	if o.gzip {
+		if o.startChunk > 0 {
+			return nil, fmt.Errorf("%w: can't resume a compressed upload", ErrNotSupported)
+		}
+
+		return c.chunkedUpload(ctx, io.NewSectionReader(ra, 0, fileSize), filePath, fileSize, opts...)
+	}
+
+	// The chunks may be read out of order, so hash the file separately
+	if o.checksum != nil {
+		o.checksum.Reset()
+
+		if _, err := io.Copy(o.checksum, io.NewSectionReader(ra, 0, fileSize)); err != nil {
+			return nil, fmt.Errorf("failed to read file for checksum: %w", err)
+		}
+	}
+
+	fields, filePath, totalChunks, err := c.uploadFields(filePath, fileSize, o.chunkSize, defaultFileType)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := o.applyContext(fields); err != nil {
+		return nil, err
+	}
+
+	o.applyIdentifier(fields)
+
+	progress := o.newProgress(fileSize)
+
+	// Chunks skipped when resuming were already uploaded
+	if progress != nil {
+		progress.uploaded = min(int64(o.startChunk)*o.chunkSize, fileSize)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var firstErr error
+
+	fail := func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if firstErr == nil {
+			firstErr = err
+			cancel()
+		}
+	}
+
+	failed := func() error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return firstErr
+	}
+
+	sem := make(chan struct{}, max(o.concurrency, 1))
+
+	retry := o.chunkRetry(c.defaultChunkRetry(chunkRetry{attempts: maxChunkAttempts, base: c.combineRetryDelay}))
+
+	for chunk := o.startChunk + 1; chunk < totalChunks; chunk++ {
+		select {
+		case <-ctx.Done():
+		case sem <- struct{}{}:
+		}
+
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			if err := c.uploadChunkAt(ctx, ra, filePath, fileSize, o.chunkSize, chunk, fields, retry); err != nil {
+				fail(err)
+				return
+			}
+
+			_, length := ChunkBounds(chunk-1, fileSize, o.chunkSize)
+
+			progress.add(length)
+		}()
+	}
+
+	// Sequential uploads always wait, so the chunks are sent in order
+	if o.finalChunkLast || o.concurrency <= 1 {
+		wg.Wait()
+	}
+
+	if err := failed(); err != nil {
+		wg.Wait()
+		return nil, err
+	}
+
+	offset, length := ChunkBounds(totalChunks-1, fileSize, o.chunkSize)
+
+	finalFields := maps.Clone(fields)
+	finalFields["resumableChunkNumber"] = strconv.Itoa(totalChunks)
+	finalFields["resumableCurrentChunkSize"] = strconv.FormatInt(length, 10)
+
+	file, err := c.uploadFinalChunk(ctx, io.NewSectionReader(ra, offset, length), filePath, fileSize, length, finalFields,
+		o.checksum, c.finalChunkRetry(o))
+
+	wg.Wait()
+
+	if err := failed(); err != nil {
+		return nil, err
+	}
+
+	if err == nil {
+		progress.add(length)
+	}
+
+	return file, err
+}
+
+// uploadChunkAt uploads the chunk numbered chunk (starting at 1) of chunkSize chunks read from ra, re-reading and
+// re-sending it when the server fails to accept it
+func (c *client) uploadChunkAt(ctx context.Context, ra io.ReaderAt, filePath string, fileSize, chunkSize int64, chunk int, fields map[string]string, retry chunkRetry) error {
+	offset, length := ChunkBounds(chunk-1, fileSize, chunkSize)
+
+	chunkFields := maps.Clone(fields)
+	chunkFields["resumableChunkNumber"] = strconv.Itoa(chunk)
+	chunkFields["resumableCurrentChunkSize"] = strconv.FormatInt(length, 10)
+
+	return c.retryChunk(ctx, filePath, chunkFields, retry, func() (*Response, error) {
+		return c.uploadChunk(ctx, io.NewSectionReader(ra, offset, length), path.Base(filePath), fileSize, length, chunkFields)
+	})
+}
+
+// retryableSend returns true if a chunk which failed to send with err may succeed on another attempt. Only network
+// errors can, not auth, signing or short chunk failures, and nothing is retried once ctx is done.
+func retryableSend(ctx context.Context, err error) bool {
+	var netErr *NetworkError
+
+	return ctx.Err() == nil && errors.As(err, &netErr)
+}
+
+// retryChunk sends a chunk with send, calling it again for network errors and 5xx responses as allowed by retry.
+// send must produce the same chunk each time it's called.
+func (c *client) retryChunk(ctx context.Context, filePath string, fields map[string]string, retry chunkRetry, send func() (*Response, error)) error {
+	var lastErr error
+
+	for attempt := 1; attempt <= max(retry.attempts, 1); attempt++ {
+		if attempt > 1 {
+			log.WithFields(log.Fields{
+				"file":    filePath,
+				"chunk":   fields["resumableChunkNumber"],
+				"attempt": attempt,
+				"error":   lastErr,
+			}).Debug("Retrying chunk upload")
+
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(retry.delay(attempt)):
+			}
+		}
+
+		res, err := send()
+
+		if err != nil {
+			if !retryableSend(ctx, err) {
+				return fmt.Errorf("chunk upload failed, error: %w", err)
+			}
+
+			lastErr = fmt.Errorf("chunk upload failed, error: %w", err)
+			continue
+		}
+
+		if res.StatusCode >= http.StatusInternalServerError {
+			lastErr = chunkError(fields["resumableChunkNumber"], res)
+			continue
+		} else if res.StatusCode != http.StatusOK {
+			return chunkError(fields["resumableChunkNumber"], res)
+		}
+
+		_ = res.Close()
+
+		return nil
+	}
+
+	return lastErr
+}
@@ -0,0 +1,847 @@
+package hoist
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Upload tests", func() {
+	DescribeTable("Should count chunks",
+		func(fileSize, chunkSize int64, expected int) {
+			Expect(ChunkCount(fileSize, chunkSize)).To(Equal(expected))
+		},
+		Entry("empty file", int64(0), int64(10), 1),
+		Entry("smaller than a chunk", int64(1), int64(10), 1),
+		Entry("exactly one chunk", int64(10), int64(10), 1),
+		Entry("one byte over a chunk", int64(11), int64(10), 2),
+		Entry("exact multiple", int64(30), int64(10), 3),
+		Entry("one byte short of a multiple", int64(29), int64(10), 3),
+		Entry("non-positive chunk size", int64(29), int64(0), 1),
+	)
+
+	It("Should cover the whole file with contiguous chunk bounds", func() {
+		for _, fileSize := range []int64{0, 1, 9, 10, 11, 30, 31} {
+			var next int64
+
+			for i := 0; i < ChunkCount(fileSize, 10); i++ {
+				offset, length := ChunkBounds(i, fileSize, 10)
+
+				Expect(offset).To(Equal(next))
+				Expect(length).To(BeNumerically("<=", 10))
+
+				next = offset + length
+			}
+
+			Expect(next).To(Equal(fileSize))
+		}
+	})
+	It("Should return the last chunk's remainder", func() {
+		offset, length := ChunkBounds(2, 25, 10)
+
+		Expect(offset).To(BeEquivalentTo(20))
+		Expect(length).To(BeEquivalentTo(5))
+
+		offset, length = ChunkBounds(2, 30, 10)
+
+		Expect(offset).To(BeEquivalentTo(20))
+		Expect(length).To(BeEquivalentTo(10))
+	})
+	It("Should return empty bounds outside of the file", func() {
+		_, length := ChunkBounds(3, 30, 10)
+
+		Expect(length).To(BeZero())
+
+		_, length = ChunkBounds(-1, 30, 10)
+
+		Expect(length).To(BeZero())
+	})
+
+	Describe("ChunkedUploadAt", func() {
+		data := []byte("0123456789abcdefghij")
+
+		// flakyUpload fails the first attempt at chunk 2 with a 502, then accepts it
+		flakyUpload := func(upload *testUploadServer) http.Handler {
+			var mu sync.Mutex
+			var failed bool
+
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				fail := !failed && r.FormValue("resumableChunkNumber") == "2"
+				failed = failed || fail
+				mu.Unlock()
+
+				if fail {
+					w.WriteHeader(http.StatusBadGateway)
+					return
+				}
+
+				upload.ServeHTTP(w, r)
+			})
+		}
+
+		chunkData := func(upload *testUploadServer) string {
+			chunks := make([]string, len(upload.Chunks))
+
+			for _, chunk := range upload.Chunks {
+				n, _ := strconv.Atoi(chunk.Fields["resumableChunkNumber"])
+				chunks[n-1] = string(chunk.Data)
+			}
+
+			return strings.Join(chunks, "")
+		}
+
+		It("Should re-read and resend a failed chunk", func() {
+			upload := &testUploadServer{}
+
+			c, server := newTestClient(flakyUpload(upload))
+			defer server.Close()
+
+			c.chunkSize = 4
+			c.combineRetryDelay = 0
+
+			file, err := c.ChunkedUploadAt(context.Background(), bytes.NewReader(data), "/file.txt", int64(len(data)))
+
+			Expect(err).To(BeNil())
+			Expect(file.Size).To(Equal(int64(len(data))))
+			Expect(upload.Chunks).To(HaveLen(5))
+			Expect(string(upload.Data())).To(Equal(string(data)))
+		})
+		It("Should resume a failed chunk when uploading concurrently", func() {
+			upload := &testUploadServer{}
+
+			c, server := newTestClient(flakyUpload(upload))
+			defer server.Close()
+
+			c.chunkSize = 4
+			c.combineRetryDelay = 0
+
+			file, err := c.ChunkedUploadAt(context.Background(), bytes.NewReader(data), "/file.txt", int64(len(data)),
+				WithChunkConcurrency(3), WithFinalChunkLast())
+
+			Expect(err).To(BeNil())
+			Expect(file.Size).To(Equal(int64(len(data))))
+			Expect(upload.Chunks).To(HaveLen(5))
+			Expect(upload.Chunks[4].Fields["resumableChunkNumber"]).To(Equal("5"))
+			Expect(chunkData(upload)).To(Equal(string(data)))
+		})
+		It("Should fail once a chunk runs out of attempts", func() {
+			c, server := newTestClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusBadGateway)
+			}))
+			defer server.Close()
+
+			c.chunkSize = 4
+			c.combineRetryDelay = 0
+
+			_, err := c.ChunkedUploadAt(context.Background(), bytes.NewReader(data), "/file.txt", int64(len(data)))
+
+			Expect(err).To(MatchError(ErrUnexpectedStatus))
+		})
+	})
+	Describe("Upload retry", func() {
+		data := []byte("0123456789abcdefghij")
+
+		// flakyChunk fails chunk 2 with a 502 the first failures times, then accepts it
+		flakyChunk := func(upload *testUploadServer, failures int) http.Handler {
+			var mu sync.Mutex
+
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				fail := failures > 0 && r.FormValue("resumableChunkNumber") == "2"
+
+				if fail {
+					failures--
+				}
+				mu.Unlock()
+
+				if fail {
+					w.WriteHeader(http.StatusBadGateway)
+					return
+				}
+
+				upload.ServeHTTP(w, r)
+			})
+		}
+
+		It("Should re-send the same chunk bytes", func() {
+			upload := &testUploadServer{}
+
+			c, server := newTestClient(flakyChunk(upload, 2))
+			defer server.Close()
+
+			c.chunkSize = 4
+
+			file, err := c.ChunkedUpload(context.Background(), bytes.NewReader(data), "/file.txt", int64(len(data)),
+				WithUploadRetry(3, time.Millisecond))
+
+			Expect(err).To(BeNil())
+			Expect(file.Size).To(Equal(int64(len(data))))
+			Expect(upload.Chunks).To(HaveLen(5))
+			Expect(string(upload.Data())).To(Equal(string(data)))
+		})
+		It("Should fail without retries or once attempts run out", func() {
+			c, server := newTestClient(flakyChunk(&testUploadServer{}, 2))
+			defer server.Close()
+
+			c.chunkSize = 4
+
+			_, err := c.ChunkedUpload(context.Background(), bytes.NewReader(data), "/file.txt", int64(len(data)))
+
+			Expect(err).To(MatchError(ErrUnexpectedStatus))
+
+			_, err = c.ChunkedUpload(context.Background(), bytes.NewReader(data), "/file.txt", int64(len(data)),
+				WithUploadRetry(1, time.Millisecond), WithChunkConcurrency(2))
+
+			Expect(err).To(MatchError(ErrUnexpectedStatus))
+		})
+		It("Should stop retrying when the context is cancelled", func() {
+			c, server := newTestClient(flakyChunk(&testUploadServer{}, 10))
+			defer server.Close()
+
+			c.chunkSize = 4
+
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+
+			start := time.Now()
+
+			_, err := c.ChunkedUpload(ctx, bytes.NewReader(data), "/file.txt", int64(len(data)),
+				WithUploadRetry(5, time.Minute))
+
+			Expect(err).To(MatchError(context.DeadlineExceeded))
+			Expect(time.Since(start)).To(BeNumerically("<", 5*time.Second))
+		})
+		It("Should retry chunks for every upload with WithChunkRetry", func() {
+			upload := &testUploadServer{}
+
+			c, server := newTestClient(flakyChunk(upload, 2), WithChunkRetry(3, time.Millisecond))
+			defer server.Close()
+
+			c.chunkSize = 4
+
+			_, err := c.ChunkedUpload(context.Background(), bytes.NewReader(data), "/file.txt", int64(len(data)))
+
+			Expect(err).To(BeNil())
+			Expect(string(upload.Data())).To(Equal(string(data)))
+
+			// A single upload can still opt out
+			c, server = newTestClient(flakyChunk(&testUploadServer{}, 2), WithChunkRetry(3, time.Millisecond))
+			defer server.Close()
+
+			c.chunkSize = 4
+
+			_, err = c.ChunkedUpload(context.Background(), bytes.NewReader(data), "/file.txt", int64(len(data)),
+				WithUploadRetry(1, 0))
+
+			Expect(err).To(MatchError(ErrUnexpectedStatus))
+		})
+		It("Should not retry 4xx responses", func() {
+			var mu sync.Mutex
+			var attempts int
+
+			c, server := newTestClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				attempts++
+				mu.Unlock()
+
+				w.WriteHeader(http.StatusBadRequest)
+			}), WithChunkRetry(5, time.Millisecond))
+			defer server.Close()
+
+			c.chunkSize = 4
+
+			_, err := c.ChunkedUpload(context.Background(), bytes.NewReader(data), "/file.txt", int64(len(data)))
+
+			Expect(err).To(MatchError(ErrUnexpectedStatus))
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			Expect(attempts).To(Equal(1))
+		})
+		It("Should not retry chunks when the token can't be retrieved", func() {
+			server := httptest.NewServer(&testUploadServer{})
+			defer server.Close()
+
+			auth := &failingAuthManager{}
+			c := NewClient(server.URL, auth).(*client)
+
+			c.chunkSize = 4
+
+			_, err := c.ChunkedUpload(context.Background(), bytes.NewReader(data), "/file.txt", int64(len(data)),
+				WithUploadRetry(5, time.Millisecond))
+
+			var authErr *AuthError
+
+			Expect(errors.As(err, &authErr)).To(BeTrue())
+			Expect(auth.Calls()).To(Equal(1))
+		})
+		It("Should not retry chunks when signing fails", func() {
+			var mu sync.Mutex
+			var attempts int
+
+			c, server := newTestClient(&testUploadServer{}, WithRequestSigner(func(r *http.Request) error {
+				mu.Lock()
+				defer mu.Unlock()
+
+				attempts++
+
+				return errors.New("signing failed")
+			}))
+			defer server.Close()
+
+			c.chunkSize = 4
+
+			_, err := c.ChunkedUpload(context.Background(), bytes.NewReader(data), "/file.txt", int64(len(data)),
+				WithUploadRetry(5, time.Millisecond))
+
+			Expect(err).To(MatchError(ContainSubstring("signing failed")))
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			Expect(attempts).To(Equal(1))
+		})
+		It("Should only retry network errors and 5xx responses with WithChunkRetry", func() {
+			server := httptest.NewServer(&testUploadServer{})
+			defer server.Close()
+
+			auth := &failingAuthManager{}
+			c := NewClient(server.URL, auth, WithChunkRetry(5, time.Millisecond)).(*client)
+
+			c.chunkSize = 4
+
+			_, err := c.ChunkedUpload(context.Background(), bytes.NewReader(data), "/file.txt", int64(len(data)))
+
+			var authErr *AuthError
+
+			Expect(errors.As(err, &authErr)).To(BeTrue())
+			Expect(auth.Calls()).To(Equal(1))
+
+			// A server which can't be reached is a network error, retried up to the limit
+			var mu sync.Mutex
+			var attempts int
+
+			closed := httptest.NewServer(http.NotFoundHandler())
+			closed.Close()
+
+			c = NewClient(closed.URL, &testAuthManager{}, WithChunkRetry(3, time.Millisecond),
+				WithRequestModifier(func(r *http.Request) error {
+					mu.Lock()
+					defer mu.Unlock()
+
+					attempts++
+
+					return nil
+				})).(*client)
+
+			c.chunkSize = 4
+
+			_, err = c.ChunkedUpload(context.Background(), bytes.NewReader(data), "/file.txt", int64(len(data)))
+
+			var netErr *NetworkError
+
+			Expect(errors.As(err, &netErr)).To(BeTrue())
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			Expect(attempts).To(Equal(3))
+		})
+		It("Should retry the final chunk only for network errors and 5xx responses, as the policy allows", func() {
+			server := httptest.NewServer(&testUploadServer{})
+			defer server.Close()
+
+			auth := &failingAuthManager{}
+			c := NewClient(server.URL, auth).(*client)
+
+			c.combineRetryDelay = 0
+
+			_, err := c.Upload(context.Background(), bytes.NewReader(data), "/file.txt", int64(len(data)))
+
+			var authErr *AuthError
+
+			Expect(errors.As(err, &authErr)).To(BeTrue())
+			Expect(auth.Calls()).To(Equal(1))
+
+			var mu sync.Mutex
+			var attempts int
+
+			c, failing := newTestClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				attempts++
+				mu.Unlock()
+
+				w.WriteHeader(http.StatusBadGateway)
+			}))
+			defer failing.Close()
+
+			for _, retries := range []int{1, 2} {
+				mu.Lock()
+				attempts = 0
+				mu.Unlock()
+
+				_, err = c.ChunkedUpload(context.Background(), bytes.NewReader(data), "/file.txt", int64(len(data)),
+					WithUploadRetry(retries, 0))
+
+				Expect(err).To(MatchError(ErrCombineFailed))
+
+				mu.Lock()
+				Expect(attempts).To(Equal(retries))
+				mu.Unlock()
+			}
+		})
+		It("Should reject invalid retry policies", func() {
+			_, err := NewCheckedClient("https://example.com", &testAuthManager{}, WithChunkRetry(0, time.Second))
+
+			Expect(err).To(HaveOccurred())
+
+			_, err = NewCheckedClient("https://example.com", &testAuthManager{}, WithChunkRetry(3, -time.Second))
+
+			Expect(err).To(HaveOccurred())
+		})
+		It("Should double the delay between attempts", func() {
+			retry := chunkRetry{attempts: 4, base: 10 * time.Millisecond}
+
+			Expect(retry.delay(2)).To(Equal(10 * time.Millisecond))
+			Expect(retry.delay(3)).To(Equal(20 * time.Millisecond))
+			Expect(retry.delay(4)).To(Equal(40 * time.Millisecond))
+		})
+	})
+	Describe("ResumeUpload", func() {
+		data := []byte("0123456789abcdefghij")
+
+		It("Should continue an interrupted upload with the same identifier", func() {
+			upload := &testUploadServer{}
+
+			var mu sync.Mutex
+			interrupted := true
+
+			c, server := newTestClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				fail := interrupted && r.FormValue("resumableChunkNumber") == "3"
+				mu.Unlock()
+
+				if fail {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+
+				upload.ServeHTTP(w, r)
+			}))
+			defer server.Close()
+
+			c.chunkSize = 4
+			c.combineRetryDelay = 0
+
+			var identifier string
+			var uploaded int64
+
+			_, err := c.ChunkedUploadAt(context.Background(), bytes.NewReader(data), "/file.txt", int64(len(data)),
+				WithUploadIdentifier(func(id string) { identifier = id }),
+				WithUploadProgress(func(n, total int64) { uploaded = n }))
+
+			Expect(err).To(MatchError(ErrUnexpectedStatus))
+			Expect(identifier).ToNot(BeEmpty())
+			Expect(uploaded).To(Equal(int64(8)))
+
+			mu.Lock()
+			interrupted = false
+			mu.Unlock()
+
+			startChunk := int(uploaded / c.ChunkSize())
+
+			file, err := c.ResumeUpload(context.Background(), bytes.NewReader(data), "/file.txt", int64(len(data)),
+				identifier, startChunk, WithUploadProgress(func(n, total int64) { uploaded = n }))
+
+			Expect(err).To(BeNil())
+			Expect(file.ID).To(Equal(identifier))
+			Expect(uploaded).To(Equal(int64(len(data))))
+			Expect(upload.Chunks).To(HaveLen(5))
+			Expect(string(upload.Data())).To(Equal(string(data)))
+
+			for _, chunk := range upload.Chunks {
+				Expect(chunk.Fields["resumableIdentifier"]).To(Equal(identifier))
+			}
+
+			Expect(upload.Chunks[2].Fields["resumableChunkNumber"]).To(Equal("3"))
+		})
+		It("Should reject invalid resume points", func() {
+			c, server := newTestClient(&testUploadServer{})
+			defer server.Close()
+
+			c.chunkSize = 4
+
+			_, err := c.ResumeUpload(context.Background(), bytes.NewReader(data), "/file.txt", int64(len(data)), "", 1)
+
+			Expect(err).To(MatchError(ErrInvalidResume))
+
+			_, err = c.ResumeUpload(context.Background(), bytes.NewReader(data), "/file.txt", int64(len(data)), "id", 5)
+
+			Expect(err).To(MatchError(ErrInvalidResume))
+
+			_, err = c.ResumeUpload(context.Background(), bytes.NewReader(data), "/file.txt", int64(len(data)), "id", 1,
+				WithUploadGzip())
+
+			Expect(err).To(MatchError(ErrNotSupported))
+		})
+	})
+	Describe("Upload", func() {
+		It("Should send the whole file in a single request", func() {
+			upload := &testUploadServer{}
+
+			c, server := newTestClient(upload)
+			defer server.Close()
+
+			c.chunkSize = 16
+
+			file, err := c.Upload(context.Background(), strings.NewReader("single shot"), "/a/file.txt", 11)
+
+			Expect(err).To(BeNil())
+			Expect(file.Size).To(Equal(int64(11)))
+			Expect(upload.Chunks).To(HaveLen(1))
+			Expect(upload.Chunks[0].Fields["resumableChunkSize"]).To(Equal("16"))
+			Expect(upload.Chunks[0].Fields["resumableTotalChunks"]).To(Equal("1"))
+			Expect(upload.Chunks[0].Fields["resumableCurrentChunkSize"]).To(Equal("11"))
+			Expect(string(upload.Data())).To(Equal("single shot"))
+		})
+		It("Should reject files larger than the chunk size without sending them", func() {
+			upload := &testUploadServer{}
+
+			c, server := newTestClient(upload)
+			defer server.Close()
+
+			c.chunkSize = 4
+
+			_, err := c.Upload(context.Background(), strings.NewReader("single shot"), "/a/file.txt", 11)
+
+			Expect(err).To(MatchError(ErrUploadTooLarge))
+			Expect(upload.Chunks).To(BeEmpty())
+		})
+	})
+
+	Describe("Concurrent chunks", func() {
+		It("Should bound in-flight chunks and cancel the rest after a failure", func() {
+			var mu sync.Mutex
+			var requests, inFlight, maxInFlight int
+
+			c, server := newTestClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				requests++
+				inFlight++
+				maxInFlight = max(maxInFlight, inFlight)
+				mu.Unlock()
+
+				defer func() {
+					mu.Lock()
+					inFlight--
+					mu.Unlock()
+				}()
+
+				if r.FormValue("resumableChunkNumber") == "2" {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+
+				time.Sleep(10 * time.Millisecond)
+
+				_, _ = w.Write([]byte(`{"success":true}`))
+			}))
+			defer server.Close()
+
+			c.chunkSize = 4
+
+			data := strings.Repeat("x", 4*50)
+
+			for _, uploadFn := range []func() (*File, error){
+				func() (*File, error) {
+					return c.ChunkedUpload(context.Background(), strings.NewReader(data), "/a/file.txt", int64(len(data)),
+						WithChunkConcurrency(3))
+				},
+				func() (*File, error) {
+					return c.ChunkedUploadAt(context.Background(), strings.NewReader(data), "/a/file.txt", int64(len(data)),
+						WithChunkConcurrency(3))
+				},
+			} {
+				// Requests cancelled by a previous run may still be running on the server
+				Eventually(func() int {
+					mu.Lock()
+					defer mu.Unlock()
+
+					return inFlight
+				}).Should(BeZero())
+
+				mu.Lock()
+				requests, maxInFlight = 0, 0
+				mu.Unlock()
+
+				_, err := uploadFn()
+
+				Expect(err).To(MatchError(ContainSubstring("chunk 2 upload failed")))
+
+				mu.Lock()
+				sent, peak := requests, maxInFlight
+				mu.Unlock()
+
+				Expect(peak).To(BeNumerically("<=", 3))
+				Expect(sent).To(BeNumerically("<", 50))
+			}
+		})
+	})
+
+	Describe("Progress", func() {
+		type report struct{ uploaded, total int64 }
+
+		It("Should report cumulative bytes after each chunk", func() {
+			upload := &testUploadServer{}
+
+			c, server := newTestClient(upload)
+			defer server.Close()
+
+			c.chunkSize = 4
+
+			var reports []report
+
+			_, err := c.ChunkedUpload(context.Background(), strings.NewReader("0123456789"), "/a/file.txt", 10,
+				WithUploadProgress(func(uploaded, total int64) {
+					reports = append(reports, report{uploaded, total})
+				}))
+
+			Expect(err).To(BeNil())
+			Expect(reports).To(Equal([]report{{4, 10}, {8, 10}, {10, 10}}))
+		})
+		It("Should finish with uploaded == total for concurrent chunks", func() {
+			upload := &testUploadServer{}
+
+			c, server := newTestClient(upload)
+			defer server.Close()
+
+			c.chunkSize = 3
+
+			data := strings.Repeat("x", 20)
+
+			for _, uploadFn := range []func(opts ...UploadOpt) (*File, error){
+				func(opts ...UploadOpt) (*File, error) {
+					return c.ChunkedUpload(context.Background(), strings.NewReader(data), "/a/file.txt", 20, opts...)
+				},
+				func(opts ...UploadOpt) (*File, error) {
+					return c.ChunkedUploadAt(context.Background(), strings.NewReader(data), "/a/file.txt", 20, opts...)
+				},
+			} {
+				var reports []report
+
+				_, err := uploadFn(WithChunkConcurrency(3), WithUploadProgress(func(uploaded, total int64) {
+					reports = append(reports, report{uploaded, total})
+				}))
+
+				Expect(err).To(BeNil())
+				Expect(reports).To(HaveLen(7))
+				Expect(reports[len(reports)-1]).To(Equal(report{20, 20}))
+
+				for i := 1; i < len(reports); i++ {
+					Expect(reports[i].uploaded).To(BeNumerically(">", reports[i-1].uploaded))
+				}
+			}
+		})
+		It("Should keep uploading when the callback panics", func() {
+			upload := &testUploadServer{}
+
+			c, server := newTestClient(upload)
+			defer server.Close()
+
+			c.chunkSize = 4
+
+			var reports []report
+
+			file, err := c.ChunkedUpload(context.Background(), strings.NewReader("0123456789"), "/a/file.txt", 10,
+				WithUploadProgress(func(uploaded, total int64) {
+					reports = append(reports, report{uploaded, total})
+
+					panic("callback failed")
+				}))
+
+			Expect(err).To(BeNil())
+			Expect(file.Size).To(Equal(int64(10)))
+			Expect(string(upload.Data())).To(Equal("0123456789"))
+			Expect(reports).To(Equal([]report{{4, 10}, {8, 10}, {10, 10}}))
+		})
+		It("Should report an empty file as complete", func() {
+			c, server := newTestClient(&testUploadServer{})
+			defer server.Close()
+
+			var reports []report
+
+			_, err := c.ChunkedUpload(context.Background(), strings.NewReader(""), "/a/empty.txt", 0,
+				WithUploadProgress(func(uploaded, total int64) {
+					reports = append(reports, report{uploaded, total})
+				}))
+
+			Expect(err).To(BeNil())
+			Expect(reports).To(Equal([]report{{0, 0}}))
+		})
+	})
+
+	Describe("Short readers", func() {
+		It("Should fail with ErrShortChunk without sending the short chunk", func() {
+			upload := &testUploadServer{}
+
+			c, server := newTestClient(upload)
+			defer server.Close()
+
+			c.chunkSize = 4
+
+			_, err := c.ChunkedUpload(context.Background(), strings.NewReader("short"), "/a/file.txt", 10)
+
+			Expect(err).To(MatchError(ErrShortChunk))
+			Expect(err).To(MatchError(ContainSubstring("chunk 2 expected 4 bytes, read 1")))
+			Expect(upload.Chunks).To(HaveLen(1))
+		})
+		It("Should not retry a short final chunk", func() {
+			upload := &testUploadServer{}
+
+			c, server := newTestClient(upload)
+			defer server.Close()
+
+			c.combineRetryDelay = 0
+
+			_, err := c.Upload(context.Background(), strings.NewReader("short"), "/a/file.txt", 10)
+
+			Expect(err).To(MatchError(ErrShortChunk))
+			Expect(upload.Chunks).To(BeEmpty())
+
+			_, err = c.ChunkedUploadAt(context.Background(), strings.NewReader("short"), "/a/file.txt", 10)
+
+			Expect(err).To(MatchError(ErrShortChunk))
+			Expect(upload.Chunks).To(BeEmpty())
+		})
+		It("Should declare the size of the bytes sent", func() {
+			upload := &testUploadServer{}
+
+			c, server := newTestClient(upload)
+			defer server.Close()
+
+			c.chunkSize = 4
+
+			_, err := c.ChunkedUpload(context.Background(), strings.NewReader("exactly"), "/a/file.txt", 7)
+
+			Expect(err).To(BeNil())
+
+			for _, chunk := range upload.Chunks {
+				Expect(chunk.Fields["resumableCurrentChunkSize"]).To(Equal(strconv.Itoa(len(chunk.Data))))
+			}
+		})
+	})
+
+	Describe("WriteFile", func() {
+		It("Should upload a small byte slice in one request", func() {
+			upload := &testUploadServer{}
+
+			c, server := newTestClient(upload)
+			defer server.Close()
+
+			file, err := c.WriteFile(context.Background(), "/config.json", []byte(`{"a":1}`))
+
+			Expect(err).To(BeNil())
+			Expect(file.Size).To(Equal(int64(7)))
+			Expect(upload.Chunks).To(HaveLen(1))
+			Expect(string(upload.Data())).To(Equal(`{"a":1}`))
+		})
+		It("Should chunk a large byte slice", func() {
+			upload := &testUploadServer{}
+
+			c, server := newTestClient(upload)
+			defer server.Close()
+
+			c.chunkSize = 1024
+
+			data := bytes.Repeat([]byte("0123456789"), 1000)
+
+			file, err := c.WriteFile(context.Background(), "/large.bin", data, WithFinalChunkLast())
+
+			Expect(err).To(BeNil())
+			Expect(file.Size).To(Equal(int64(len(data))))
+			Expect(upload.Chunks).To(HaveLen(10))
+			Expect(upload.Data()).To(Equal(data))
+		})
+		It("Should use Upload for small data and concurrent chunks for larger data", func() {
+			upload := &testUploadServer{}
+
+			var mu sync.Mutex
+			var inFlight, maxInFlight int
+
+			c, server := newTestClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				inFlight++
+				maxInFlight = max(maxInFlight, inFlight)
+				mu.Unlock()
+
+				defer func() {
+					mu.Lock()
+					inFlight--
+					mu.Unlock()
+				}()
+
+				if r.FormValue("resumableChunkNumber") != r.FormValue("resumableTotalChunks") {
+					time.Sleep(20 * time.Millisecond)
+				}
+
+				upload.ServeHTTP(w, r)
+			}))
+			defer server.Close()
+
+			c.chunkSize = 4
+
+			counting := &countingWriteClient{client: c}
+
+			_, err := WriteFile(context.Background(), counting, "/small.txt", []byte("tiny"))
+
+			Expect(err).To(BeNil())
+			Expect(counting.uploads).To(Equal(1))
+			Expect(counting.chunked).To(BeZero())
+
+			upload.Chunks = nil
+
+			data := []byte(strings.Repeat("0123", 5))
+
+			file, err := WriteFile(context.Background(), counting, "/large.txt", data)
+
+			Expect(err).To(BeNil())
+			Expect(file.Size).To(Equal(int64(len(data))))
+			Expect(counting.uploads).To(Equal(1))
+			Expect(counting.chunked).To(Equal(1))
+			Expect(maxInFlight).To(BeNumerically(">", 1))
+			Expect(upload.Chunks).To(HaveLen(5))
+			Expect(upload.Chunks[4].Fields["resumableChunkNumber"]).To(Equal("5"))
+			Expect(upload.Data()).To(Equal(data))
+		})
+	})
+})
+
+// countingWriteClient counts which upload method WriteFile picks
+type countingWriteClient struct {
+	*client
+	uploads, chunked int
+}
+
+func (c *countingWriteClient) Upload(ctx context.Context, in io.Reader, filePath string, fileSize int64) (*File, error) {
+	c.uploads++
+
+	return c.client.Upload(ctx, in, filePath, fileSize)
+}
+
+func (c *countingWriteClient) ChunkedUploadAt(ctx context.Context, in io.ReaderAt, filePath string, fileSize int64, opts ...UploadOpt) (*File, error) {
+	c.chunked++
+
+	return c.client.ChunkedUploadAt(ctx, in, filePath, fileSize, opts...)
+}
@@ -0,0 +1,372 @@
+package hoist
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+const defaultDirConcurrency = 4
+
+// uploadDirOptions are the options for UploadDir
+type uploadDirOptions struct {
+	include       []string
+	exclude       []string
+	skipUnchanged bool
+	concurrency   int
+	failFast      bool
+	manifest      string
+	uploadOpts    []UploadOpt
+}
+
+// UploadDirOpt allows defining options for UploadDir
+type UploadDirOpt func(o *uploadDirOptions)
+
+// WithInclude only uploads files matching at least one of the patterns (see path.Match).
+// Patterns are matched against both the file name and the slash-separated path relative to the local directory.
+func WithInclude(patterns ...string) UploadDirOpt {
+	return func(o *uploadDirOptions) {
+		o.include = append(o.include, patterns...)
+	}
+}
+
+// WithExclude skips files and directories matching any of the patterns, matched the same way as WithInclude
+func WithExclude(patterns ...string) UploadDirOpt {
+	return func(o *uploadDirOptions) {
+		o.exclude = append(o.exclude, patterns...)
+	}
+}
+
+// WithSkipUnchanged skips files which already exist remotely with the same size and SHA-256 checksum.
+// As the backend doesn't expose checksums, files of matching size are downloaded to be compared.
+func WithSkipUnchanged() UploadDirOpt {
+	return func(o *uploadDirOptions) {
+		o.skipUnchanged = true
+	}
+}
+
+// WithDirConcurrency sets how many files are uploaded at once, defaulting to 4
+func WithDirConcurrency(n int) UploadDirOpt {
+	return func(o *uploadDirOptions) {
+		o.concurrency = n
+	}
+}
+
+// WithDirFailFast stops uploading remaining files after the first failure, which are then reported as failed with
+// context.Canceled
+func WithDirFailFast() UploadDirOpt {
+	return func(o *uploadDirOptions) {
+		o.failFast = true
+	}
+}
+
+// WithDirManifest records completed uploads in the transfer manifest at name, skipping files it lists as already
+// uploaded with the same size and modification time. Use the same manifest to resume an interrupted UploadDir.
+func WithDirManifest(name string) UploadDirOpt {
+	return func(o *uploadDirOptions) {
+		o.manifest = name
+	}
+}
+
+// WithDirUploadOpts applies the upload options to every file uploaded
+func WithDirUploadOpts(opts ...UploadOpt) UploadDirOpt {
+	return func(o *uploadDirOptions) {
+		o.uploadOpts = append(o.uploadOpts, opts...)
+	}
+}
+
+// matchAny returns true if the name or relative path matches any of the patterns
+func matchAny(patterns []string, rel string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, rel); ok {
+			return true
+		}
+
+		if ok, _ := path.Match(pattern, path.Base(rel)); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// UploadDir mirrors a local directory into remoteDir. See UploadDir.
+func (c *client) UploadDir(ctx context.Context, localDir, remoteDir string, opts ...UploadDirOpt) (map[string]*File, map[string]error) {
+	return UploadDir(ctx, c, localDir, remoteDir, opts...)
+}
+
+// UploadDir walks localDir, creating the mirrored folders below remoteDir and uploading files with bounded concurrency.
+// Results are keyed by the slash-separated path relative to localDir: uploaded (or unchanged) files are returned in
+// the first map and failures in the second. Errors which prevent the walk entirely are keyed by ".".
+func UploadDir(ctx context.Context, c FileClient, localDir, remoteDir string, opts ...UploadDirOpt) (map[string]*File, map[string]error) {
+	o := uploadDirOptions{
+		concurrency: defaultDirConcurrency,
+	}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if o.concurrency < 1 {
+		o.concurrency = 1
+	}
+
+	files := make(map[string]*File)
+	failed := make(map[string]error)
+
+	var manifest *transferManifest
+
+	if o.manifest != "" {
+		var err error
+
+		if manifest, err = openTransferManifest(o.manifest); err != nil {
+			failed["."] = err
+			return files, failed
+		}
+
+		defer manifest.Close()
+	}
+
+	var dirs, uploads []string
+
+	err := filepath.WalkDir(localDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(localDir, p)
+
+		if err != nil {
+			return err
+		}
+
+		rel = filepath.ToSlash(rel)
+
+		if rel == "." {
+			return nil
+		}
+
+		if matchAny(o.exclude, rel) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		if d.IsDir() {
+			dirs = append(dirs, rel)
+		} else if d.Type().IsRegular() && (len(o.include) == 0 || matchAny(o.include, rel)) {
+			uploads = append(uploads, rel)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		failed["."] = err
+		return files, failed
+	}
+
+	folders, err := c.GetFolders(ctx)
+
+	if err == nil && len(folders) == 0 {
+		err = ErrNoFolder
+	}
+
+	if err != nil {
+		failed["."] = err
+		return files, failed
+	}
+
+	root := folders[0]
+
+	// Create the remote directory and mirrored folders, parents first
+	sort.Strings(dirs)
+
+	if _, _, err := mkdirAllTree(ctx, c, &root, remoteDir); err != nil {
+		failed["."] = err
+		return files, failed
+	}
+
+	for _, dir := range dirs {
+		if _, _, err := mkdirAllTree(ctx, c, &root, path.Join(remoteDir, dir)); err != nil {
+			failed[dir] = err
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	sem := make(chan struct{}, o.concurrency)
+
+	for _, rel := range uploads {
+		if !acquireDirWorker(ctx, sem, &mu, failed, path.Dir(rel), rel) {
+			continue
+		}
+
+		wg.Add(1)
+
+		go func(rel string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			file, err := uploadDirResumable(ctx, c, manifest, rel, filepath.Join(localDir, filepath.FromSlash(rel)), path.Join("/", remoteDir, rel), o)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if err != nil {
+				failed[rel] = err
+
+				if o.failFast {
+					cancel()
+				}
+			} else {
+				files[rel] = file
+			}
+		}(rel)
+	}
+
+	wg.Wait()
+
+	return files, failed
+}
+
+// acquireDirWorker waits for a free worker slot for rel, recording the failure and returning false if its directory
+// failed or the context is done
+func acquireDirWorker(ctx context.Context, sem chan struct{}, mu *sync.Mutex, failed map[string]error, dir, rel string) bool {
+	mu.Lock()
+	err, dirFailed := failed[dir]
+	mu.Unlock()
+
+	if !dirFailed {
+		select {
+		case <-ctx.Done():
+		case sem <- struct{}{}:
+			if ctx.Err() != nil {
+				<-sem
+			}
+		}
+
+		err = ctx.Err()
+	}
+
+	if err != nil {
+		mu.Lock()
+		failed[rel] = err
+		mu.Unlock()
+
+		return false
+	}
+
+	return true
+}
+
+// uploadDirResumable uploads a file unless the manifest lists it as complete, recording it once uploaded
+func uploadDirResumable(ctx context.Context, c FileClient, manifest *transferManifest, rel, localPath, remotePath string, o uploadDirOptions) (*File, error) {
+	if manifest == nil {
+		return uploadDirFile(ctx, c, localPath, remotePath, o)
+	}
+
+	stat, err := os.Stat(localPath)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if entry, ok := manifest.done(rel, stat.Size(), stat.ModTime()); ok {
+		dir, name := path.Split(remotePath)
+
+		return &File{ID: entry.ID, Name: name, Size: entry.Size, FolderPath: path.Clean(dir)}, nil
+	}
+
+	file, err := uploadDirFile(ctx, c, localPath, remotePath, o)
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = manifest.record(TransferEntry{
+		Path:     rel,
+		ID:       file.ID,
+		Size:     stat.Size(),
+		Modified: stat.ModTime(),
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return file, nil
+}
+
+// uploadDirFile uploads a single local file, skipping it if unchanged and requested
+func uploadDirFile(ctx context.Context, c FileClient, localPath, remotePath string, o uploadDirOptions) (*File, error) {
+	f, err := os.Open(localPath)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	stat, err := f.Stat()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if o.skipUnchanged {
+		existing, err := c.GetFileByPath(ctx, remotePath)
+
+		if err == nil && existing.Size == stat.Size() {
+			if same, err := sameContents(ctx, c, existing.ID, f); err == nil && same {
+				return existing, nil
+			}
+
+			if _, err := f.Seek(0, io.SeekStart); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return c.ChunkedUpload(ctx, f, remotePath, stat.Size(), o.uploadOpts...)
+}
+
+// sameContents compares the SHA-256 checksum of the remote file with the local reader
+func sameContents(ctx context.Context, c FileClient, id string, local io.Reader) (bool, error) {
+	localHash := sha256.New()
+
+	if _, err := io.Copy(localHash, local); err != nil {
+		return false, err
+	}
+
+	remote, err := c.DownloadFile(ctx, id)
+
+	if err != nil {
+		return false, err
+	}
+
+	defer remote.Close()
+
+	remoteHash := sha256.New()
+
+	if _, err := io.Copy(remoteHash, remote); err != nil {
+		return false, err
+	}
+
+	return bytes.Equal(localHash.Sum(nil), remoteHash.Sum(nil)), nil
+}
@@ -0,0 +1,109 @@
+package hoist
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("UploadDir tests", func() {
+	It("Should resume an interrupted upload from the manifest", func() {
+		upload := &testUploadServer{}
+
+		var mu sync.Mutex
+		var uploads int
+
+		// limit is how many uploads the server accepts before failing them, or -1 for no limit
+		limit := 2
+
+		mux := http.NewServeMux()
+
+		mux.HandleFunc(testPath(apiFolders), func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewEncoder(w).Encode(FolderResponse{
+				defaultResponse: defaultResponse{Success: true},
+				Folder:          testTree(),
+			})
+		})
+
+		mux.HandleFunc(testPath(apiUpload), func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			accept := limit < 0 || uploads < limit
+
+			if accept {
+				uploads++
+			}
+			mu.Unlock()
+
+			if !accept {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			upload.ServeHTTP(w, r)
+		})
+
+		c, server := newTestClient(mux)
+		defer server.Close()
+
+		reset := func() {
+			mu.Lock()
+			defer mu.Unlock()
+
+			uploads, limit = 0, -1
+		}
+
+		dir := GinkgoT().TempDir()
+		manifest := filepath.Join(GinkgoT().TempDir(), "upload.manifest")
+
+		for _, name := range []string{"a.txt", "b.txt", "c.txt", "d.txt", "e.txt"} {
+			Expect(os.WriteFile(filepath.Join(dir, name), []byte(name), 0o644)).To(Succeed())
+		}
+
+		files, failed := c.UploadDir(context.Background(), dir, "/a",
+			WithDirManifest(manifest), WithDirConcurrency(1), WithDirFailFast())
+
+		Expect(files).To(HaveLen(2))
+		Expect(failed).To(HaveLen(3))
+
+		// Simulate a crash while writing the next entry
+		f, err := os.OpenFile(manifest, os.O_APPEND|os.O_WRONLY, 0)
+
+		Expect(err).To(BeNil())
+
+		_, err = f.WriteString(`{"path":"c.tx`)
+
+		Expect(err).To(BeNil())
+		Expect(f.Close()).To(Succeed())
+
+		reset()
+
+		files, failed = c.UploadDir(context.Background(), dir, "/a", WithDirManifest(manifest))
+
+		Expect(failed).To(BeEmpty())
+		Expect(files).To(HaveLen(5))
+		Expect(uploads).To(Equal(3))
+		Expect(upload.Chunks).To(HaveLen(5))
+
+		data, err := os.ReadFile(manifest)
+
+		Expect(err).To(BeNil())
+		Expect(bytes.Count(data, []byte("\n"))).To(Equal(5))
+
+		// Changed files are uploaded again
+		Expect(os.WriteFile(filepath.Join(dir, "a.txt"), []byte("changed"), 0o644)).To(Succeed())
+
+		reset()
+
+		_, failed = c.UploadDir(context.Background(), dir, "/a", WithDirManifest(manifest))
+
+		Expect(failed).To(BeEmpty())
+		Expect(uploads).To(Equal(1))
+	})
+})
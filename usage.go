@@ -0,0 +1,52 @@
+package hoist
+
+import (
+	"cmp"
+	"slices"
+)
+
+// UsageCategory is a labeled amount of storage, see DiskUsage.Breakdown
+type UsageCategory struct {
+	Name  string `json:"name"`
+	Bytes int64  `json:"bytes"`
+}
+
+// Breakdown returns the storage used by each context, largest first, for charts. Contexts using nothing are omitted.
+// Usage not attributed to any context is reported as "Other", so the categories always sum to Used.
+// When Allowed is set, the unused remainder follows as "Unused", so everything sums to Allowed unless over quota.
+func (d DiskUsage) Breakdown() []UsageCategory {
+	categories := []UsageCategory{
+		{Name: "Mailboxes", Bytes: d.Mailboxes},
+		{Name: "Appointments", Bytes: d.Appointments},
+		{Name: "Contacts", Bytes: d.Contacts},
+		{Name: "Notes", Bytes: d.Notes},
+		{Name: "Tasks", Bytes: d.Tasks},
+		{Name: "File storage", Bytes: d.FileStorage},
+		{Name: "Meeting workspace", Bytes: d.MeetingWorkspace},
+		{Name: "Chat files", Bytes: d.ChatFiles},
+	}
+
+	var attributed int64
+
+	for _, category := range categories {
+		attributed += category.Bytes
+	}
+
+	if other := d.Used - attributed; other > 0 {
+		categories = append(categories, UsageCategory{Name: "Other", Bytes: other})
+	}
+
+	categories = slices.DeleteFunc(categories, func(category UsageCategory) bool {
+		return category.Bytes <= 0
+	})
+
+	slices.SortStableFunc(categories, func(a, b UsageCategory) int {
+		return cmp.Compare(b.Bytes, a.Bytes)
+	})
+
+	if d.Allowed > 0 {
+		categories = append(categories, UsageCategory{Name: "Unused", Bytes: max(d.Allowed-max(d.Used, attributed), 0)})
+	}
+
+	return categories
+}
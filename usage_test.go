@@ -0,0 +1,50 @@
+package hoist
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Disk usage tests", func() {
+	sum := func(categories []UsageCategory) int64 {
+		var total int64
+
+		for _, category := range categories {
+			total += category.Bytes
+		}
+
+		return total
+	}
+
+	It("Should break usage down by size, followed by the unused remainder", func() {
+		usage := DiskUsage{Allowed: 1000, Used: 600, Mailboxes: 100, FileStorage: 400, ChatFiles: 50, Notes: 50}
+
+		breakdown := usage.Breakdown()
+
+		Expect(breakdown).To(Equal([]UsageCategory{
+			{Name: "File storage", Bytes: 400},
+			{Name: "Mailboxes", Bytes: 100},
+			{Name: "Notes", Bytes: 50},
+			{Name: "Chat files", Bytes: 50},
+			{Name: "Unused", Bytes: 400},
+		}))
+		Expect(sum(breakdown)).To(Equal(usage.Allowed))
+	})
+	It("Should report unattributed usage as other", func() {
+		usage := DiskUsage{Allowed: 1000, Used: 700, FileStorage: 400}
+
+		breakdown := usage.Breakdown()
+
+		Expect(breakdown).To(ContainElement(UsageCategory{Name: "Other", Bytes: 300}))
+		Expect(sum(breakdown[:len(breakdown)-1])).To(Equal(usage.Used))
+		Expect(sum(breakdown)).To(Equal(usage.Allowed))
+	})
+	It("Should not report a negative remainder when over quota", func() {
+		breakdown := DiskUsage{Allowed: 100, Used: 150, FileStorage: 150}.Breakdown()
+
+		Expect(breakdown).To(Equal([]UsageCategory{{Name: "File storage", Bytes: 150}, {Name: "Unused", Bytes: 0}}))
+	})
+	It("Should omit the remainder without a quota", func() {
+		Expect(DiskUsage{Used: 10, Tasks: 10}.Breakdown()).To(Equal([]UsageCategory{{Name: "Tasks", Bytes: 10}}))
+	})
+})
@@ -0,0 +1,39 @@
+package hoist
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+)
+
+// WriteFile uploads data to remotePath. See WriteFile.
+func (c *client) WriteFile(ctx context.Context, remotePath string, data []byte, opts ...UploadOpt) (*File, error) {
+	return WriteFile(ctx, c, remotePath, data, opts...)
+}
+
+// defaultWriteFileConcurrency is how many chunks WriteFile uploads at once, unless WithChunkConcurrency is given
+const defaultWriteFileConcurrency = 4
+
+// WriteFile uploads data to remotePath without the caller constructing a reader. Data fitting in a single chunk of a
+// client reporting its ChunkSize is sent in one request with Upload, unless options Upload doesn't support are
+// given. Larger data is uploaded with ChunkedUploadAt, 4 chunks at a time by default (see WithChunkConcurrency), with
+// the final chunk sent last (see WithFinalChunkLast). With WithCreateParents, missing parent folders are created first.
+func WriteFile(ctx context.Context, c FileClient, remotePath string, data []byte, opts ...UploadOpt) (*File, error) {
+	o := newUploadOptions(opts)
+
+	if o.createParents {
+		parent, _ := ParsePath(remotePath)
+
+		if _, err := MkdirAll(ctx, c, parent); err != nil {
+			return nil, fmt.Errorf("failed to create parent folders of %s: %w", remotePath, err)
+		}
+	}
+
+	if sized, ok := c.(interface{ ChunkSize() int64 }); ok && o.singleRequest() && int64(len(data)) <= sized.ChunkSize() {
+		return c.Upload(ctx, bytes.NewReader(data), remotePath, int64(len(data)))
+	}
+
+	opts = append([]UploadOpt{WithChunkConcurrency(defaultWriteFileConcurrency), WithFinalChunkLast()}, opts...)
+
+	return c.ChunkedUploadAt(ctx, bytes.NewReader(data), remotePath, int64(len(data)), opts...)
+}